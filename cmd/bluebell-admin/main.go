@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/logger"
+	"bluebell/logic"
+	"bluebell/pkg/snowflake"
+	"bluebell/settings"
+	"flag"
+	"fmt"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"os"
+	"time"
+)
+
+// bluebell 运维命令行工具，在 config.yaml 所在目录执行，复用服务的配置、mysql 和 redis 初始化
+/*
+	bluebell-admin create-admin -username admin -password xxx   创建管理员，用户已存在时设为管理员
+	bluebell-admin reset-password -username foo [-password xxx] 重置密码，不指定时生成临时密码
+	bluebell-admin set-owner -community go -username foo       设置社区的创建者，原创建者改为版主
+	bluebell-admin migrate [-file models/create_tables.sql] [-reset] 按建表脚本迁移数据库
+	bluebell-admin rebuild-ranking [-from-scratch]              从 MySQL 重建 redis 排序数据
+	bluebell-admin sync-votes                                   立即把投票数据同步到 MySQL 一次
+	bluebell-admin purge-blacklist                              清理 jwt_blacklist 中已过期的Token
+	bluebell-admin seed [-users 10] [-communities 3] [-posts 50] 生成测试数据
+	bluebell-admin check-config                                 检查配置以及 mysql、redis 能否连接
+*/
+
+// command 子命令，needMySQL、needRedis 表示运行前需要初始化的连接
+type command struct {
+	usage     string
+	needMySQL bool
+	needRedis bool
+	run       func(args []string) error
+}
+
+var commands = map[string]*command{
+	"create-admin":    {usage: "创建管理员，用户已存在时设为管理员", needMySQL: true, run: createAdmin},
+	"reset-password":  {usage: "重置用户密码，用户已登录的会话全部失效", needMySQL: true, needRedis: true, run: resetPassword},
+	"set-owner":       {usage: "设置社区的创建者，用于补全没有创建者的旧社区", needMySQL: true, run: setOwner},
+	"migrate":         {usage: "按建表脚本迁移数据库", needMySQL: true, run: migrate},
+	"rebuild-ranking": {usage: "从 MySQL 重建 redis 中的帖子排序数据", needMySQL: true, needRedis: true, run: rebuildRanking},
+	"sync-votes":      {usage: "立即把投票数据同步到 MySQL 一次", needMySQL: true, needRedis: true, run: syncVotes},
+	"purge-blacklist": {usage: "清理 jwt_blacklist 中已过期的Token", needMySQL: true, run: purgeBlacklist},
+	"seed":            {usage: "生成测试用户、社区和帖子", needMySQL: true, needRedis: true, run: seed},
+	"check-config":    {usage: "检查配置以及 mysql、redis 能否连接", run: checkConfig},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: bluebell-admin <command> [flags]")
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, name := range []string{"create-admin", "reset-password", "set-owner", "migrate", "rebuild-ranking", "sync-votes", "purge-blacklist", "seed", "check-config"} {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", name, commands[name].usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	// 1.加载配置
+	if err := settings.Init(); err != nil {
+		fmt.Printf("init settings failed, err:%v\n", err)
+		os.Exit(1)
+	}
+	// 2.初始化日志
+	if err := logger.Init(viper.GetString("app.mode")); err != nil {
+		fmt.Printf("init logger failed, err:%v\n", err)
+		os.Exit(1)
+	}
+	defer zap.L().Sync()
+
+	if err := run(cmd, os.Args[2:]); err != nil {
+		fmt.Printf("%s failed, err:%v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+// run 初始化子命令需要的连接后运行子命令
+func run(cmd *command, args []string) error {
+	if cmd.needMySQL {
+		if err := mysql.Init(); err != nil {
+			return fmt.Errorf("init mysql: %w", err)
+		}
+		defer mysql.Close()
+	}
+	if cmd.needRedis {
+		if err := redis.Init(); err != nil {
+			return fmt.Errorf("init redis: %w", err)
+		}
+		defer redis.Close()
+	}
+	// 创建用户、社区和帖子时需要生成id
+	if cmd.needMySQL {
+		if err := snowflake.Init(viper.GetString("app.start_time"), viper.GetInt64("app.machine_id")); err != nil {
+			return fmt.Errorf("init snowflake: %w", err)
+		}
+	}
+	return cmd.run(args)
+}
+
+func createAdmin(args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	username := fs.String("username", "", "username of the admin")
+	password := fs.String("password", "", "password of the admin, required when the user does not exist")
+	_ = fs.Parse(args)
+	if *username == "" {
+		return fmt.Errorf("-username is required")
+	}
+	userID, created, err := logic.CreateAdminUser(*username, *password)
+	if err != nil {
+		return err
+	}
+	if created {
+		fmt.Printf("created admin %s, user_id: %d\n", *username, userID)
+	} else {
+		fmt.Printf("user %s (user_id: %d) is now an admin\n", *username, userID)
+	}
+	return nil
+}
+
+func resetPassword(args []string) error {
+	fs := flag.NewFlagSet("reset-password", flag.ExitOnError)
+	username := fs.String("username", "", "username of the user")
+	password := fs.String("password", "", "new password, a temporary password is generated when empty")
+	_ = fs.Parse(args)
+	if *username == "" {
+		return fmt.Errorf("-username is required")
+	}
+	newPassword, err := logic.ResetPasswordByName(*username, *password)
+	if err != nil {
+		return err
+	}
+	if *password == "" {
+		fmt.Printf("password of %s reset, temporary password: %s\n", *username, newPassword)
+	} else {
+		fmt.Printf("password of %s reset\n", *username)
+	}
+	return nil
+}
+
+func setOwner(args []string) error {
+	fs := flag.NewFlagSet("set-owner", flag.ExitOnError)
+	community := fs.String("community", "", "name of the community")
+	username := fs.String("username", "", "username of the new owner")
+	_ = fs.Parse(args)
+	if *community == "" || *username == "" {
+		return fmt.Errorf("-community and -username are required")
+	}
+	communityID, userID, err := logic.SetCommunityOwnerByName(*community, *username)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("owner of %s (community_id: %d) is now %s (user_id: %d)\n", *community, communityID, *username, userID)
+	return nil
+}
+
+func migrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	file := fs.String("file", "models/create_tables.sql", "schema script")
+	reset := fs.Bool("reset", false, "run DROP TABLE statements, ALL DATA IN THESE TABLES IS LOST")
+	_ = fs.Parse(args)
+	applied, skipped, err := logic.MigrateSchema(*file, *reset)
+	fmt.Printf("migrate %s: %d applied, %d skipped\n", *file, applied, skipped)
+	return err
+}
+
+func rebuildRanking(args []string) error {
+	fs := flag.NewFlagSet("rebuild-ranking", flag.ExitOnError)
+	fromScratch := fs.Bool("from-scratch", false, "ignore the rebuild checkpoint and start over")
+	_ = fs.Parse(args)
+	total, err := logic.RebuildRedisRanking(*fromScratch)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("rebuild redis ranking success, total posts: %d\n", total)
+	return nil
+}
+
+func syncVotes(args []string) error {
+	logic.SyncHotsDLikesToMySQL()
+	cp, err := logic.GetVoteSyncStatus()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("vote sync: last run %s, synced %d posts, pending %d, lag %ds, failures %d\n",
+		time.Unix(cp.LastRun, 0).Format(time.DateTime), cp.LastSynced, cp.Pending, cp.Lag, cp.Failures)
+	return nil
+}
+
+func purgeBlacklist(args []string) error {
+	n, err := logic.PurgeTokenBlacklist()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("purged %d expired tokens from jwt_blacklist\n", n)
+	return nil
+}
+
+func seed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	users := fs.Int("users", 10, "number of test users")
+	communities := fs.Int("communities", 3, "number of test communities")
+	posts := fs.Int("posts", 50, "number of test posts")
+	_ = fs.Parse(args)
+	result, err := logic.SeedTestData(*users, *communities, *posts)
+	if result != nil {
+		fmt.Printf("seeded %d users, %d communities, %d posts; password of test users: %s\n",
+			result.Users, result.Communities, result.Posts, logic.SeedPassword)
+	}
+	return err
+}
+
+// checkConfig 检查必填的配置项，并尝试连接 mysql 和 redis
+func checkConfig(args []string) error {
+	failed := 0
+	check := func(name string, err error) {
+		if err != nil {
+			failed++
+			fmt.Printf("[FAIL] %s: %v\n", name, err)
+			return
+		}
+		fmt.Printf("[ OK ] %s\n", name)
+	}
+	for _, key := range []string{"app.mode", "app.port", "app.start_time", "auth.jwt_expire",
+		"log.level", "log.filename", "mysql.host", "mysql.port", "mysql.user", "mysql.dbname", "redis.host", "redis.port"} {
+		var err error
+		if !viper.IsSet(key) || viper.GetString(key) == "" {
+			err = fmt.Errorf("not set")
+		}
+		check(key, err)
+	}
+	if viper.GetInt("auth.jwt_expire") <= 0 {
+		check("auth.jwt_expire > 0", fmt.Errorf("got %d", viper.GetInt("auth.jwt_expire")))
+	}
+	check("snowflake app.start_time/app.machine_id", snowflake.Init(viper.GetString("app.start_time"), viper.GetInt64("app.machine_id")))
+	if len(viper.GetIntSlice("admin.user_ids")) == 0 {
+		fmt.Println("[WARN] admin.user_ids is empty, create an admin with create-admin")
+	}
+	if viper.GetString("email.host") == "" {
+		fmt.Println("[WARN] email.host is empty, users cannot verify their email")
+	}
+	for _, file := range viper.GetStringSlice("sensitive.word_files") {
+		_, err := os.Stat(file)
+		check("sensitive word file "+file, err)
+	}
+	if err := mysql.Init(); err != nil {
+		check("connect mysql", err)
+	} else {
+		check("connect mysql", nil)
+		mysql.Close()
+	}
+	if err := redis.Init(); err != nil {
+		check("connect redis", err)
+	} else {
+		check("connect redis", nil)
+		redis.Close()
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d checks failed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"bluebell/logic"
+	"bluebell/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// --------- 管理后台 -----------
+
+// AdminSearchUsersHandler 管理员按用户名、邮箱或用户id分页搜索用户
+func AdminSearchUsersHandler(c *gin.Context) {
+	p := new(models.ParamAdminUserList)
+	if err := c.ShouldBindQuery(p); err != nil {
+		zap.L().Error("AdminSearchUsersHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	operatorID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	users, err := logic.SearchUsers(operatorID, p)
+	if err != nil {
+		zap.L().Error("logic.SearchUsers failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, users)
+}
+
+// AdminGetUserHandler 管理员查看用户的完整记录
+func AdminGetUserHandler(c *gin.Context) {
+	userID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	operatorID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	detail, err := logic.GetAdminUserDetail(operatorID, userID)
+	if err != nil {
+		zap.L().Error("logic.GetAdminUserDetail failed", zap.Int64("userID", userID), zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, detail)
+}
+
+// AdminSetUserRoleHandler 管理员设置用户的全站角色：0 普通用户，1 管理员
+func AdminSetUserRoleHandler(c *gin.Context) {
+	userID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	p := new(models.ParamAdminRole)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("AdminSetUserRoleHandler with invalid param", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMcg(c, CodeInvalidParam, removeTopStruct(errs.Translate(trans)))
+		return
+	}
+	setAuditDetail(c, fmt.Sprintf("role=%d", *p.Role))
+	operatorID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.SetUserRole(operatorID, userID, *p.Role); err != nil {
+		zap.L().Error("logic.SetUserRole failed", zap.Int64("userID", userID), zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// AdminResetPasswordHandler 管理员重置用户密码，返回临时密码，用户已登录的会话全部失效
+func AdminResetPasswordHandler(c *gin.Context) {
+	userID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	operatorID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	password, err := logic.ResetUserPassword(operatorID, userID)
+	if err != nil {
+		zap.L().Error("logic.ResetUserPassword failed", zap.Int64("userID", userID), zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"password": password})
+}
+
+// AdminDeletePostHandler 管理员移除任意帖子
+func AdminDeletePostHandler(c *gin.Context) {
+	postID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	p := new(models.ParamModReason)
+	if err := c.ShouldBindJSON(p); err != nil && c.Request.ContentLength > 0 {
+		zap.L().Error("AdminDeletePostHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	setAuditDetail(c, "reason="+p.Reason)
+	operatorID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.AdminDeletePost(c, operatorID, postID, p.Reason); err != nil {
+		zap.L().Error("logic.AdminDeletePost failed", zap.Int64("postID", postID), zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// AdminDeleteCommentHandler 管理员移除任意评论及其全部回复
+func AdminDeleteCommentHandler(c *gin.Context) {
+	commentID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	p := new(models.ParamModReason)
+	if err := c.ShouldBindJSON(p); err != nil && c.Request.ContentLength > 0 {
+		zap.L().Error("AdminDeleteCommentHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	setAuditDetail(c, "reason="+p.Reason)
+	operatorID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.AdminDeleteComment(operatorID, commentID, p.Reason); err != nil {
+		zap.L().Error("logic.AdminDeleteComment failed", zap.Int64("commentID", commentID), zap.Error(err))
+		responseCommentError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// AdminUpdateCommunityHandler 管理员修改任意社区的名称、简介和设置
+func AdminUpdateCommunityHandler(c *gin.Context) {
+	communityID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	p := new(models.ParamAdminUpdateCommunity)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("AdminUpdateCommunityHandler with invalid param", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMcg(c, CodeInvalidParam, removeTopStruct(errs.Translate(trans)))
+		return
+	}
+	if p.Name != nil {
+		setAuditDetail(c, "name="+*p.Name)
+	}
+	operatorID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.AdminUpdateCommunity(operatorID, communityID, p); err != nil {
+		zap.L().Error("logic.AdminUpdateCommunity failed", zap.Int64("communityID", communityID), zap.Error(err))
+		if errors.Is(err, sql.ErrNoRows) {
+			ResponseError(c, CodeCommNotExist)
+			return
+		}
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// GetMaintenanceJobsHandler 管理员查看维护任务的运行状态和投票同步进度
+func GetMaintenanceJobsHandler(c *gin.Context) {
+	operatorID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	status, err := logic.GetMaintenanceStatus(operatorID)
+	if err != nil {
+		zap.L().Error("logic.GetMaintenanceStatus failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, status)
+}
+
+// RunMaintenanceJobHandler 管理员在后台运行维护任务，立即返回，结果通过任务状态查看
+func RunMaintenanceJobHandler(c *gin.Context) {
+	name := c.Param("name")
+	setAuditDetail(c, "job="+name)
+	operatorID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.RunMaintenanceJob(operatorID, name); err != nil {
+		zap.L().Error("logic.RunMaintenanceJob failed", zap.String("job", name), zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"bluebell/logic"
+	"bluebell/models"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// --------- 审计日志 -----------
+
+// 审计中间件从上下文中读取的信息，由响应函数和处理函数写入
+const (
+	CtxResponseCodeKey = "response_code" // 接口返回的业务状态码
+	CtxAuditActorKey   = "audit_actor"   // 没有登录状态时的操作人，如登录接口
+	CtxAuditTargetKey  = "audit_target"  // 不在路径参数中的操作对象
+	CtxAuditDetailKey  = "audit_detail"  // 补充说明
+)
+
+// setAuditActor 指定审计日志的操作人
+func setAuditActor(c *gin.Context, userID int64) {
+	c.Set(CtxAuditActorKey, userID)
+}
+
+// setAuditTarget 指定审计日志的操作对象
+func setAuditTarget(c *gin.Context, targetID int64) {
+	c.Set(CtxAuditTargetKey, targetID)
+}
+
+// setAuditDetail 补充审计日志的说明
+func setAuditDetail(c *gin.Context, detail string) {
+	c.Set(CtxAuditDetailKey, detail)
+}
+
+// GetAuditLogsHandler 管理员查询审计日志
+// GET请求参数（query string）: /api/admin/audit?actor_id=1&action=login&success=false&start_time=1700000000&end_time=1700086400&offset=1&limit=20
+func GetAuditLogsHandler(c *gin.Context) {
+	p := new(models.ParamAuditLogList)
+	if err := c.ShouldBindQuery(p); err != nil {
+		zap.L().Error("GetAuditLogsHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	logs, err := logic.GetAuditLogs(userID, p)
+	if err != nil {
+		zap.L().Error("logic.GetAuditLogs failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, logs)
+}
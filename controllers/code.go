@@ -16,6 +16,25 @@ const (
 	CodeOTPInvalid
 	CodeModifyNil
 	CodeCommNotExist
+	CodeCommentNotExist
+	CodeNoPermission
+	CodePostNotExist
+	CodePostLocked
+	CodeBannedInCommunity
+	CodePinLimit
+	CodeNotCommunityMember
+	CodeCommunityPrivate
+	CodePostTypeNotAllowed
+	CodeJoinRequestNotExist
+	CodeCommunityArchived
+	CodeReportNotExist
+	CodeReportClosed
+	CodeSensitiveContent
+	CodeTrustLevelTooLow
+	CodeUserSuspended
+	CodeCommunityNameExist
+	CodeJobRunning
+	CodeEmailUnavailable
 )
 
 var codeMsgMap = map[int]string{
@@ -32,6 +51,26 @@ var codeMsgMap = map[int]string{
 	CodeOTPInvalid:      "验证码无效",
 	CodeModifyNil:       "不允许修改",
 	CodeCommNotExist:    "社区不存在",
+	CodeCommentNotExist: "评论不存在",
+	CodeNoPermission:    "没有权限",
+
+	CodePostNotExist:        "帖子不存在",
+	CodePostLocked:          "帖子已锁定",
+	CodeBannedInCommunity:   "已被禁止在该社区发言",
+	CodePinLimit:            "置顶帖子数量已达上限",
+	CodeNotCommunityMember:  "不是社区成员",
+	CodeCommunityPrivate:    "私密社区，只有成员可以浏览",
+	CodePostTypeNotAllowed:  "社区不允许发布该类型的帖子",
+	CodeJoinRequestNotExist: "加入申请不存在",
+	CodeCommunityArchived:   "社区已归档，只能浏览",
+	CodeReportNotExist:      "举报单不存在",
+	CodeReportClosed:        "举报单已处理",
+	CodeSensitiveContent:    "内容包含敏感词",
+	CodeTrustLevelTooLow:    "账号信任等级不足",
+	CodeUserSuspended:       "账号已被封禁",
+	CodeCommunityNameExist:  "社区名称已存在",
+	CodeJobRunning:          "维护任务正在运行",
+	CodeEmailUnavailable:    "邮件服务暂不可用",
 }
 
 func (code ResCode) Msg() string {
@@ -1,8 +1,10 @@
 package controllers
 
 import (
+	"bluebell/dao/mysql"
 	"bluebell/logic"
 	"bluebell/models"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
@@ -38,6 +40,14 @@ func CreateCommentConntroller(c *gin.Context) {
 	commentID, err := logic.CreateComment(req.PostID, req.ParentID, req.UserID, req.Content)
 	if err != nil {
 		zap.L().Error("CreateCommentConntroller: logic.CreateComment", zap.Error(err))
+		if errors.Is(err, mysql.ErrorCommentNotExist) {
+			ResponseError(c, CodeCommentNotExist)
+			return
+		}
+		if code, ok := moderationErrorCode(err); ok {
+			ResponseError(c, code)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
 		return
 	}
@@ -48,6 +58,7 @@ func CreateCommentConntroller(c *gin.Context) {
 }
 
 // GetCommentConntroller 获取顶级评论的处理函数
+// GET请求参数: /api/v1/comment/:post_id?sort=best，sort 可选 new(默认)/best/top/controversial
 func GetCommentConntroller(c *gin.Context) {
 	// 从 URL 路径获取 post_id 参数
 	postIDStr := c.Param("post_id")
@@ -57,9 +68,21 @@ func GetCommentConntroller(c *gin.Context) {
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
-	if comments, err := logic.GetCommentByPostID(postID); err != nil {
+	p := &models.ParamCommentList{Sort: models.CommentSortNew}
+	if err := c.ShouldBindQuery(p); err != nil {
+		zap.L().Error("GetCommentConntroller with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("获取用户id失败", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if comments, err := logic.GetCommentByPostID(c, userID, postID, p.Sort); err != nil {
 		zap.L().Error("GetCommentConntroller: logic.GetCommentByPostID", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		responseCommentError(c, err)
 		return
 	} else {
 		ResponseSuccess(c, comments)
@@ -76,17 +99,98 @@ func GetChildCommentsController(c *gin.Context) {
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
-	comments, err := logic.GetChildComments(parentID)
+	p := &models.ParamCommentList{Sort: models.CommentSortNew}
+	if err := c.ShouldBindQuery(p); err != nil {
+		zap.L().Error("GetChildCommentsController with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("获取用户id失败", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	comments, err := logic.GetChildComments(c, userID, parentID, p.Sort)
 	if err != nil {
 		zap.L().Error("logic.GetChildComments error", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		responseCommentError(c, err)
 		return
 	}
 
 	ResponseSuccess(c, comments)
 }
 
+// GetCommentTreeController 获取帖子的评论树
+// GET请求参数: /api/v1/comment/tree/:post_id?sort=best&max_depth=5&limit=20&cursor=xxx
+// cursor 为上次返回的 more 令牌，用于继续加载某条评论下的子评论或更多顶级评论
+func GetCommentTreeController(c *gin.Context) {
+	postID, err := strconv.ParseInt(c.Param("post_id"), 10, 64)
+	if err != nil {
+		zap.L().Error("invalid post_id", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p := &models.ParamCommentTree{
+		Sort:     models.CommentSortNew,
+		MaxDepth: 5,
+		Limit:    20,
+	}
+	if err := c.ShouldBindQuery(p); err != nil {
+		zap.L().Error("GetCommentTreeController with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("获取用户id失败", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	tree, err := logic.GetCommentTree(c, userID, postID, p)
+	if err != nil {
+		zap.L().Error("logic.GetCommentTree error", zap.Error(err))
+		if errors.Is(err, logic.ErrInvalidCursor) {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		responseCommentError(c, err)
+		return
+	}
+	ResponseSuccess(c, tree)
+}
+
+// CommentVoteController 用户为评论投票
+func CommentVoteController(c *gin.Context) {
+	p := new(models.ParamCommentVote)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("CommentVoteController ShouldBindJSON error", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMcg(c, CodeInvalidParam, removeTopStruct(errs.Translate(trans)))
+		return
+	}
+
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.VoteForComment(c, userID, p); err != nil {
+		zap.L().Error("logic.VoteForComment error", zap.Error(err))
+		responseCommentError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
 // DeleteCommentController 删除评论处理函数
+// 作者可以删除自己的评论，帖子作者和社区版主可以移除帖子下的评论
 func DeleteCommentController(c *gin.Context) {
 	commentIDParam := new(models.ParamDeleteComment)
 	if err := c.ShouldBindJSON(commentIDParam); err != nil {
@@ -94,11 +198,20 @@ func DeleteCommentController(c *gin.Context) {
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
+	setAuditTarget(c, commentIDParam.CommentID)
+	setAuditDetail(c, "reason="+commentIDParam.Reason)
+
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("获取用户id失败", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
 
 	// 调用业务逻辑层删除评论
-	if err := logic.DeleteComment(commentIDParam.CommentID); err != nil {
+	if err := logic.DeleteComment(commentIDParam.CommentID, userID, commentIDParam.Reason); err != nil {
 		zap.L().Error("DeleteCommentController: logic.DeleteComment", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		responseCommentError(c, err)
 		return
 	}
 
@@ -106,6 +219,47 @@ func DeleteCommentController(c *gin.Context) {
 	ResponseSuccess(c, nil)
 }
 
+// EditCommentController 作者编辑自己的评论
+func EditCommentController(c *gin.Context) {
+	p := new(models.ParamEditComment)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("EditCommentController: ShouldBindJSON(&req)", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMcg(c, CodeInvalidParam, removeTopStruct(errs.Translate(trans)))
+		return
+	}
+
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("获取用户id失败", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+
+	if err := logic.EditComment(userID, p); err != nil {
+		zap.L().Error("EditCommentController: logic.EditComment", zap.Error(err))
+		responseCommentError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// responseCommentError 根据评论操作的错误返回对应的响应
+func responseCommentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mysql.ErrorCommentNotExist), errors.Is(err, logic.ErrCommentDeleted):
+		ResponseError(c, CodeCommentNotExist)
+	case errors.Is(err, logic.ErrNotCommentAuthor), errors.Is(err, logic.ErrNoCommentPermission):
+		ResponseError(c, CodeNoPermission)
+	default:
+		responseModerationError(c, err)
+	}
+}
+
 // PinCommentController 处理置顶评论的请求
 // 例如，路由为：DELETE 或 POST /comment/pin/:comment_id
 func PinCommentController(c *gin.Context) {
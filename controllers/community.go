@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
+	"net/http"
 	"strconv"
 )
 
@@ -39,13 +40,30 @@ func CreateCommunityHandler(c *gin.Context) {
 			return
 		}
 	}
-	// 2. 创建社区
+	// 2. 当前用户成为社区的创建者
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	comm.OwnerID = userID
+	setAuditTarget(c, comm.ID)
+	// 3. 创建社区
 	if err := logic.CreateCommunity(comm); err != nil {
 		zap.L().Error("Create community failed", zap.Error(err))
+		if errors.Is(err, logic.ErrSensitiveContent) {
+			ResponseError(c, CodeSensitiveContent)
+			return
+		}
+		if errors.Is(err, logic.ErrTrustLevelTooLow) {
+			ResponseError(c, CodeTrustLevelTooLow)
+			return
+		}
 		ResponseError(c, CodeServerBusy)
 		return
 	}
-	// 3. 返回响应
+	// 4. 返回响应
 	ResponseSuccess(c, nil)
 }
 
@@ -100,3 +118,309 @@ func CommunityDetailHandler(c *gin.Context) {
 	}
 
 }
+
+// JoinCommunityHandler 加入社区，受限和私密社区提交加入申请
+func JoinCommunityHandler(c *gin.Context) {
+	communityID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	pending, err := logic.JoinCommunity(c, communityID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ResponseError(c, CodeCommNotExist)
+			return
+		}
+		zap.L().Error("logic.JoinCommunity failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	// 受限和私密社区需要等待版主批准
+	ResponseSuccess(c, gin.H{"pending": pending})
+}
+
+// LeaveCommunityHandler 退出社区
+func LeaveCommunityHandler(c *gin.Context) {
+	communityID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.LeaveCommunity(c, communityID, userID); err != nil {
+		zap.L().Error("logic.LeaveCommunity failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// GetMyCommunitiesHandler 获取当前用户加入的社区
+func GetMyCommunitiesHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	data, err := logic.GetUserCommunities(userID)
+	if err != nil {
+		zap.L().Error("logic.GetUserCommunities failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// GetHomeFeedHandler 首页：按热度获取已加入社区中的帖子
+// GET请求参数（query string）: /api/v1/feed?offset=1&limit=10
+func GetHomeFeedHandler(c *gin.Context) {
+	p := &models.ParamPostList{
+		Offset: 1,
+		Limit:  10,
+		Order:  models.OrderScore,
+	}
+	if err := c.ShouldBindQuery(p); err != nil {
+		zap.L().Error("Get home feed with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	data, err := logic.GetHomeFeed(c, userID, p)
+	if err != nil {
+		zap.L().Error("logic.GetHomeFeed failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// GetCommunitySettingsHandler 获取社区设置
+func GetCommunitySettingsHandler(c *gin.Context) {
+	communityID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	settings, err := logic.GetCommunitySettings(communityID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ResponseError(c, CodeCommNotExist)
+			return
+		}
+		zap.L().Error("logic.GetCommunitySettings failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, settings)
+}
+
+// UpdateCommunitySettingsHandler 版主修改社区设置
+func UpdateCommunitySettingsHandler(c *gin.Context) {
+	communityID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	p := new(models.ParamCommunitySettings)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("UpdateCommunitySettingsHandler with invalid param", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMcg(c, CodeInvalidParam, removeTopStruct(errs.Translate(trans)))
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.UpdateCommunitySettings(communityID, userID, p); err != nil {
+		zap.L().Error("logic.UpdateCommunitySettings failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// UpdateCommunityHandler 版主修改社区简介和设置，只修改传入的字段
+func UpdateCommunityHandler(c *gin.Context) {
+	communityID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	p := new(models.ParamUpdateCommunity)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("UpdateCommunityHandler with invalid param", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMcg(c, CodeInvalidParam, removeTopStruct(errs.Translate(trans)))
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.UpdateCommunity(communityID, userID, p); err != nil {
+		zap.L().Error("logic.UpdateCommunity failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// ArchiveCommunityHandler 创建者归档（POST）或取消归档（DELETE）社区
+func ArchiveCommunityHandler(c *gin.Context) {
+	communityID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.ArchiveCommunity(communityID, userID, c.Request.Method != http.MethodDelete); err != nil {
+		zap.L().Error("logic.ArchiveCommunity failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// GetCommunityStatsHandler 获取社区最近若干天每天的统计数据
+// GET请求参数（query string）: /api/v1/community/:id/stats?days=30
+func GetCommunityStatsHandler(c *gin.Context) {
+	communityID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	p := new(models.ParamCommunityStats)
+	if err := c.ShouldBindQuery(p); err != nil {
+		zap.L().Error("GetCommunityStatsHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	stats, err := logic.GetCommunityStats(communityID, userID, p.Days)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ResponseError(c, CodeCommNotExist)
+			return
+		}
+		zap.L().Error("logic.GetCommunityStats failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, stats)
+}
+
+// InviteCommunityMemberHandler 版主邀请用户加入社区
+func InviteCommunityMemberHandler(c *gin.Context) {
+	communityID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	p := new(models.ParamInviteMember)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("InviteCommunityMemberHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.InviteCommunityMember(communityID, userID, p.UserID); err != nil {
+		zap.L().Error("logic.InviteCommunityMember failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// GetJoinRequestsHandler 版主查看待处理的加入申请
+func GetJoinRequestsHandler(c *gin.Context) {
+	communityID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	reqs, err := logic.GetJoinRequests(communityID, userID)
+	if err != nil {
+		zap.L().Error("logic.GetJoinRequests failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, reqs)
+}
+
+// ApproveJoinRequestHandler 版主批准加入申请
+func ApproveJoinRequestHandler(c *gin.Context) {
+	reviewJoinRequest(c, true)
+}
+
+// RejectJoinRequestHandler 版主拒绝加入申请
+func RejectJoinRequestHandler(c *gin.Context) {
+	reviewJoinRequest(c, false)
+}
+
+func reviewJoinRequest(c *gin.Context, approve bool) {
+	communityID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	applicantID, ok := parseIDParam(c, "user_id")
+	if !ok {
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.ReviewJoinRequest(c, communityID, userID, applicantID, approve); err != nil {
+		zap.L().Error("logic.ReviewJoinRequest failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"bluebell/logic"
+	"bluebell/models"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// --------- 社区发现 -----------
+
+// SearchCommunitiesHandler 按名称和简介搜索社区
+// GET请求参数（query string）: /api/v1/community/search?q=go&offset=1&limit=10
+func SearchCommunitiesHandler(c *gin.Context) {
+	p := new(models.ParamCommunitySearch)
+	if err := c.ShouldBindQuery(p); err != nil {
+		zap.L().Error("SearchCommunitiesHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	data, err := logic.SearchCommunities(userID, p)
+	if err != nil {
+		zap.L().Error("logic.SearchCommunities failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// SuggestCommunitiesHandler 社区名称自动补全
+// GET请求参数（query string）: /api/v1/community/suggest?prefix=go&limit=10
+func SuggestCommunitiesHandler(c *gin.Context) {
+	p := new(models.ParamCommunitySuggest)
+	if err := c.ShouldBindQuery(p); err != nil {
+		zap.L().Error("SuggestCommunitiesHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	data, err := logic.SuggestCommunities(userID, p)
+	if err != nil {
+		zap.L().Error("logic.SuggestCommunities failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// GetTrendingCommunitiesHandler 获取活跃度增长最快的社区
+// GET请求参数（query string）: /api/v1/community/trending?limit=10
+func GetTrendingCommunitiesHandler(c *gin.Context) {
+	p := new(models.ParamCommunityDiscover)
+	if err := c.ShouldBindQuery(p); err != nil {
+		zap.L().Error("GetTrendingCommunitiesHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	data, err := logic.GetTrendingCommunities(userID, p.Limit)
+	if err != nil {
+		zap.L().Error("logic.GetTrendingCommunities failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// GetRecommendedCommunitiesHandler 获取当前用户可能喜欢的社区
+// GET请求参数（query string）: /api/v1/community/recommend?limit=10
+func GetRecommendedCommunitiesHandler(c *gin.Context) {
+	p := new(models.ParamCommunityDiscover)
+	if err := c.ShouldBindQuery(p); err != nil {
+		zap.L().Error("GetRecommendedCommunitiesHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	data, err := logic.GetRecommendedCommunities(c, userID, p.Limit)
+	if err != nil {
+		zap.L().Error("logic.GetRecommendedCommunities failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/logic"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"strconv"
+)
+
+// responseFollowError 根据关注操作的错误返回对应的响应
+func responseFollowError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mysql.ErrorUserNotExist):
+		ResponseError(c, CodeUserNotExist)
+	case errors.Is(err, logic.ErrFollowSelf):
+		ResponseError(c, CodeInvalidParam)
+	case errors.Is(err, logic.ErrUserBlocked):
+		ResponseError(c, CodeNoPermission)
+	default:
+		ResponseError(c, CodeServerBusy)
+	}
+}
+
+// FollowUserHandler 关注用户
+func FollowUserHandler(c *gin.Context) {
+	followeeID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		zap.L().Error("invalid user id", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.FollowUser(userID, followeeID); err != nil {
+		zap.L().Error("logic.FollowUser error", zap.Error(err))
+		responseFollowError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// UnfollowUserHandler 取消关注用户
+func UnfollowUserHandler(c *gin.Context) {
+	followeeID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		zap.L().Error("invalid user id", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.UnfollowUser(userID, followeeID); err != nil {
+		zap.L().Error("logic.UnfollowUser error", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// GetFollowersHandler 查询关注了当前用户的用户
+func GetFollowersHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	users, err := logic.GetFollowers(userID)
+	if err != nil {
+		zap.L().Error("logic.GetFollowers error", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, users)
+}
+
+// GetFollowingHandler 查询当前用户关注的用户
+func GetFollowingHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	users, err := logic.GetFollowing(userID)
+	if err != nil {
+		zap.L().Error("logic.GetFollowing error", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, users)
+}
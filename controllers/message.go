@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"strconv"
+)
+
+// responseMessageError 根据私信操作的错误返回对应的响应
+func responseMessageError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mysql.ErrorUserNotExist):
+		ResponseError(c, CodeUserNotExist)
+	case errors.Is(err, logic.ErrMessageSelf):
+		ResponseError(c, CodeInvalidParam)
+	case errors.Is(err, logic.ErrUserBlocked), errors.Is(err, logic.ErrNotConversationMember):
+		ResponseError(c, CodeNoPermission)
+	case errors.Is(err, logic.ErrNewConversationLimit):
+		ResponseError(c, CodeRateLimit)
+	default:
+		ResponseError(c, CodeServerBusy)
+	}
+}
+
+// SendMessageHandler 发送私信
+func SendMessageHandler(c *gin.Context) {
+	p := new(models.ParamSendMessage)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("SendMessageHandler ShouldBindJSON error", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMcg(c, CodeInvalidParam, removeTopStruct(errs.Translate(trans)))
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	msg, err := logic.SendMessage(c, userID, p)
+	if err != nil {
+		zap.L().Error("logic.SendMessage error", zap.Error(err))
+		responseMessageError(c, err)
+		return
+	}
+	ResponseSuccess(c, msg)
+}
+
+// GetConversationsHandler 分页查询当前用户的会话列表
+// GET请求参数（query string）: /api/v1/conversations?offset=1&limit=20
+func GetConversationsHandler(c *gin.Context) {
+	offset, limit := getPageInfo(c)
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	convs, err := logic.GetConversations(userID, offset, limit)
+	if err != nil {
+		zap.L().Error("logic.GetConversations error", zap.Error(err))
+		responseMessageError(c, err)
+		return
+	}
+	ResponseSuccess(c, convs)
+}
+
+// GetMessagesHandler 查询会话中的消息，按时间倒序
+// GET请求参数（query string）: /api/v1/conversations/:id/messages?before=xxx&limit=20
+func GetMessagesHandler(c *gin.Context) {
+	conversationID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		zap.L().Error("invalid conversation id", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p := &models.ParamMessageList{Limit: 20}
+	if err := c.ShouldBindQuery(p); err != nil {
+		zap.L().Error("GetMessagesHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	msgs, err := logic.GetMessages(userID, conversationID, p)
+	if err != nil {
+		zap.L().Error("logic.GetMessages error", zap.Error(err))
+		responseMessageError(c, err)
+		return
+	}
+	ResponseSuccess(c, msgs)
+}
+
+// MarkConversationReadHandler 将会话标记为已读
+func MarkConversationReadHandler(c *gin.Context) {
+	conversationID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		zap.L().Error("invalid conversation id", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.MarkConversationRead(userID, conversationID); err != nil {
+		zap.L().Error("logic.MarkConversationRead error", zap.Error(err))
+		responseMessageError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// GetUnreadMessageCountHandler 查询当前用户的未读私信数量
+func GetUnreadMessageCountHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	count, err := logic.GetUnreadMessageCount(userID)
+	if err != nil {
+		zap.L().Error("logic.GetUnreadMessageCount error", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, gin.H{"unread": count})
+}
+
+// BlockUserHandler 屏蔽用户
+func BlockUserHandler(c *gin.Context) {
+	blockedID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		zap.L().Error("invalid user id", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.BlockUser(userID, blockedID); err != nil {
+		zap.L().Error("logic.BlockUser error", zap.Error(err))
+		responseMessageError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// UnblockUserHandler 取消屏蔽用户
+func UnblockUserHandler(c *gin.Context) {
+	blockedID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		zap.L().Error("invalid user id", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.UnblockUser(userID, blockedID); err != nil {
+		zap.L().Error("logic.UnblockUser error", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// GetBlockedUsersHandler 查询当前用户屏蔽的用户
+func GetBlockedUsersHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	users, err := logic.GetBlockedUsers(userID)
+	if err != nil {
+		zap.L().Error("logic.GetBlockedUsers error", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, users)
+}
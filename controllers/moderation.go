@@ -0,0 +1,317 @@
+package controllers
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+)
+
+// --------- 社区管理 -----------
+
+// moderationErrorCode 社区管理相关的错误对应的响应码，不是这类错误时返回false
+func moderationErrorCode(err error) (ResCode, bool) {
+	switch {
+	case errors.Is(err, mysql.ErrorPostNotExist), errors.Is(err, logic.ErrPostRemoved):
+		return CodePostNotExist, true
+	case errors.Is(err, mysql.ErrorUserNotExist):
+		return CodeUserNotExist, true
+	case errors.Is(err, logic.ErrPostLocked):
+		return CodePostLocked, true
+	case errors.Is(err, logic.ErrBannedFromCommunity):
+		return CodeBannedInCommunity, true
+	case errors.Is(err, logic.ErrPinnedPostLimit):
+		return CodePinLimit, true
+	case errors.Is(err, logic.ErrNotCommunityMember), errors.Is(err, logic.ErrCommunityRestricted):
+		return CodeNotCommunityMember, true
+	case errors.Is(err, logic.ErrCommunityPrivate):
+		return CodeCommunityPrivate, true
+	case errors.Is(err, logic.ErrPostTypeNotAllowed):
+		return CodePostTypeNotAllowed, true
+	case errors.Is(err, logic.ErrJoinRequestNotExist):
+		return CodeJoinRequestNotExist, true
+	case errors.Is(err, logic.ErrCommunityArchived):
+		return CodeCommunityArchived, true
+	case errors.Is(err, logic.ErrReportNotExist):
+		return CodeReportNotExist, true
+	case errors.Is(err, logic.ErrReportClosed):
+		return CodeReportClosed, true
+	case errors.Is(err, logic.ErrReportSelf):
+		return CodeInvalidParam, true
+	case errors.Is(err, logic.ErrSensitiveContent):
+		return CodeSensitiveContent, true
+	case errors.Is(err, logic.ErrActionRateLimited):
+		return CodeRateLimit, true
+	case errors.Is(err, logic.ErrTrustLevelTooLow):
+		return CodeTrustLevelTooLow, true
+	case errors.Is(err, logic.ErrCommunityNameExist):
+		return CodeCommunityNameExist, true
+	case errors.Is(err, logic.ErrJobRunning):
+		return CodeJobRunning, true
+	case errors.Is(err, logic.ErrJobNotExist):
+		return CodeInvalidParam, true
+	case errors.Is(err, logic.ErrNotCommunityOwner), errors.Is(err, logic.ErrNotModerator),
+		errors.Is(err, logic.ErrModeratorProtected), errors.Is(err, logic.ErrOwnerCannotLeave),
+		errors.Is(err, logic.ErrNotSiteAdmin), errors.Is(err, logic.ErrSanctionProtected),
+		errors.Is(err, logic.ErrAdminRoleProtected):
+		return CodeNoPermission, true
+	}
+	return CodeServerBusy, false
+}
+
+// responseModerationError 根据社区管理相关的错误返回对应的响应
+func responseModerationError(c *gin.Context, err error) {
+	code, _ := moderationErrorCode(err)
+	ResponseError(c, code)
+}
+
+// parseIDParam 解析路径中的id参数，失败时返回参数错误响应
+func parseIDParam(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil {
+		zap.L().Error("invalid path param", zap.String("name", name), zap.String("value", c.Param(name)))
+		ResponseError(c, CodeInvalidParam)
+		return 0, false
+	}
+	return id, true
+}
+
+// GetModeratorsHandler 获取社区的创建者和版主
+func GetModeratorsHandler(c *gin.Context) {
+	communityID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	mods, err := logic.GetCommunityModerators(communityID)
+	if err != nil {
+		zap.L().Error("logic.GetCommunityModerators failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, mods)
+}
+
+// AppointModeratorHandler 创建者任命版主
+func AppointModeratorHandler(c *gin.Context) {
+	communityID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	p := new(models.ParamAppointModerator)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("AppointModeratorHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	setAuditTarget(c, p.UserID)
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.AppointModerator(communityID, userID, p.UserID); err != nil {
+		zap.L().Error("logic.AppointModerator failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// DismissModeratorHandler 创建者撤销版主
+func DismissModeratorHandler(c *gin.Context) {
+	communityID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	modID, ok := parseIDParam(c, "user_id")
+	if !ok {
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.DismissModerator(communityID, userID, modID); err != nil {
+		zap.L().Error("logic.DismissModerator failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// RemovePostHandler 版主移除帖子
+func RemovePostHandler(c *gin.Context) {
+	postID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	p := new(models.ParamModReason)
+	if err := c.ShouldBindJSON(p); err != nil && c.Request.ContentLength > 0 {
+		zap.L().Error("RemovePostHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	setAuditDetail(c, "reason="+p.Reason)
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.RemovePost(c, postID, userID, p.Reason); err != nil {
+		zap.L().Error("logic.RemovePost failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// PinPostHandler 版主置顶帖子（POST）或取消置顶（DELETE）
+func PinPostHandler(c *gin.Context) {
+	postID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.PinPost(postID, userID, c.Request.Method != http.MethodDelete); err != nil {
+		zap.L().Error("logic.PinPost failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// LockPostHandler 版主锁定帖子（POST）或解锁（DELETE）
+func LockPostHandler(c *gin.Context) {
+	postID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.LockPost(postID, userID, c.Request.Method != http.MethodDelete); err != nil {
+		zap.L().Error("logic.LockPost failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// BanCommunityUserHandler 版主在社区中禁言用户
+func BanCommunityUserHandler(c *gin.Context) {
+	communityID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	p := new(models.ParamCommunityBan)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("BanCommunityUserHandler with invalid param", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMcg(c, CodeInvalidParam, removeTopStruct(errs.Translate(trans)))
+		return
+	}
+	setAuditTarget(c, p.UserID)
+	setAuditDetail(c, fmt.Sprintf("hours=%d reason=%s", p.Hours, p.Reason))
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.BanCommunityUser(communityID, userID, p); err != nil {
+		zap.L().Error("logic.BanCommunityUser failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// UnbanCommunityUserHandler 版主解除禁言
+func UnbanCommunityUserHandler(c *gin.Context) {
+	communityID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	bannedID, ok := parseIDParam(c, "user_id")
+	if !ok {
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.UnbanCommunityUser(communityID, userID, bannedID); err != nil {
+		zap.L().Error("logic.UnbanCommunityUser failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// GetCommunityBansHandler 版主查看社区中的禁言
+func GetCommunityBansHandler(c *gin.Context) {
+	communityID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	bans, err := logic.GetCommunityBans(communityID, userID)
+	if err != nil {
+		zap.L().Error("logic.GetCommunityBans failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, bans)
+}
+
+// GetModerationLogsHandler 版主分页查看社区的管理日志
+// GET请求参数（query string）: /api/v1/community/:id/modlog?offset=1&limit=10
+func GetModerationLogsHandler(c *gin.Context) {
+	communityID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	offset, limit := getPageInfo(c)
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	logs, err := logic.GetModerationLogs(communityID, userID, offset, limit)
+	if err != nil {
+		zap.L().Error("logic.GetModerationLogs failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, logs)
+}
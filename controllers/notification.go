@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"strconv"
+)
+
+// GetNotificationsHandler 分页查询当前用户的通知及未读数量
+// GET请求参数（query string）: /api/v1/notifications?offset=1&limit=20&unread_only=true
+func GetNotificationsHandler(c *gin.Context) {
+	p := &models.ParamNotificationList{
+		Offset: 1,
+		Limit:  20,
+	}
+	if err := c.ShouldBindQuery(p); err != nil {
+		zap.L().Error("GetNotificationsHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	if p.Offset <= 0 || p.Limit <= 0 {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	data, err := logic.GetNotifications(userID, p)
+	if err != nil {
+		zap.L().Error("logic.GetNotifications error", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// GetUnreadNotificationCountHandler 查询当前用户的未读通知数量
+func GetUnreadNotificationCountHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	unread, err := logic.GetUnreadNotificationCount(userID)
+	if err != nil {
+		zap.L().Error("logic.GetUnreadNotificationCount error", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, gin.H{"unread": unread})
+}
+
+// MarkNotificationReadHandler 将一条通知标记为已读
+func MarkNotificationReadHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		zap.L().Error("invalid notification id", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.MarkNotificationRead(userID, id); err != nil {
+		zap.L().Error("logic.MarkNotificationRead error", zap.Error(err))
+		if errors.Is(err, mysql.ErrorNotificationNotExist) {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// MarkAllNotificationsReadHandler 将当前用户的全部通知标记为已读
+func MarkAllNotificationsReadHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.MarkAllNotificationsRead(userID); err != nil {
+		zap.L().Error("logic.MarkAllNotificationsRead error", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// GetNotificationPreferencesHandler 查询当前用户的通知设置
+func GetNotificationPreferencesHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	prefs, err := logic.GetNotificationPreferences(userID)
+	if err != nil {
+		zap.L().Error("logic.GetNotificationPreferences error", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, prefs)
+}
+
+// SetNotificationPreferenceHandler 开启或关闭一种通知
+func SetNotificationPreferenceHandler(c *gin.Context) {
+	p := new(models.ParamNotificationPreference)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("SetNotificationPreferenceHandler ShouldBindJSON error", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMcg(c, CodeInvalidParam, removeTopStruct(errs.Translate(trans)))
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.SetNotificationPreference(userID, p); err != nil {
+		zap.L().Error("logic.SetNotificationPreference error", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
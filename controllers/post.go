@@ -3,6 +3,8 @@ package controllers
 import (
 	"bluebell/logic"
 	"bluebell/models"
+	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -47,7 +49,7 @@ func CreatePostHandler(c *gin.Context) {
 	// 2. 创建帖子
 	if err := logic.CreatePost(c, p); err != nil {
 		zap.L().Error("Create post failed", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		responseModerationError(c, err)
 		return
 	}
 
@@ -78,7 +80,11 @@ func GetPostDetailHandler(c *gin.Context) {
 	var p *models.ApiPostDetail
 	if p, err = logic.GetPostDetail(uid, pid); err != nil {
 		zap.L().Error("logic.GetPostDetail(pid) failed", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		if errors.Is(err, sql.ErrNoRows) {
+			ResponseError(c, CodePostNotExist)
+			return
+		}
+		responseModerationError(c, err)
 		return
 	}
 
@@ -87,17 +93,19 @@ func GetPostDetailHandler(c *gin.Context) {
 }
 
 // GetPostListHandler 获取全部帖子详情的处理函数的升级版
-// 根据前端传来的参数（创建时间/分数）动态地获取帖子列表
+// 根据前端传来的参数（创建时间/热度/top/争议度/best/重力衰减）动态地获取帖子列表
 // 1. 获取参数
 // 2. 去redis查询id列表
 // 3. 根据id去数据库查询帖子信息
 func GetPostListHandler(c *gin.Context) {
 	// GET请求参数（query string）: /api/v1/post2?page=1&size=10&order=time
+	// order=top 时可以用 window=day/week/month 指定时间窗口
 	// 初始化结构体时指定初始参数
 	p := &models.ParamPostList{
 		Offset:       1,
 		Limit:        10,
 		Order:        models.OrderTime,
+		Window:       models.WindowDay,
 		Community_id: 0,
 	}
 	if err := c.ShouldBindQuery(p); err != nil {
@@ -106,11 +114,19 @@ func GetPostListHandler(c *gin.Context) {
 		return
 	}
 
+	// 获取当前用户，用于返回其对每个帖子的投票
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("Get user id failed", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+
 	// 获取全部帖子
-	ps, err := logic.GetPostListByScore(c, p)
+	ps, err := logic.GetPostListByScore(c, userID, p)
 	if err != nil {
 		zap.L().Error("logic.GetPostList failed", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		responseModerationError(c, err)
 		return
 	}
 	ResponseSuccess(c, ps)
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"bluebell/logic"
+	"bluebell/models"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"io"
+	"strconv"
+	"time"
+)
+
+// realtimeHeartbeat 没有事件时发送心跳的间隔，防止代理断开空闲连接
+const realtimeHeartbeat = 30 * time.Second
+
+// CreateStreamTicketHandler 生成建立事件流的一次性凭证，有效期一分钟
+func CreateStreamTicketHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	ticket, err := logic.CreateStreamTicket(c, userID)
+	if err != nil {
+		zap.L().Error("logic.CreateStreamTicket failed", zap.Int64("userID", userID), zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, gin.H{"ticket": ticket})
+}
+
+// RealtimeEventsHandler 通过 Server-Sent Events 推送实时事件
+// GET请求参数: /api/v1/events?post_id=xxx
+// 推送当前用户的新通知，带 post_id 时同时推送该帖子的新评论和票数变化
+// 浏览器的 EventSource 不能设置请求头，可以先通过 /api/v1/events/ticket 获取一次性凭证，放在 ticket 参数中
+func RealtimeEventsHandler(c *gin.Context) {
+	var postID int64
+	if s := c.Query("post_id"); s != "" {
+		var err error
+		if postID, err = strconv.ParseInt(s, 10, 64); err != nil {
+			zap.L().Error("invalid post_id", zap.Error(err))
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+
+	events, cancel, err := logic.SubscribeUserEvents(c, userID, postID)
+	if err != nil {
+		zap.L().Error("logic.SubscribeUserEvents error", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭 nginx 的缓冲
+	heartbeat := time.NewTicker(realtimeHeartbeat)
+	defer heartbeat.Stop()
+
+	c.SSEvent("ready", gin.H{"user_id": strconv.FormatInt(userID, 10), "post_id": strconv.FormatInt(postID, 10)})
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				// 服务关闭
+				return false
+			}
+			c.SSEvent(ev.Type, ev.Data)
+			// 账号被封禁，关闭事件流
+			return ev.Type != models.EventSessionClose
+		case <-heartbeat.C:
+			// 错过封禁事件时，由心跳时的检查关闭事件流
+			if err := logic.CheckUserNotSuspended(c, userID); err != nil {
+				c.SSEvent(models.EventSessionClose, &models.SessionCloseEvent{Reason: err.Error()})
+				return false
+			}
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"bluebell/logic"
+	"bluebell/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// --------- 举报及审核队列 -----------
+
+// SubmitReportHandler 举报帖子、评论或用户
+func SubmitReportHandler(c *gin.Context) {
+	p := new(models.ParamReport)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("SubmitReportHandler with invalid param", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMcg(c, CodeInvalidParam, removeTopStruct(errs.Translate(trans)))
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.SubmitReport(userID, p); err != nil {
+		zap.L().Error("logic.SubmitReport failed", zap.Error(err))
+		responseCommentError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// GetReportQueueHandler 分页获取审核队列
+// GET请求参数（query string）: /api/v1/reports?community_id=1&status=0&offset=1&limit=20
+func GetReportQueueHandler(c *gin.Context) {
+	p := new(models.ParamReportList)
+	if err := c.ShouldBindQuery(p); err != nil {
+		zap.L().Error("GetReportQueueHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	cases, err := logic.GetReportQueue(userID, p)
+	if err != nil {
+		zap.L().Error("logic.GetReportQueue failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, cases)
+}
+
+// GetReportCaseHandler 获取举报单及其中的全部举报
+func GetReportCaseHandler(c *gin.Context) {
+	caseID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	rc, err := logic.GetReportCase(caseID, userID)
+	if err != nil {
+		zap.L().Error("logic.GetReportCase failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, rc)
+}
+
+// ResolveReportHandler 举报成立，移除被举报的内容
+func ResolveReportHandler(c *gin.Context) {
+	caseID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	p := new(models.ParamReviewReport)
+	if err := c.ShouldBindJSON(p); err != nil && c.Request.ContentLength > 0 {
+		zap.L().Error("ResolveReportHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.ResolveReport(c, caseID, userID, p.Note); err != nil {
+		zap.L().Error("logic.ResolveReport failed", zap.Error(err))
+		responseCommentError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// DismissReportHandler 举报不成立，恢复被自动隐藏的内容
+func DismissReportHandler(c *gin.Context) {
+	caseID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	p := new(models.ParamReviewReport)
+	if err := c.ShouldBindJSON(p); err != nil && c.Request.ContentLength > 0 {
+		zap.L().Error("DismissReportHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.DismissReport(caseID, userID, p.Note); err != nil {
+		zap.L().Error("logic.DismissReport failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
@@ -12,6 +12,7 @@ type ResponseData struct {
 }
 
 func ResponseError(c *gin.Context, code ResCode) {
+	c.Set(CtxResponseCodeKey, code)
 	rd := &ResponseData{
 		Code: code,
 		Msg:  code.Msg(),
@@ -21,6 +22,7 @@ func ResponseError(c *gin.Context, code ResCode) {
 }
 
 func ResponseErrorWithMcg(c *gin.Context, code ResCode, msg interface{}) {
+	c.Set(CtxResponseCodeKey, code)
 	rd := &ResponseData{
 		Code: code,
 		Msg:  msg,
@@ -30,6 +32,7 @@ func ResponseErrorWithMcg(c *gin.Context, code ResCode, msg interface{}) {
 }
 
 func ResponseSuccess(c *gin.Context, data interface{}) {
+	c.Set(CtxResponseCodeKey, CodeSuccess)
 	rd := &ResponseData{
 		Code: CodeSuccess,
 		Msg:  codeMsgMap[CodeSuccess],
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"strconv"
+)
+
+// --------- 全站封禁和影子封禁 -----------
+
+// sanctionUser 管理员对路径中的用户施加某类处罚
+func sanctionUser(c *gin.Context, typ string) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p := new(models.ParamUserSanction)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("sanctionUser with invalid param", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMcg(c, CodeInvalidParam, removeTopStruct(errs.Translate(trans)))
+		return
+	}
+	setAuditDetail(c, fmt.Sprintf("reason=%s hours=%d note=%s", p.Reason, p.Hours, p.Note))
+	operatorID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.SanctionUser(operatorID, userID, typ, p); err != nil {
+		zap.L().Error("logic.SanctionUser failed", zap.String("type", typ), zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// liftSanction 管理员解除路径中的用户的某类处罚
+func liftSanction(c *gin.Context, typ string) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	operatorID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.LiftSanction(operatorID, userID, typ); err != nil {
+		zap.L().Error("logic.LiftSanction failed", zap.String("type", typ), zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// SuspendUserHandler 管理员封禁用户，hours 为 0 时永久封禁
+func SuspendUserHandler(c *gin.Context) {
+	sanctionUser(c, models.SanctionSuspend)
+}
+
+// UnsuspendUserHandler 管理员解除封禁
+func UnsuspendUserHandler(c *gin.Context) {
+	liftSanction(c, models.SanctionSuspend)
+}
+
+// ShadowBanUserHandler 管理员影子封禁用户，hours 为 0 时永久
+func ShadowBanUserHandler(c *gin.Context) {
+	sanctionUser(c, models.SanctionShadowBan)
+}
+
+// UnshadowBanUserHandler 管理员解除影子封禁
+func UnshadowBanUserHandler(c *gin.Context) {
+	liftSanction(c, models.SanctionShadowBan)
+}
+
+// GetUserSanctionsHandler 管理员查看用户尚未到期的处罚
+func GetUserSanctionsHandler(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	operatorID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	list, err := logic.GetUserSanctions(operatorID, userID)
+	if err != nil {
+		zap.L().Error("logic.GetUserSanctions failed", zap.Error(err))
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, list)
+}
+
+// AccountStatusHandler 查询账号状态，被封禁时说明原因和解封时间
+// 被封禁的用户不能登录，所以用用户名和密码查询
+func AccountStatusHandler(c *gin.Context) {
+	p := new(models.ParamAccountStatus)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("AccountStatusHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	status, err := logic.GetAccountStatus(p)
+	if err != nil {
+		zap.L().Error("logic.GetAccountStatus failed", zap.String("username", p.Username), zap.Error(err))
+		switch {
+		case errors.Is(err, mysql.ErrorUserNotExist):
+			ResponseError(c, CodeUserNotExist)
+		case errors.Is(err, mysql.ErrorUserPassword):
+			ResponseError(c, CodeInvalidPassword)
+		default:
+			ResponseError(c, CodeServerBusy)
+		}
+		return
+	}
+	ResponseSuccess(c, status)
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// --------- 信任等级及邮箱验证 -----------
+
+// responseTrustError 根据信任等级和邮箱验证相关的错误返回对应的响应
+func responseTrustError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mysql.ErrorUserNotExist):
+		ResponseError(c, CodeUserNotExist)
+	case errors.Is(err, logic.ErrEmailNotSet):
+		ResponseError(c, CodeInvalidParam)
+	case errors.Is(err, logic.ErrEmailUnavailable):
+		ResponseError(c, CodeEmailUnavailable)
+	case errors.Is(err, logic.ErrorOPTExpired):
+		ResponseError(c, CodeOTPExpired)
+	case errors.Is(err, logic.ErrorOPTInvalid):
+		ResponseError(c, CodeOTPInvalid)
+	default:
+		ResponseError(c, CodeServerBusy)
+	}
+}
+
+// GetUserTrustHandler 查询当前用户的信任等级及每小时配额
+func GetUserTrustHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	trust, err := logic.GetUserTrust(userID)
+	if err != nil {
+		zap.L().Error("logic.GetUserTrust failed", zap.Error(err))
+		responseTrustError(c, err)
+		return
+	}
+	ResponseSuccess(c, trust)
+}
+
+// SendEmailCodeHandler 向当前用户的邮箱发送验证码
+func SendEmailCodeHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.SendEmailCode(c, userID); err != nil {
+		zap.L().Error("logic.SendEmailCode failed", zap.Error(err))
+		responseTrustError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// VerifyEmailHandler 用验证码验证当前用户的邮箱
+func VerifyEmailHandler(c *gin.Context) {
+	p := new(models.ParamVerifyEmail)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("VerifyEmailHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.VerifyEmail(c, userID, p.Code); err != nil {
+		zap.L().Error("logic.VerifyEmail failed", zap.Error(err))
+		responseTrustError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
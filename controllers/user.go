@@ -38,6 +38,10 @@ func SignUpHandler(c *gin.Context) {
 			ResponseError(c, CodeUserExist)
 			return
 		}
+		if errors.Is(err, logic.ErrSensitiveContent) {
+			ResponseError(c, CodeSensitiveContent)
+			return
+		}
 		ResponseError(c, CodeServerBusy)
 		return
 	}
@@ -62,6 +66,7 @@ func LogInHandler(c *gin.Context) {
 		return
 	}
 
+	setAuditDetail(c, "username="+p.Username)
 	// 2. 业务处理
 	user, err := logic.LogIn(c, p)
 	if err != nil {
@@ -82,10 +87,15 @@ func LogInHandler(c *gin.Context) {
 			ResponseError(c, CodeOTPInvalid)
 			return
 		}
+		if errors.Is(err, logic.ErrUserSuspended) {
+			ResponseError(c, CodeUserSuspended)
+			return
+		}
 		ResponseError(c, CodeServerBusy)
 		return
 	}
 
+	setAuditActor(c, user.UserID)
 	ResponseSuccess(c, gin.H{
 		"userid":   fmt.Sprintf("%d", user.UserID), // id值大于2**53-1时，json超出范围
 		"username": user.Username,
@@ -178,6 +188,10 @@ func ModifyUserInfoHandler(c *gin.Context) {
 			ResponseError(c, CodeModifyNil)
 			return
 		}
+		if errors.Is(err, logic.ErrSensitiveContent) {
+			ResponseError(c, CodeSensitiveContent)
+			return
+		}
 		ResponseError(c, CodeServerBusy)
 		return
 	}
@@ -267,7 +281,13 @@ func GetUserPostHandler(c *gin.Context) {
 	}
 	// 处理获取用户帖子的逻辑
 	var userpost *models.UserPost
-	userpost, err = logic.GetUserPosts(userID)
+	viewerID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("Get user id failed", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	userpost, err = logic.GetUserPosts(viewerID, userID)
 	if err != nil {
 		zap.L().Error("logic.GetUserPosts failed", zap.Error(err))
 		ResponseError(c, CodeServerBusy)
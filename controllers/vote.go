@@ -34,8 +34,45 @@ func PostVoteController(c *gin.Context) {
 	// 投票
 	if err := logic.VoteForPost(c, userid, p); err != nil {
 		zap.L().Error("logic.VoteForPost error", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		responseModerationError(c, err)
 		return
 	}
 	ResponseSuccess(c, nil)
 }
+
+// GetUserVotesHandler 分页查询当前用户投过票的帖子
+// GET请求参数（query string）: /api/v1/user/votes?offset=1&limit=10&direction=1
+func GetUserVotesHandler(c *gin.Context) {
+	p := &models.ParamVoteList{
+		Offset: 1,
+		Limit:  10,
+	}
+	if err := c.ShouldBindQuery(p); err != nil {
+		zap.L().Error("GetUserVotesHandler with invalid param", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMcg(c, CodeInvalidParam, removeTopStruct(errs.Translate(trans)))
+		return
+	}
+	if p.Offset <= 0 || p.Limit <= 0 {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		zap.L().Error("getCurrentUserID error", zap.Error(err))
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	posts, err := logic.GetUserVotedPosts(userID, p)
+	if err != nil {
+		zap.L().Error("logic.GetUserVotedPosts error", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, posts)
+}
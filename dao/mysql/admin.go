@@ -0,0 +1,79 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"database/sql"
+	"errors"
+	"strconv"
+)
+
+// GetUserRole 查询用户的全站角色，用户不存在时返回普通用户
+func GetUserRole(userID int64) (int8, error) {
+	var role int8
+	err := db.Get(&role, `SELECT role FROM user WHERE user_id = ?`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.UserRoleUser, nil
+	}
+	return role, err
+}
+
+// SetUserRole 修改用户的全站角色
+func SetUserRole(userID int64, role int8) error {
+	_, err := db.Exec(`UPDATE user SET role = ? WHERE user_id = ?`, role, userID)
+	return err
+}
+
+// SearchUsers 按用户名或邮箱前缀分页搜索用户，关键词是数字时同时匹配用户id；没有关键词时按注册时间倒序列出全部用户
+func SearchUsers(keyword string, offset, limit int64) ([]*models.AdminUser, error) {
+	users := make([]*models.AdminUser, 0)
+	sqlStr := `SELECT user_id, username, COALESCE(email, '') AS email, email_verified, role, create_time, update_time
+				FROM user`
+	args := make([]interface{}, 0)
+	if keyword != "" {
+		prefix := likeEscaper.Replace(keyword) + "%"
+		sqlStr += ` WHERE username LIKE ? OR email LIKE ?`
+		args = append(args, prefix, prefix)
+		if id, err := strconv.ParseInt(keyword, 10, 64); err == nil {
+			sqlStr += ` OR user_id = ?`
+			args = append(args, id)
+		}
+	}
+	sqlStr += ` ORDER BY create_time DESC, user_id DESC LIMIT ?, ?`
+	args = append(args, (offset-1)*limit, limit)
+	err := db.Select(&users, sqlStr, args...)
+	return users, err
+}
+
+// GetAdminUser 查询管理后台中的用户信息
+func GetAdminUser(userID int64) (*models.AdminUser, error) {
+	user := new(models.AdminUser)
+	sqlStr := `SELECT user_id, username, COALESCE(email, '') AS email, email_verified, role, create_time, update_time
+				FROM user WHERE user_id = ?`
+	err := db.Get(user, sqlStr, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrorUserNotExist
+	}
+	return user, err
+}
+
+// CountUserContent 统计用户发布的帖子和评论数量，包括已删除和被移除的
+func CountUserContent(userID int64) (posts, comments int64, err error) {
+	if err = db.Get(&posts, `SELECT COUNT(*) FROM post WHERE author_id = ?`, userID); err != nil {
+		return
+	}
+	err = db.Get(&comments, `SELECT COUNT(*) FROM comments WHERE user_id = ?`, userID)
+	return
+}
+
+// CountOpenReportsAgainst 统计针对用户的内容尚未处理的举报单数量
+func CountOpenReportsAgainst(userID int64) (int64, error) {
+	var count int64
+	err := db.Get(&count, `SELECT COUNT(*) FROM report_case WHERE target_user_id = ? AND status = ?`, userID, models.ReportCaseOpen)
+	return count, err
+}
+
+// UpdateCommunityName 修改社区名称
+func UpdateCommunityName(communityID int64, name string) error {
+	_, err := db.Exec(`UPDATE community SET community_name = ? WHERE community_id = ?`, name, communityID)
+	return err
+}
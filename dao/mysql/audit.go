@@ -0,0 +1,56 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"time"
+)
+
+// 审计日志只提供追加和查询，数据库触发器禁止修改和删除
+
+// AddAuditLog 追加一条审计日志
+func AddAuditLog(l *models.AuditLog) error {
+	sqlStr := `INSERT INTO audit_log (actor_id, action, target_type, target_id, success, code, detail, ip, user_agent)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := db.Exec(sqlStr, l.ActorID, l.Action, l.TargetType, l.TargetID, l.Success, l.Code, l.Detail, l.IP, l.UserAgent)
+	return err
+}
+
+// GetAuditLogs 按操作人、操作、对象、结果和时间范围分页查询审计日志，最近的在前
+func GetAuditLogs(p *models.ParamAuditLogList) ([]*models.AuditLog, error) {
+	logs := make([]*models.AuditLog, 0)
+	sqlStr := `SELECT id, actor_id, action, target_type, target_id, success, code, detail, ip, user_agent, create_time
+				FROM audit_log WHERE 1 = 1`
+	args := make([]interface{}, 0)
+	if p.ActorID != 0 {
+		sqlStr += ` AND actor_id = ?`
+		args = append(args, p.ActorID)
+	}
+	if p.Action != "" {
+		sqlStr += ` AND action = ?`
+		args = append(args, p.Action)
+	}
+	if p.TargetType != "" {
+		sqlStr += ` AND target_type = ?`
+		args = append(args, p.TargetType)
+	}
+	if p.TargetID != 0 {
+		sqlStr += ` AND target_id = ?`
+		args = append(args, p.TargetID)
+	}
+	if p.Success != nil {
+		sqlStr += ` AND success = ?`
+		args = append(args, *p.Success)
+	}
+	if p.StartTime > 0 {
+		sqlStr += ` AND create_time >= ?`
+		args = append(args, time.Unix(p.StartTime, 0))
+	}
+	if p.EndTime > 0 {
+		sqlStr += ` AND create_time < ?`
+		args = append(args, time.Unix(p.EndTime, 0))
+	}
+	sqlStr += ` ORDER BY id DESC LIMIT ?, ?`
+	args = append(args, (p.Offset-1)*p.Limit, p.Limit)
+	err := db.Select(&logs, sqlStr, args...)
+	return logs, err
+}
@@ -0,0 +1,35 @@
+package mysql
+
+import "bluebell/models"
+
+// BlockUser 屏蔽用户
+func BlockUser(userID, blockedID int64) error {
+	_, err := db.Exec(`INSERT IGNORE INTO user_block (user_id, blocked_id) VALUES (?, ?)`, userID, blockedID)
+	return err
+}
+
+// UnblockUser 取消屏蔽用户
+func UnblockUser(userID, blockedID int64) error {
+	_, err := db.Exec(`DELETE FROM user_block WHERE user_id = ? AND blocked_id = ?`, userID, blockedID)
+	return err
+}
+
+// IsBlockedEither 两个用户中是否有一方屏蔽了另一方
+func IsBlockedEither(a, b int64) (bool, error) {
+	var count int
+	sqlStr := `SELECT COUNT(*) FROM user_block WHERE (user_id = ? AND blocked_id = ?) OR (user_id = ? AND blocked_id = ?)`
+	err := db.Get(&count, sqlStr, a, b, b, a)
+	return count > 0, err
+}
+
+// GetBlockedUsers 查询用户屏蔽的全部用户
+func GetBlockedUsers(userID int64) ([]*models.UserSafe, error) {
+	users := make([]*models.UserSafe, 0)
+	sqlStr := `SELECT u.user_id, u.username, u.avatar_url
+				FROM user_block b
+				JOIN user u ON u.user_id = b.blocked_id
+				WHERE b.user_id = ?
+				ORDER BY b.create_time DESC`
+	err := db.Select(&users, sqlStr, userID)
+	return users, err
+}
@@ -2,16 +2,33 @@ package mysql
 
 import (
 	"bluebell/models"
+	"database/sql"
+	"go.uber.org/zap"
+	"strconv"
 	"time"
 )
 
 // CreateComment 发表评论
-func CreateComment(commentID, postID, parentID, userID int64, content string) (int64, error) {
+// 评论的物化路径为父评论的路径加上自身id，顶级评论为 /comment_id/
+func CreateComment(commentID, postID, parentID, userID int64, content string, status int8) (int64, error) {
+	path, depth := "/"+strconv.FormatInt(commentID, 10)+"/", 0
+	if parentID != 0 {
+		parent := new(models.Comment)
+		err := db.Get(parent, `SELECT post_id, path, depth FROM comments WHERE comment_id = ?`, parentID)
+		if err == sql.ErrNoRows || (err == nil && parent.PostID != postID) {
+			return 0, ErrorCommentNotExist
+		}
+		if err != nil {
+			return 0, err
+		}
+		path, depth = parent.Path+strconv.FormatInt(commentID, 10)+"/", parent.Depth+1
+	}
+
 	strSql := `
-        INSERT INTO comments (comment_id, post_id, parent_id, user_id, content)
-        VALUES (?, ?, ?, ?, ?)
+        INSERT INTO comments (comment_id, post_id, parent_id, user_id, content, status, path, depth)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
     `
-	result, err := db.Exec(strSql, commentID, postID, parentID, userID, content)
+	result, err := db.Exec(strSql, commentID, postID, parentID, userID, content, status, path, depth)
 	if err != nil {
 		return 0, err
 	}
@@ -25,10 +42,25 @@ func CreateComment(commentID, postID, parentID, userID int64, content string) (i
 	return lastInsertID, nil
 }
 
+// GetCommentByID 根据评论id查询评论
+func GetCommentByID(commentID int64) (*models.Comment, error) {
+	strSql := `select post_id, comment_id, parent_id, user_id, content, likes, 
+            dislikes, status, create_time, update_time, is_top, top_time, path, depth, edit_time FROM comments 
+        WHERE comment_id = ?;`
+	comment := new(models.Comment)
+	if err := db.Get(comment, strSql, commentID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrorCommentNotExist
+		}
+		return nil, err
+	}
+	return comment, nil
+}
+
 // GetCommentByPostID 查看某一个帖子的全部顶级评论
 func GetCommentByPostID(postID int64) ([]*models.Comment, error) {
 	strSql := `select post_id, comment_id, parent_id, user_id, content, likes, 
-            dislikes, status, create_time, update_time FROM comments 
+            dislikes, status, create_time, update_time, is_top, top_time, edit_time FROM comments 
         WHERE post_id = ? and parent_id = 0
         ORDER BY create_time DESC;`
 
@@ -41,11 +73,28 @@ func GetCommentByPostID(postID int64) ([]*models.Comment, error) {
 	return comments, nil
 }
 
+// GetCommentSubtree 通过物化路径一次查询帖子中path下层级不超过maxDepth的全部评论
+// path 为空时查询整个帖子的评论
+func GetCommentSubtree(postID int64, path string, maxDepth int) ([]*models.Comment, error) {
+	strSql := `
+		SELECT 
+			 comment_id, post_id, parent_id, user_id, content, likes, dislikes, status, create_time, update_time, is_top, top_time, path, depth, edit_time
+		FROM comments 
+		WHERE post_id = ? AND path LIKE CONCAT(?, '%') AND depth <= ?
+		ORDER BY create_time DESC;
+	`
+	var comments []*models.Comment
+	if err := db.Select(&comments, strSql, postID, path, maxDepth); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
 // GetChildCommentsByParentID 查询指定父评论下的子评论
 func GetChildCommentsByParentID(parentID int64) ([]*models.Comment, error) {
 	strSql := `
 		SELECT 
-			 comment_id, post_id, parent_id, user_id, content, likes, dislikes, status, create_time, update_time
+			 comment_id, post_id, parent_id, user_id, content, likes, dislikes, status, create_time, update_time, is_top, top_time, edit_time
 		FROM comments 
 		WHERE parent_id = ? 
 		ORDER BY create_time DESC;
@@ -58,24 +107,29 @@ func GetChildCommentsByParentID(parentID int64) ([]*models.Comment, error) {
 	return comments, nil
 }
 
-// DeleteCommentByID 删除一个评论
-func DeleteCommentByID(commentID int64) error {
-	strSql := `delete from comments where comment_id = ?;`
-	_, err := db.Exec(strSql, commentID)
-	if err != nil {
-		return err
-	}
-	return nil
+// UpdateCommentContent 修改评论内容并记录编辑时间，已删除的评论不能修改
+func UpdateCommentContent(commentID int64, content string, status int8) error {
+	strSql := `UPDATE comments SET content = ?, status = ?, edit_time = NOW() WHERE comment_id = ? AND status = ?;`
+	_, err := db.Exec(strSql, content, status, commentID, models.CommentStatusNormal)
+	return err
+}
+
+// SetCommentStatus 修改一个评论的状态，评论本身保留以维持评论树的结构
+func SetCommentStatus(commentID int64, status int8) error {
+	strSql := `UPDATE comments SET status = ? WHERE comment_id = ?;`
+	_, err := db.Exec(strSql, status, commentID)
+	return err
 }
 
-// DeleteCommentByParentID 删除以某评论为父评论的子评论
-func DeleteCommentByParentID(parentID int64) error {
-	strSql := `delete from comments where parent_id = ?;`
-	_, err := db.Exec(strSql, parentID)
+// RemoveCommentSubtree 移除path下任意层级的全部评论，返回移除的数量
+// 已被作者删除的评论保持删除状态
+func RemoveCommentSubtree(postID int64, path string) (int64, error) {
+	strSql := `UPDATE comments SET status = ? WHERE post_id = ? AND path LIKE CONCAT(?, '%') AND status = ?;`
+	result, err := db.Exec(strSql, models.CommentStatusRemoved, postID, path, models.CommentStatusNormal)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	return nil
+	return result.RowsAffected()
 }
 
 // PinCommentByID 更新评论为置顶状态
@@ -118,3 +172,36 @@ func CheckCoometAndPost(commentID, userID int64) error {
 	var postAuthorID int64
 	return db.Get(&postAuthorID, strSql, commentID, userID)
 }
+
+// SyncCommentVotes 在一个MySQL事务中写入一批评论的点赞数和点踩数
+func SyncCommentVotes(data []*models.CommentSyncData) error {
+	if len(data) == 0 {
+		return nil
+	}
+	tx, err := db.Beginx()
+	if err != nil {
+		zap.L().Error("开启mysql事务失败", zap.Error(err))
+		return err
+	}
+
+	update, err := tx.Preparex("UPDATE comments SET likes = ?, dislikes = ? WHERE comment_id = ?")
+	if err != nil {
+		zap.L().Error("SQL预编译失败", zap.Error(err))
+		tx.Rollback()
+		return err
+	}
+	defer update.Close()
+	for _, comment := range data {
+		if _, err = update.Exec(comment.Likes, comment.Dislikes, comment.CommentID); err != nil {
+			zap.L().Error("更新评论点赞点踩数失败", zap.String("commentID", comment.CommentID), zap.Error(err))
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		zap.L().Error("提交MySQL事务失败", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -7,7 +7,7 @@ import (
 
 // GetCommunityList 查询全部社区
 func GetCommunityList() ([]*models.Community, error) {
-	sqlStr := "SELECT community_id, community_name FROM community"
+	sqlStr := "SELECT community_id, community_name, member_count FROM community"
 	var communityList []*models.Community // 先声明切片变量
 	err := db.Select(&communityList, sqlStr)
 	if err != nil {
@@ -24,7 +24,7 @@ func GetCommunityList() ([]*models.Community, error) {
 
 // GetCommunityById 根据id查询社区详情
 func GetCommunityById(id int64) (*models.CommunityDetail, error) {
-	sqlStr := "SELECT community_id, community_name, introduction, create_time, update_time FROM community WHERE community_id = ?"
+	sqlStr := "SELECT community_id, community_name, introduction, member_count, owner_id, create_time, update_time FROM community WHERE community_id = ?"
 
 	var communityDetail models.CommunityDetail // 不要用指针的指针，直接定义结构体变量
 	err := db.Get(&communityDetail, sqlStr, id)
@@ -35,19 +35,73 @@ func GetCommunityById(id int64) (*models.CommunityDetail, error) {
 	return &communityDetail, nil
 }
 
-// CreateCommunity 创建社区
-func CreateCommunity(comm *models.CommunityDetail) error {
-	sqlStr := "INSERT INTO community (community_id, community_name, introduction) VALUES (?, ?, ?)"
-	_, err := db.Exec(sqlStr, comm.ID, comm.Name, comm.Introduction)
+// CreateCommunity 创建社区，创建者成为社区的第一个成员
+func CreateCommunity(comm *models.CommunityDetail) (err error) {
+	tx, err := db.Beginx()
 	if err != nil {
 		return err
 	}
-	return nil
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	sqlStr := "INSERT INTO community (community_id, community_name, introduction, owner_id, member_count) VALUES (?, ?, ?, ?, 1)"
+	if _, err = tx.Exec(sqlStr, comm.ID, comm.Name, comm.Introduction, comm.OwnerID); err != nil {
+		return err
+	}
+	sqlStr = "INSERT INTO community_member (community_id, user_id, role) VALUES (?, ?, ?)"
+	if _, err = tx.Exec(sqlStr, comm.ID, comm.OwnerID, models.CommunityRoleOwner); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetCommunityOwner 设置社区的创建者，用户不是社区成员时加入社区，原来的创建者改为版主
+func SetCommunityOwner(communityID, userID int64) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	sqlStr := "UPDATE community_member SET role = ? WHERE community_id = ? AND role = ? AND user_id <> ?"
+	if _, err = tx.Exec(sqlStr, models.CommunityRoleModerator, communityID, models.CommunityRoleOwner, userID); err != nil {
+		return err
+	}
+	// 新插入时影响 1 行，已是成员时影响 2 行或 0 行
+	sqlStr = "INSERT INTO community_member (community_id, user_id, role) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE role = VALUES(role)"
+	result, err := tx.Exec(sqlStr, communityID, userID, models.CommunityRoleOwner)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 1 {
+		if _, err = tx.Exec("UPDATE community SET member_count = member_count + 1 WHERE community_id = ?", communityID); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.Exec("UPDATE community SET owner_id = ? WHERE community_id = ?", userID, communityID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateCommunityIntroduction 修改社区简介
+func UpdateCommunityIntroduction(communityID int64, introduction string) error {
+	_, err := db.Exec("UPDATE community SET introduction = ? WHERE community_id = ?", introduction, communityID)
+	return err
 }
 
 // GetCommunityByName 根据name查询社区详情
 func GetCommunityByName(username string) (*models.CommunityDetail, error) {
-	sqlStr := "SELECT community_id, community_name, introduction, create_time, update_time FROM community WHERE community_name = ?"
+	sqlStr := "SELECT community_id, community_name, introduction, member_count, owner_id, create_time, update_time FROM community WHERE community_name = ?"
 
 	var communityDetail models.CommunityDetail // 不要用指针的指针，直接定义结构体变量
 	err := db.Get(&communityDetail, sqlStr, username)
@@ -57,3 +111,76 @@ func GetCommunityByName(username string) (*models.CommunityDetail, error) {
 
 	return &communityDetail, nil
 }
+
+// JoinCommunity 用户加入社区，返回是否新加入
+func JoinCommunity(communityID, userID int64) (joined bool, err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	result, err := tx.Exec(`INSERT IGNORE INTO community_member (community_id, user_id) VALUES (?, ?)`, communityID, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected > 0 {
+		if _, err = tx.Exec(`UPDATE community SET member_count = member_count + 1 WHERE community_id = ?`, communityID); err != nil {
+			return false, err
+		}
+	}
+	return affected > 0, tx.Commit()
+}
+
+// LeaveCommunity 用户退出社区，返回是否原本是社区成员
+func LeaveCommunity(communityID, userID int64) (left bool, err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	result, err := tx.Exec(`DELETE FROM community_member WHERE community_id = ? AND user_id = ?`, communityID, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected > 0 {
+		if _, err = tx.Exec(`UPDATE community SET member_count = member_count - 1 WHERE community_id = ? AND member_count > 0`, communityID); err != nil {
+			return false, err
+		}
+	}
+	return affected > 0, tx.Commit()
+}
+
+// GetUserCommunities 查询用户加入的社区，最近加入的在前
+func GetUserCommunities(userID int64) ([]*models.Community, error) {
+	communities := make([]*models.Community, 0)
+	sqlStr := `SELECT c.community_id, c.community_name, c.member_count
+				FROM community_member m
+				JOIN community c ON c.community_id = m.community_id
+				WHERE m.user_id = ?
+				ORDER BY m.create_time DESC`
+	err := db.Select(&communities, sqlStr, userID)
+	return communities, err
+}
+
+// GetUserCommunityIDs 查询用户加入的全部社区id
+func GetUserCommunityIDs(userID int64) ([]int64, error) {
+	ids := make([]int64, 0)
+	err := db.Select(&ids, `SELECT community_id FROM community_member WHERE user_id = ?`, userID)
+	return ids, err
+}
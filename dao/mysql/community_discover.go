@@ -0,0 +1,80 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"github.com/jmoiron/sqlx"
+	"strings"
+	"time"
+)
+
+// likeEscaper 转义 LIKE 中的通配符
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchCommunities 按名称和简介搜索社区
+// 名称以关键词开头的排在最前，其次是名称包含关键词的，最后是简介包含关键词的，同一类中成员多的在前
+func SearchCommunities(keyword string, offset, limit int64) ([]*models.Community, error) {
+	communities := make([]*models.Community, 0)
+	kw := likeEscaper.Replace(keyword)
+	prefix, contains := kw+"%", "%"+kw+"%"
+	sqlStr := `SELECT community_id, community_name, member_count FROM community
+				WHERE community_name LIKE ? OR introduction LIKE ?
+				ORDER BY CASE WHEN community_name LIKE ? THEN 0 WHEN community_name LIKE ? THEN 1 ELSE 2 END,
+				member_count DESC, community_id
+				LIMIT ?, ?`
+	err := db.Select(&communities, sqlStr, contains, contains, prefix, contains, (offset-1)*limit, limit)
+	return communities, err
+}
+
+// SuggestCommunities 查询名称以 prefix 开头的社区，成员多的在前
+func SuggestCommunities(prefix string, limit int64) ([]*models.Community, error) {
+	communities := make([]*models.Community, 0)
+	sqlStr := `SELECT community_id, community_name, member_count FROM community
+				WHERE community_name LIKE ? ORDER BY member_count DESC, community_name LIMIT ?`
+	err := db.Select(&communities, sqlStr, likeEscaper.Replace(prefix)+"%", limit)
+	return communities, err
+}
+
+// GetCommunitiesByIDs 根据id查询一批社区，按给定id的顺序返回
+func GetCommunitiesByIDs(ids []int64) ([]*models.Community, error) {
+	communities := make([]*models.Community, 0)
+	if len(ids) == 0 {
+		return communities, nil
+	}
+	query, args, err := sqlx.In(`SELECT community_id, community_name, member_count FROM community
+				WHERE community_id IN (?) ORDER BY FIELD(community_id, ?)`, ids, ids)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Select(&communities, db.Rebind(query), args...)
+	return communities, err
+}
+
+// GetCommunityActivity 从每天的统计数据中汇总各社区 [recentSince, 今天] 和 [since, recentSince) 两段时间的活跃度
+func GetCommunityActivity(since, recentSince time.Time) ([]*models.CommunityActivity, error) {
+	activity := make([]*models.CommunityActivity, 0)
+	sqlStr := `SELECT community_id,
+				SUM(IF(day >= ?, posts + comments + votes + new_members, 0)) AS recent,
+				SUM(IF(day < ?, posts + comments + votes + new_members, 0)) AS baseline
+				FROM community_daily_stats WHERE day >= ? GROUP BY community_id`
+	recent := recentSince.Format("2006-01-02")
+	err := db.Select(&activity, sqlStr, recent, recent, since.Format("2006-01-02"))
+	return activity, err
+}
+
+// GetSimilarUserCommunityRates 按用户和社区汇总 user_post_behavior 中的行为分数
+// 只查询用户自己以及在用户有行为的社区中也有行为的用户，其他用户与该用户的相似度为 0
+func GetSimilarUserCommunityRates(userID int64) ([]*models.UserCommunityRate, error) {
+	rates := make([]*models.UserCommunityRate, 0)
+	sqlStr := `SELECT b.user_id, p.community_id, SUM(b.rate) AS rate
+				FROM user_post_behavior b JOIN post p ON p.post_id = b.post_id
+				WHERE b.user_id IN (
+					SELECT DISTINCT sb.user_id
+					FROM user_post_behavior sb JOIN post sp ON sp.post_id = sb.post_id
+					WHERE sp.community_id IN (
+						SELECT DISTINCT mp.community_id
+						FROM user_post_behavior mb JOIN post mp ON mp.post_id = mb.post_id
+						WHERE mb.user_id = ?))
+				GROUP BY b.user_id, p.community_id`
+	err := db.Select(&rates, sqlStr, userID)
+	return rates, err
+}
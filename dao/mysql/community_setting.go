@@ -0,0 +1,94 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"database/sql"
+)
+
+// GetCommunitySettings 查询社区设置，没有修改过设置时返回nil
+func GetCommunitySettings(communityID int64) (*models.CommunitySettings, error) {
+	settings := new(models.CommunitySettings)
+	sqlStr := `SELECT community_id, rules, icon, banner, allowed_post_types, visibility, archived
+				FROM community_setting WHERE community_id = ?`
+	err := db.Get(settings, sqlStr, communityID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return settings, err
+}
+
+// SaveCommunitySettings 保存社区设置，不修改归档状态
+func SaveCommunitySettings(s *models.CommunitySettings) error {
+	sqlStr := `INSERT INTO community_setting (community_id, rules, icon, banner, allowed_post_types, visibility)
+				VALUES (?, ?, ?, ?, ?, ?)
+				ON DUPLICATE KEY UPDATE rules = VALUES(rules), icon = VALUES(icon), banner = VALUES(banner),
+				allowed_post_types = VALUES(allowed_post_types), visibility = VALUES(visibility)`
+	_, err := db.Exec(sqlStr, s.CommunityID, s.Rules, s.Icon, s.Banner, s.PostTypes, s.Visibility)
+	return err
+}
+
+// SetCommunityArchived 归档或取消归档社区
+func SetCommunityArchived(communityID int64, archived bool) error {
+	sqlStr := `INSERT INTO community_setting (community_id, rules, archived) VALUES (?, '', ?)
+				ON DUPLICATE KEY UPDATE archived = VALUES(archived)`
+	_, err := db.Exec(sqlStr, communityID, archived)
+	return err
+}
+
+// GetPrivateCommunityIDs 查询全部私密社区
+func GetPrivateCommunityIDs() ([]int64, error) {
+	ids := make([]int64, 0)
+	err := db.Select(&ids, `SELECT community_id FROM community_setting WHERE visibility = ?`, models.CommunityPrivate)
+	return ids, err
+}
+
+// GetJoinRequest 查询用户加入社区的申请或邀请，没有时返回nil
+func GetJoinRequest(communityID, userID int64) (*models.CommunityJoinRequest, error) {
+	req := new(models.CommunityJoinRequest)
+	sqlStr := `SELECT community_id, user_id, inviter_id, status, create_time
+				FROM community_join_request WHERE community_id = ? AND user_id = ?`
+	err := db.Get(req, sqlStr, communityID, userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return req, err
+}
+
+// CreateJoinRequest 用户申请加入社区，被拒绝后可以再次申请
+func CreateJoinRequest(communityID, userID int64) error {
+	sqlStr := `INSERT INTO community_join_request (community_id, user_id, status) VALUES (?, ?, ?)
+				ON DUPLICATE KEY UPDATE status = VALUES(status), inviter_id = 0, create_time = NOW()`
+	_, err := db.Exec(sqlStr, communityID, userID, models.JoinRequestPending)
+	return err
+}
+
+// InviteCommunityUser 版主邀请用户加入社区，用户加入时不再需要批准
+func InviteCommunityUser(communityID, userID, inviterID int64) error {
+	sqlStr := `INSERT INTO community_join_request (community_id, user_id, inviter_id, status) VALUES (?, ?, ?, ?)
+				ON DUPLICATE KEY UPDATE inviter_id = VALUES(inviter_id), status = VALUES(status), create_time = NOW()`
+	_, err := db.Exec(sqlStr, communityID, userID, inviterID, models.JoinRequestApproved)
+	return err
+}
+
+// SetJoinRequestStatus 修改待处理的加入申请的状态，返回是否存在待处理的申请
+func SetJoinRequestStatus(communityID, userID int64, status int8) (bool, error) {
+	sqlStr := `UPDATE community_join_request SET status = ? WHERE community_id = ? AND user_id = ? AND status = ?`
+	result, err := db.Exec(sqlStr, status, communityID, userID, models.JoinRequestPending)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetPendingJoinRequests 查询社区待处理的加入申请，最早的在前
+func GetPendingJoinRequests(communityID int64) ([]*models.CommunityJoinRequest, error) {
+	reqs := make([]*models.CommunityJoinRequest, 0)
+	sqlStr := `SELECT r.community_id, r.user_id, u.username, r.inviter_id, r.status, r.create_time
+				FROM community_join_request r
+				JOIN user u ON u.user_id = r.user_id
+				WHERE r.community_id = ? AND r.status = ?
+				ORDER BY r.create_time`
+	err := db.Select(&reqs, sqlStr, communityID, models.JoinRequestPending)
+	return reqs, err
+}
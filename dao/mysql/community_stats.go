@@ -0,0 +1,109 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"time"
+)
+
+// communityCount 按社区分组的计数
+type communityCount struct {
+	CommunityID int64 `db:"community_id"`
+	Count       int64 `db:"n"`
+}
+
+// countByCommunity 执行按社区分组计数的查询，返回 社区id -> 数量
+func countByCommunity(sqlStr string, args ...interface{}) (map[int64]int64, error) {
+	rows := make([]*communityCount, 0)
+	if err := db.Select(&rows, sqlStr, args...); err != nil {
+		return nil, err
+	}
+	counts := make(map[int64]int64, len(rows))
+	for _, r := range rows {
+		counts[r.CommunityID] = r.Count
+	}
+	return counts, nil
+}
+
+// CountCommunityPosts 统计 [start, end) 内各社区的新帖子数
+func CountCommunityPosts(start, end time.Time) (map[int64]int64, error) {
+	sqlStr := `SELECT community_id, COUNT(*) AS n FROM post
+				WHERE create_time >= ? AND create_time < ? GROUP BY community_id`
+	return countByCommunity(sqlStr, start, end)
+}
+
+// CountCommunityComments 统计 [start, end) 内各社区的新评论数
+func CountCommunityComments(start, end time.Time) (map[int64]int64, error) {
+	sqlStr := `SELECT p.community_id, COUNT(*) AS n FROM comments c JOIN post p ON p.post_id = c.post_id
+				WHERE c.create_time >= ? AND c.create_time < ? GROUP BY p.community_id`
+	return countByCommunity(sqlStr, start, end)
+}
+
+// CountCommunityVotes 统计 [start, end) 内各社区帖子收到的投票数
+func CountCommunityVotes(start, end time.Time) (map[int64]int64, error) {
+	sqlStr := `SELECT p.community_id, COUNT(*) AS n FROM post_vote_event v JOIN post p ON p.post_id = v.post_id
+				WHERE v.create_time >= ? AND v.create_time < ? GROUP BY p.community_id`
+	return countByCommunity(sqlStr, start, end)
+}
+
+// CountCommunityActiveUsers 统计 [start, end) 内各社区发帖、评论或投票的用户数
+func CountCommunityActiveUsers(start, end time.Time) (map[int64]int64, error) {
+	sqlStr := `SELECT community_id, COUNT(DISTINCT user_id) AS n FROM (
+					SELECT community_id, author_id AS user_id FROM post
+					WHERE create_time >= ? AND create_time < ?
+					UNION ALL
+					SELECT p.community_id, c.user_id FROM comments c JOIN post p ON p.post_id = c.post_id
+					WHERE c.create_time >= ? AND c.create_time < ?
+					UNION ALL
+					SELECT p.community_id, v.user_id FROM post_vote_event v JOIN post p ON p.post_id = v.post_id
+					WHERE v.create_time >= ? AND v.create_time < ?
+				) a GROUP BY community_id`
+	return countByCommunity(sqlStr, start, end, start, end, start, end)
+}
+
+// CountCommunityNewMembers 统计 [start, end) 内各社区的新成员数
+func CountCommunityNewMembers(start, end time.Time) (map[int64]int64, error) {
+	sqlStr := `SELECT community_id, COUNT(*) AS n FROM community_member
+				WHERE create_time >= ? AND create_time < ? GROUP BY community_id`
+	return countByCommunity(sqlStr, start, end)
+}
+
+// GetCommunityMemberCounts 查询全部社区当前的成员数
+func GetCommunityMemberCounts() (map[int64]int64, error) {
+	return countByCommunity(`SELECT community_id, member_count AS n FROM community`)
+}
+
+// SaveCommunityDailyStats 写入社区某天的统计数据，重复汇总时覆盖
+// updateMemberCount 为 false 时保留已有的成员数，用于补算已经过去的日期
+func SaveCommunityDailyStats(stats []*models.CommunityDailyStats, updateMemberCount bool) error {
+	if len(stats) == 0 {
+		return nil
+	}
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	sqlStr := `INSERT INTO community_daily_stats
+				(community_id, day, posts, comments, votes, active_users, new_members, member_count)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+				ON DUPLICATE KEY UPDATE posts = VALUES(posts), comments = VALUES(comments), votes = VALUES(votes),
+				active_users = VALUES(active_users), new_members = VALUES(new_members),
+				member_count = IF(?, VALUES(member_count), member_count)`
+	for _, s := range stats {
+		if _, err := tx.Exec(sqlStr, s.CommunityID, s.Day, s.Posts, s.Comments, s.Votes,
+			s.ActiveUsers, s.NewMembers, s.MemberCount, updateMemberCount); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetCommunityDailyStats 查询社区从 since 当天起每天的统计数据，按日期升序
+func GetCommunityDailyStats(communityID int64, since time.Time) ([]*models.CommunityDailyStats, error) {
+	stats := make([]*models.CommunityDailyStats, 0)
+	sqlStr := `SELECT community_id, DATE_FORMAT(day, '%Y-%m-%d') AS day, posts, comments, votes,
+				active_users, new_members, member_count
+				FROM community_daily_stats WHERE community_id = ? AND day >= ? ORDER BY day`
+	err := db.Select(&stats, sqlStr, communityID, since.Format("2006-01-02"))
+	return stats, err
+}
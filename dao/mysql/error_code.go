@@ -7,6 +7,10 @@ var (
 	ErrorUserNotExist = errors.New("用户不存在")
 	ErrorUserPassword = errors.New("密码错误")
 	ErrorInvalidInfo  = errors.New("无效的信息")
+
+	ErrorPostNotExist         = errors.New("帖子不存在")
+	ErrorCommentNotExist      = errors.New("评论不存在")
+	ErrorNotificationNotExist = errors.New("通知不存在")
 )
 
 const (
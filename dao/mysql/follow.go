@@ -0,0 +1,46 @@
+package mysql
+
+import "bluebell/models"
+
+// FollowUser 关注用户，返回是否新增了关注
+func FollowUser(userID, followerID int64) (bool, error) {
+	result, err := db.Exec(`INSERT IGNORE INTO user_follow (user_id, follower_id) VALUES (?, ?)`, userID, followerID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// UnfollowUser 取消关注用户
+func UnfollowUser(userID, followerID int64) error {
+	_, err := db.Exec(`DELETE FROM user_follow WHERE user_id = ? AND follower_id = ?`, userID, followerID)
+	return err
+}
+
+// GetFollowers 查询关注了用户的全部用户
+func GetFollowers(userID int64) ([]*models.UserSafe, error) {
+	users := make([]*models.UserSafe, 0)
+	sqlStr := `SELECT u.user_id, u.username, u.avatar_url
+				FROM user_follow f
+				JOIN user u ON u.user_id = f.follower_id
+				WHERE f.user_id = ?
+				ORDER BY f.create_time DESC`
+	err := db.Select(&users, sqlStr, userID)
+	return users, err
+}
+
+// GetFollowing 查询用户关注的全部用户
+func GetFollowing(followerID int64) ([]*models.UserSafe, error) {
+	users := make([]*models.UserSafe, 0)
+	sqlStr := `SELECT u.user_id, u.username, u.avatar_url
+				FROM user_follow f
+				JOIN user u ON u.user_id = f.user_id
+				WHERE f.follower_id = ?
+				ORDER BY f.create_time DESC`
+	err := db.Select(&users, sqlStr, followerID)
+	return users, err
+}
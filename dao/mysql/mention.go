@@ -0,0 +1,41 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"github.com/jmoiron/sqlx"
+	"strings"
+)
+
+// CreateMentions 批量保存提及记录，同一内容重复提及同一用户时忽略
+func CreateMentions(mentions []*models.Mention) error {
+	if len(mentions) == 0 {
+		return nil
+	}
+	placeholders := make([]string, 0, len(mentions))
+	values := make([]interface{}, 0, len(mentions)*6)
+	for _, m := range mentions {
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?, ?)")
+		values = append(values, m.SourceType, m.SourceID, m.PostID, m.UserID, m.Username, m.AuthorID)
+	}
+	sqlStr := `INSERT IGNORE INTO mention (source_type, source_id, post_id, user_id, username, author_id) VALUES ` +
+		strings.Join(placeholders, ",")
+	_, err := db.Exec(sqlStr, values...)
+	return err
+}
+
+// GetMentionsBySources 查询一批帖子或评论中的提及记录
+func GetMentionsBySources(sourceType int8, sourceIDs []int64) ([]*models.Mention, error) {
+	mentions := make([]*models.Mention, 0)
+	if len(sourceIDs) == 0 {
+		return mentions, nil
+	}
+	query, args, err := sqlx.In(`SELECT source_type, source_id, post_id, user_id, username, author_id, create_time
+		FROM mention WHERE source_type = ? AND source_id IN (?)`, sourceType, sourceIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Select(&mentions, db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	return mentions, nil
+}
@@ -0,0 +1,147 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// GetConversationByID 根据id查询会话
+func GetConversationByID(conversationID int64) (*models.Conversation, error) {
+	conv := new(models.Conversation)
+	sqlStr := `SELECT conversation_id, user_a, user_b, a_last_read_id, b_last_read_id, last_message_id, last_message_time, create_time
+				FROM conversation WHERE conversation_id = ?`
+	if err := db.Get(conv, sqlStr, conversationID); err != nil {
+		return nil, err
+	}
+	return conv, nil
+}
+
+// ConversationExists 查询两个用户之间是否已有会话，userA 为较小的用户id
+func ConversationExists(userA, userB int64) (bool, error) {
+	var count int
+	err := db.Get(&count, `SELECT COUNT(*) FROM conversation WHERE user_a = ? AND user_b = ?`, userA, userB)
+	return count > 0, err
+}
+
+// SaveMessage 在一个事务中保存消息，没有会话时先创建会话，返回消息所在的会话id
+// 发送者视为已读到自己发送的消息
+func SaveMessage(conversationID, userA, userB int64, msg *models.Message) (int64, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// 两个用户同时给对方发第一条消息时只会创建一个会话
+	if _, err = tx.Exec(`INSERT INTO conversation (conversation_id, user_a, user_b) VALUES (?, ?, ?)
+				ON DUPLICATE KEY UPDATE conversation_id = conversation_id`, conversationID, userA, userB); err != nil {
+		return 0, err
+	}
+	if err = tx.Get(&conversationID, `SELECT conversation_id FROM conversation WHERE user_a = ? AND user_b = ? FOR UPDATE`, userA, userB); err != nil {
+		return 0, err
+	}
+	msg.ConversationID = conversationID
+	if _, err = tx.Exec(`INSERT INTO message (message_id, conversation_id, sender_id, content) VALUES (?, ?, ?, ?)`,
+		msg.MessageID, msg.ConversationID, msg.SenderID, msg.Content); err != nil {
+		return 0, err
+	}
+	sqlStr := `UPDATE conversation SET last_message_id = ?, last_message_time = NOW(),
+					a_last_read_id = IF(user_a = ?, ?, a_last_read_id),
+					b_last_read_id = IF(user_b = ?, ?, b_last_read_id)
+				WHERE conversation_id = ?`
+	if _, err = tx.Exec(sqlStr, msg.MessageID, msg.SenderID, msg.MessageID, msg.SenderID, msg.MessageID, conversationID); err != nil {
+		return 0, err
+	}
+	return conversationID, tx.Commit()
+}
+
+// GetConversations 分页查询用户的会话，最近有消息的在前
+func GetConversations(userID, offset, limit int64) ([]*models.Conversation, error) {
+	convs := make([]*models.Conversation, 0)
+	sqlStr := `SELECT conversation_id, user_a, user_b, a_last_read_id, b_last_read_id, last_message_id, last_message_time, create_time
+				FROM conversation
+				WHERE user_a = ? OR user_b = ?
+				ORDER BY last_message_time DESC
+				LIMIT ?,?`
+	err := db.Select(&convs, sqlStr, userID, userID, (offset-1)*limit, limit)
+	return convs, err
+}
+
+// GetMessages 按消息id倒序查询会话中id小于before的消息，before为0时从最新的消息开始
+func GetMessages(conversationID, before, limit int64) ([]*models.Message, error) {
+	msgs := make([]*models.Message, 0)
+	sqlStr := `SELECT message_id, conversation_id, sender_id, content, create_time
+				FROM message
+				WHERE conversation_id = ? AND (? = 0 OR message_id < ?)
+				ORDER BY message_id DESC
+				LIMIT ?`
+	err := db.Select(&msgs, sqlStr, conversationID, before, before, limit)
+	return msgs, err
+}
+
+// GetMessagesByIDs 根据id查询一批消息
+func GetMessagesByIDs(ids []int64) ([]*models.Message, error) {
+	msgs := make([]*models.Message, 0)
+	if len(ids) == 0 {
+		return msgs, nil
+	}
+	query, args, err := sqlx.In(`SELECT message_id, conversation_id, sender_id, content, create_time FROM message WHERE message_id IN (?)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Select(&msgs, db.Rebind(query), args...)
+	return msgs, err
+}
+
+// CountUnreadByConversation 查询用户在一批会话中的未读消息数量
+func CountUnreadByConversation(userID int64, conversationIDs []int64) (map[int64]int64, error) {
+	counts := make(map[int64]int64)
+	if len(conversationIDs) == 0 {
+		return counts, nil
+	}
+	query, args, err := sqlx.In(`SELECT m.conversation_id, COUNT(*) AS unread
+				FROM message m
+				JOIN conversation c ON c.conversation_id = m.conversation_id
+				WHERE c.conversation_id IN (?) AND m.sender_id != ?
+					AND m.message_id > IF(c.user_a = ?, c.a_last_read_id, c.b_last_read_id)
+				GROUP BY m.conversation_id`, conversationIDs, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		ConversationID int64 `db:"conversation_id"`
+		Unread         int64 `db:"unread"`
+	}
+	if err = db.Select(&rows, db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.ConversationID] = row.Unread
+	}
+	return counts, nil
+}
+
+// CountUnreadMessages 查询用户全部会话中的未读消息数量
+func CountUnreadMessages(userID int64) (count int64, err error) {
+	sqlStr := `SELECT COUNT(*)
+				FROM message m
+				JOIN conversation c ON c.conversation_id = m.conversation_id
+				WHERE (c.user_a = ? OR c.user_b = ?) AND m.sender_id != ?
+					AND m.message_id > IF(c.user_a = ?, c.a_last_read_id, c.b_last_read_id)`
+	err = db.Get(&count, sqlStr, userID, userID, userID, userID)
+	return
+}
+
+// MarkConversationRead 将用户在会话中的消息标记为已读到最后一条消息，返回已读到的消息id
+func MarkConversationRead(conversationID, userID int64) (int64, error) {
+	sqlStr := `UPDATE conversation SET
+					a_last_read_id = IF(user_a = ?, last_message_id, a_last_read_id),
+					b_last_read_id = IF(user_b = ?, last_message_id, b_last_read_id)
+				WHERE conversation_id = ?`
+	if _, err := db.Exec(sqlStr, userID, userID, conversationID); err != nil {
+		return 0, err
+	}
+	var lastReadID int64
+	err := db.Get(&lastReadID, `SELECT last_message_id FROM conversation WHERE conversation_id = ?`, conversationID)
+	return lastReadID, err
+}
@@ -0,0 +1,67 @@
+package mysql
+
+import (
+	"errors"
+	driver "github.com/go-sql-driver/mysql"
+)
+
+// 表、字段、索引、外键已存在，或要删除的字段、索引不存在时的错误码
+var schemaExistErrors = map[uint16]struct{}{
+	1050: {}, // ER_TABLE_EXISTS_ERROR
+	1060: {}, // ER_DUP_FIELDNAME
+	1061: {}, // ER_DUP_KEYNAME
+	1068: {}, // ER_MULTIPLE_PRI_KEY
+	1091: {}, // ER_CANT_DROP_FIELD_OR_KEY
+	1826: {}, // ER_FK_DUP_NAME
+}
+
+// ExecSchema 执行一条建表或改表语句，对象已经是目标结构时返回 skipped 为 true
+func ExecSchema(stmt string) (skipped bool, err error) {
+	if _, err = db.Exec(stmt); err != nil {
+		var me *driver.MySQLError
+		if errors.As(err, &me) {
+			if _, ok := schemaExistErrors[me.Number]; ok {
+				return true, nil
+			}
+		}
+		return false, err
+	}
+	return false, nil
+}
+
+// InitSchemaMigration 创建记录已执行迁移语句的表
+func InitSchemaMigration() error {
+	sqlStr := `CREATE TABLE IF NOT EXISTS schema_migration (
+				checksum char(64) NOT NULL,
+				statement text NOT NULL,
+				applied_time timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (checksum)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci`
+	_, err := db.Exec(sqlStr)
+	return err
+}
+
+// GetAppliedSchema 查询已执行过的迁移语句的校验和
+func GetAppliedSchema() (map[string]struct{}, error) {
+	var checksums []string
+	if err := db.Select(&checksums, `select checksum from schema_migration`); err != nil {
+		return nil, err
+	}
+	applied := make(map[string]struct{}, len(checksums))
+	for _, sum := range checksums {
+		applied[sum] = struct{}{}
+	}
+	return applied, nil
+}
+
+// MarkSchemaApplied 记录迁移语句已执行
+func MarkSchemaApplied(checksum, stmt string) error {
+	_, err := db.Exec(`insert ignore into schema_migration (checksum, statement) values (?, ?)`, checksum, stmt)
+	return err
+}
+
+// ClearSchemaApplied 清空迁移记录，删除重建全部表时使用
+func ClearSchemaApplied() error {
+	_, err := db.Exec(`delete from schema_migration`)
+	return err
+}
@@ -0,0 +1,103 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"database/sql"
+	"time"
+)
+
+// GetCommunityRole 查询用户在社区中的角色，不是社区成员时返回 CommunityRoleNone
+func GetCommunityRole(communityID, userID int64) (int8, error) {
+	var role int8
+	err := db.Get(&role, `SELECT role FROM community_member WHERE community_id = ? AND user_id = ?`, communityID, userID)
+	if err == sql.ErrNoRows {
+		return models.CommunityRoleNone, nil
+	}
+	return role, err
+}
+
+// SetCommunityRole 修改社区成员的角色，返回是否存在该成员
+func SetCommunityRole(communityID, userID int64, role int8) (bool, error) {
+	result, err := db.Exec(`UPDATE community_member SET role = ? WHERE community_id = ? AND user_id = ?`, role, communityID, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetCommunityModerators 查询社区的创建者和全部版主
+func GetCommunityModerators(communityID int64) ([]*models.CommunityModerator, error) {
+	mods := make([]*models.CommunityModerator, 0)
+	sqlStr := `SELECT m.user_id, u.username, m.role, m.create_time
+				FROM community_member m
+				JOIN user u ON u.user_id = m.user_id
+				WHERE m.community_id = ? AND m.role >= ?
+				ORDER BY m.role DESC, m.create_time`
+	err := db.Select(&mods, sqlStr, communityID, models.CommunityRoleModerator)
+	return mods, err
+}
+
+// BanCommunityUser 在社区中禁言用户，已被禁言时更新禁言时间和原因
+func BanCommunityUser(ban *models.CommunityBan) error {
+	sqlStr := `INSERT INTO community_ban (community_id, user_id, operator_id, reason, expire_time) VALUES (?, ?, ?, ?, ?)
+				ON DUPLICATE KEY UPDATE operator_id = VALUES(operator_id), reason = VALUES(reason),
+				expire_time = VALUES(expire_time), create_time = NOW()`
+	_, err := db.Exec(sqlStr, ban.CommunityID, ban.UserID, ban.OperatorID, ban.Reason, ban.ExpireTime)
+	return err
+}
+
+// UnbanCommunityUser 解除用户在社区中的禁言，返回是否存在禁言记录
+func UnbanCommunityUser(communityID, userID int64) (bool, error) {
+	result, err := db.Exec(`DELETE FROM community_ban WHERE community_id = ? AND user_id = ?`, communityID, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetCommunityBan 查询用户在社区中尚未到期的禁言，没有时返回nil
+func GetCommunityBan(communityID, userID int64, now time.Time) (*models.CommunityBan, error) {
+	ban := new(models.CommunityBan)
+	sqlStr := `SELECT community_id, user_id, operator_id, reason, expire_time, create_time
+				FROM community_ban
+				WHERE community_id = ? AND user_id = ? AND expire_time > ?`
+	err := db.Get(ban, sqlStr, communityID, userID, now)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return ban, err
+}
+
+// GetCommunityBans 查询社区中尚未到期的全部禁言
+func GetCommunityBans(communityID int64, now time.Time) ([]*models.CommunityBan, error) {
+	bans := make([]*models.CommunityBan, 0)
+	sqlStr := `SELECT b.community_id, b.user_id, u.username, b.operator_id, b.reason, b.expire_time, b.create_time
+				FROM community_ban b
+				JOIN user u ON u.user_id = b.user_id
+				WHERE b.community_id = ? AND b.expire_time > ?
+				ORDER BY b.expire_time`
+	err := db.Select(&bans, sqlStr, communityID, now)
+	return bans, err
+}
+
+// AddModerationLog 记录一次版主操作
+func AddModerationLog(l *models.ModerationLog) error {
+	sqlStr := `INSERT INTO moderation_log (community_id, moderator_id, action, target_user_id, post_id, comment_id, reason)
+				VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err := db.Exec(sqlStr, l.CommunityID, l.ModeratorID, l.Action, l.TargetUserID, l.PostID, l.CommentID, l.Reason)
+	return err
+}
+
+// GetModerationLogs 分页查询社区的管理日志，最近的在前
+func GetModerationLogs(communityID, offset, limit int64) ([]*models.ModerationLog, error) {
+	logs := make([]*models.ModerationLog, 0)
+	sqlStr := `SELECT id, community_id, moderator_id, action, target_user_id, post_id, comment_id, reason, create_time
+				FROM moderation_log
+				WHERE community_id = ?
+				ORDER BY id DESC
+				LIMIT ?,?;`
+	err := db.Select(&logs, sqlStr, communityID, (offset-1)*limit, limit)
+	return logs, err
+}
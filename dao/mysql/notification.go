@@ -0,0 +1,108 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"github.com/jmoiron/sqlx"
+	"strings"
+)
+
+// CreateNotifications 批量保存通知
+func CreateNotifications(ns []*models.Notification) error {
+	if len(ns) == 0 {
+		return nil
+	}
+	placeholders := make([]string, 0, len(ns))
+	values := make([]interface{}, 0, len(ns)*5)
+	for _, n := range ns {
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?)")
+		values = append(values, n.UserID, n.ActorID, n.Type, n.PostID, n.CommentID)
+	}
+	sqlStr := `INSERT INTO notification (user_id, actor_id, type, post_id, comment_id) VALUES ` +
+		strings.Join(placeholders, ",")
+	_, err := db.Exec(sqlStr, values...)
+	return err
+}
+
+// AddAggregatedNotification 合并同一对象上的未读通知，没有未读通知时新建一条
+func AddAggregatedNotification(n *models.Notification) error {
+	sqlStr := `UPDATE notification SET count = count + 1, actor_id = ?, update_time = NOW()
+				WHERE user_id = ? AND type = ? AND post_id = ? AND comment_id = ? AND is_read = 0`
+	result, err := db.Exec(sqlStr, n.ActorID, n.UserID, n.Type, n.PostID, n.CommentID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+		return err
+	}
+	return CreateNotifications([]*models.Notification{n})
+}
+
+// GetNotifications 分页查询用户的通知，最近更新的在前
+func GetNotifications(userID int64, p *models.ParamNotificationList) ([]*models.Notification, error) {
+	ns := make([]*models.Notification, 0)
+	sqlStr := `SELECT id, user_id, actor_id, type, post_id, comment_id, count, is_read, create_time, update_time
+				FROM notification
+				WHERE user_id = ? AND (? = 0 OR is_read = 0)
+				ORDER BY update_time DESC, id DESC
+				LIMIT ?,?;`
+	err := db.Select(&ns, sqlStr, userID, p.UnreadOnly, (p.Offset-1)*p.Limit, p.Limit)
+	return ns, err
+}
+
+// CountUnreadNotifications 查询用户的未读通知数量
+func CountUnreadNotifications(userID int64) (count int64, err error) {
+	sqlStr := `SELECT COUNT(*) FROM notification WHERE user_id = ? AND is_read = 0`
+	err = db.Get(&count, sqlStr, userID)
+	return
+}
+
+// MarkNotificationRead 将用户的一条通知标记为已读，返回是否存在该通知
+func MarkNotificationRead(userID, id int64) (bool, error) {
+	var exist int
+	if err := db.Get(&exist, `SELECT COUNT(*) FROM notification WHERE id = ? AND user_id = ?`, id, userID); err != nil {
+		return false, err
+	}
+	if exist == 0 {
+		return false, nil
+	}
+	_, err := db.Exec(`UPDATE notification SET is_read = 1 WHERE id = ? AND user_id = ?`, id, userID)
+	return true, err
+}
+
+// MarkAllNotificationsRead 将用户的全部通知标记为已读，返回标记的数量
+func MarkAllNotificationsRead(userID int64) (int64, error) {
+	result, err := db.Exec(`UPDATE notification SET is_read = 1 WHERE user_id = ? AND is_read = 0`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetNotificationPreferences 查询用户修改过的通知设置
+func GetNotificationPreferences(userID int64) ([]*models.NotificationPreference, error) {
+	prefs := make([]*models.NotificationPreference, 0)
+	err := db.Select(&prefs, `SELECT type, enabled FROM notification_preference WHERE user_id = ?`, userID)
+	return prefs, err
+}
+
+// SetNotificationPreference 修改用户对一种通知的设置
+func SetNotificationPreference(userID int64, typ int8, enabled bool) error {
+	sqlStr := `INSERT INTO notification_preference (user_id, type, enabled) VALUES (?, ?, ?)
+				ON DUPLICATE KEY UPDATE enabled = VALUES(enabled)`
+	_, err := db.Exec(sqlStr, userID, typ, enabled)
+	return err
+}
+
+// GetNotificationDisabledUsers 查询一批用户中关闭了某种通知的用户
+func GetNotificationDisabledUsers(typ int8, userIDs []int64) ([]int64, error) {
+	ids := make([]int64, 0)
+	if len(userIDs) == 0 {
+		return ids, nil
+	}
+	query, args, err := sqlx.In(`SELECT user_id FROM notification_preference WHERE type = ? AND enabled = 0 AND user_id IN (?)`, typ, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Select(&ids, db.Rebind(query), args...)
+	return ids, err
+}
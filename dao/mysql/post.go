@@ -4,7 +4,6 @@ import (
 	"bluebell/models"
 	"fmt"
 	"github.com/jmoiron/sqlx"
-	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"log"
 	"strings"
@@ -19,24 +18,25 @@ import (
 
 // CreatePost 向数据库插入一个帖子
 func CreatePost(p *models.Post) (err error) {
-	sqlStr := `insert into post (post_id, title, content, author_id, community_id) values (?,?,?,?,?)`
-	_, err = db.Exec(sqlStr, p.ID, p.Title, p.Content, p.AuthorID, p.CommunityID)
+	sqlStr := `insert into post (post_id, title, content, author_id, community_id, post_type, status) values (?,?,?,?,?,?,?)`
+	_, err = db.Exec(sqlStr, p.ID, p.Title, p.Content, p.AuthorID, p.CommunityID, p.PostType, p.Status)
 	return
 }
 
 // GetPostByID 根据id查询单个帖子的详细信息
 func GetPostByID(pid int64) (p *models.Post, err error) {
 	p = new(models.Post)
-	sqlStr := `select post_id, title, content, author_id, community_id, create_time from post where post_id = ?`
+	sqlStr := `select post_id, title, content, author_id, community_id, post_type, status, pinned, locked, create_time from post where post_id = ?`
 	err = db.Get(p, sqlStr, pid)
 	return
 }
 
-// GetPostList 获得数据库中全部帖子信息
+// GetPostList 获得数据库中全部帖子信息，不包括被移除和隐藏的帖子
 func GetPostList(offset, limit int64) ([]*models.Post, error) {
 	posts := make([]*models.Post, 0)
-	sqlStr := `select post_id, title, content, author_id, community_id, status, create_time from post order by create_time desc limit ?, ?`
-	err := db.Select(&posts, sqlStr, ((offset - 1) * limit), limit)
+	sqlStr := `select post_id, title, content, author_id, community_id, status, create_time from post
+				where status NOT IN (?, ?) order by create_time desc limit ?, ?`
+	err := db.Select(&posts, sqlStr, models.PostStatusRemoved, models.PostStatusHidden, ((offset - 1) * limit), limit)
 	//fmt.Println("len", len(posts))
 	if err != nil {
 		zap.L().Error("mysql.GetPostList failed", zap.Error(err))
@@ -51,9 +51,10 @@ func GetPostList(offset, limit int64) ([]*models.Post, error) {
 
 // 根据给定的id列表查询帖子数据
 func GetPostsListByIds(ids []string) (postlist []*models.Post, err error) {
-	sqlStr := `select post_id, title, content, author_id, community_id, create_time from post where post_id in (?)
+	sqlStr := `select post_id, title, content, author_id, community_id, post_type, status, pinned, locked, create_time from post
+				where post_id in (?) and status NOT IN (?, ?)
 				order by FIND_IN_SET(post_id, ?)`
-	query, args, err := sqlx.In(sqlStr, ids, strings.Join(ids, ","))
+	query, args, err := sqlx.In(sqlStr, ids, models.PostStatusRemoved, models.PostStatusHidden, strings.Join(ids, ","))
 	if err != nil {
 		return nil, err
 	}
@@ -66,11 +67,12 @@ func GetPostsListByIds(ids []string) (postlist []*models.Post, err error) {
 // GetPostsListByIds 根据给定的 ID 列表查询帖子数据，支持传入 int64 切片
 func GetPostsListByInt64Ids(ids []int64) (postlist []*models.Post, err error) {
 	// 定义 SQL 查询语句，使用 FIND_IN_SET 进行排序
-	sqlStr := `select post_id, title, content, author_id, community_id, create_time from post where post_id in (?) 
+	sqlStr := `select post_id, title, content, author_id, community_id, post_type, status, pinned, locked, create_time from post
+				where post_id in (?) and status NOT IN (?, ?)
 				order by FIND_IN_SET(post_id, ?)`
 
 	// 使用 sqlx.In 将切片参数绑定到查询语句中
-	query, args, err := sqlx.In(sqlStr, ids, strings.Join(toStringSlice(ids), ","))
+	query, args, err := sqlx.In(sqlStr, ids, models.PostStatusRemoved, models.PostStatusHidden, strings.Join(toStringSlice(ids), ","))
 	if err != nil {
 		return nil, err
 	}
@@ -92,11 +94,11 @@ func toStringSlice(ids []int64) []string {
 
 // 根据给定的id列表查询对应社区的数据
 func GetPostsListByIdsAndComm(comm_id int64, ids []string) (postlist []*models.Post, err error) {
-	sqlStr := `SELECT post_id, title, content, author_id, community_id, create_time
+	sqlStr := `SELECT post_id, title, content, author_id, community_id, post_type, status, pinned, locked, create_time
 				FROM post
-				WHERE post_id IN (?) AND community_id = ?
+				WHERE post_id IN (?) AND community_id = ? AND status NOT IN (?, ?)
 				ORDER BY FIND_IN_SET(post_id, ?);`
-	query, args, err := sqlx.In(sqlStr, ids, comm_id, strings.Join(ids, ","))
+	query, args, err := sqlx.In(sqlStr, ids, comm_id, models.PostStatusRemoved, models.PostStatusHidden, strings.Join(ids, ","))
 	if err != nil {
 		return nil, err
 	}
@@ -109,23 +111,24 @@ func GetPostsListByIdsAndComm(comm_id int64, ids []string) (postlist []*models.P
 // 按照时间顺序查询帖子
 func GetPostIdsInTime(p *models.ParamPostList) (post []*models.Post, err error) {
 	post = make([]*models.Post, 0)
-	sqlStr := `SELECT post_id, title, content, author_id, community_id, create_time
+	sqlStr := `SELECT post_id, title, content, author_id, community_id, post_type, status, pinned, locked, create_time
 				FROM post
+				WHERE status NOT IN (?, ?)
 				ORDER BY create_time DESC
 				LIMIT ?,?;`
-	err = db.Select(&post, sqlStr, (p.Offset-1)*p.Limit, p.Limit)
+	err = db.Select(&post, sqlStr, models.PostStatusRemoved, models.PostStatusHidden, (p.Offset-1)*p.Limit, p.Limit)
 	return
 }
 
 // 按照时间和社区查询帖子
 func GetPostIdsInCommTime(p *models.ParamPostList) (post []*models.Post, err error) {
 	post = make([]*models.Post, 0)
-	sqlStr := `SELECT post_id, title, content, author_id, community_id, create_time
+	sqlStr := `SELECT post_id, title, content, author_id, community_id, post_type, status, pinned, locked, create_time
 				FROM post
-				WHERE community_id = ? 
+				WHERE community_id = ? AND status NOT IN (?, ?)
 				ORDER BY create_time DESC
 				LIMIT ?,?;`
-	err = db.Select(&post, sqlStr, p.Community_id, (p.Offset-1)*p.Limit, p.Limit)
+	err = db.Select(&post, sqlStr, p.Community_id, models.PostStatusRemoved, models.PostStatusHidden, (p.Offset-1)*p.Limit, p.Limit)
 	return
 }
 
@@ -141,8 +144,11 @@ func GetPostCreateTime(postID int64) (ctimestamp int64, err error) {
 	return ctimestamp, nil
 }
 
-// 开始MySQL事务，并将数据存入mysql数据库
-func BatchInsertPostHotScores(postUpdateScores []redis.Z) error {
+// SyncPostVotes 在一个MySQL事务中写入一批帖子的热度、点赞数和点踩数
+func SyncPostVotes(data []*models.PostSyncData) error {
+	if len(data) == 0 {
+		return nil
+	}
 	// 开启事务
 	tx, err := db.Beginx()
 	if err != nil {
@@ -150,121 +156,40 @@ func BatchInsertPostHotScores(postUpdateScores []redis.Z) error {
 		return err
 	}
 
-	// 构造批量插入 SQL
+	// 构造批量插入热度的 SQL
 	stmt := "INSERT INTO post_hot_scores (post_id, hot_score, updated_at) VALUES "
 	values := []interface{}{}
 	placeholders := []string{}
-
-	for _, post := range postUpdateScores {
-		postID := post.Member.(string) // 获取帖子的 ID
-		hotScore := post.Score         // 获取热度
+	for _, post := range data {
 		placeholders = append(placeholders, "(?, ?, NOW())")
-		values = append(values, postID, hotScore)
+		values = append(values, post.PostID, post.Hot)
 	}
-
-	// 拼接 SQL 语句
 	stmt += strings.Join(placeholders, ",")
 	stmt += " ON DUPLICATE KEY UPDATE hot_score = VALUES(hot_score), updated_at = NOW();"
-
-	// 执行批量插入
-	_, err = tx.Exec(stmt, values...)
-	if err != nil {
-		zap.L().Error("批量插入MySQL失败", zap.Error(err))
+	if _, err = tx.Exec(stmt, values...); err != nil {
+		zap.L().Error("批量插入帖子热度失败", zap.Error(err))
 		tx.Rollback()
 		return err
 	}
 
-	// 提交事务
-	err = tx.Commit()
-	if err != nil {
-		zap.L().Error("提交MySQL事务失败", zap.Error(err))
-		return err
-	}
-	return nil
-}
-
-// BatchInsertLikes 开始MySQL事务，并将更新的点赞数据存入mysql数据库
-func BatchInsertLikes(postUpdateScores []redis.Z) error {
-	// 开启事务
-	tx, err := db.Beginx()
-	if err != nil {
-		zap.L().Error("开启mysql事务失败", zap.Error(err))
-		return err
-	}
-
-	// 构造批量插入 SQL
-	// 1️⃣ 预编译 SQL 语句
-	stmt, err := tx.Preparex("UPDATE post SET likes = ? WHERE post_id = ?")
-	if err != nil {
-		zap.L().Error("SQL预编译失败", zap.Error(err))
-		tx.Rollback() // 事务回滚
-		return err
-	}
-	defer stmt.Close()
-
-	// 2️⃣ 批量执行更新
-	for _, post := range postUpdateScores {
-		postID, ok := post.Member.(string) // Redis ZSET Member 可能是 string
-		if !ok {
-			zap.L().Warn("跳过无效的postID", zap.Any("post", post))
-			continue
-		}
-
-		_, err := stmt.Exec(post.Score, postID)
-		if err != nil {
-			zap.L().Error("更新帖子点赞数失败", zap.String("postID", postID), zap.Error(err))
-			tx.Rollback() // 事务回滚
-			return err
-		}
-	}
-
-	// 提交事务
-	err = tx.Commit()
-	if err != nil {
-		zap.L().Error("提交MySQL事务失败", zap.Error(err))
-		return err
-	}
-	return nil
-}
-
-// BatchInsertDisLikes 开始MySQL事务，并将更新的点赞数据存入mysql数据库
-func BatchInsertDisLikes(postUpdateScores []redis.Z) error {
-	// 开启事务
-	tx, err := db.Beginx()
-	if err != nil {
-		zap.L().Error("开启mysql事务失败", zap.Error(err))
-		return err
-	}
-
-	// 构造批量插入 SQL
-	// 1️⃣ 预编译 SQL 语句
-	stmt, err := tx.Preparex("UPDATE post SET dislikes = ? WHERE post_id = ?")
+	// 预编译更新点赞点踩数的 SQL 语句
+	update, err := tx.Preparex("UPDATE post SET likes = ?, dislikes = ? WHERE post_id = ?")
 	if err != nil {
 		zap.L().Error("SQL预编译失败", zap.Error(err))
 		tx.Rollback() // 事务回滚
 		return err
 	}
-	defer stmt.Close()
-
-	// 2️⃣ 批量执行更新
-	for _, post := range postUpdateScores {
-		postID, ok := post.Member.(string) // Redis ZSET Member 可能是 string
-		if !ok {
-			zap.L().Warn("跳过无效的postID", zap.Any("post", post))
-			continue
-		}
-
-		_, err := stmt.Exec(post.Score, postID)
-		if err != nil {
-			zap.L().Error("更新帖子点踩数失败", zap.String("postID", postID), zap.Error(err))
+	defer update.Close()
+	for _, post := range data {
+		if _, err = update.Exec(post.Likes, post.Dislikes, post.PostID); err != nil {
+			zap.L().Error("更新帖子点赞点踩数失败", zap.String("postID", post.PostID), zap.Error(err))
 			tx.Rollback() // 事务回滚
 			return err
 		}
 	}
 
 	// 提交事务
-	err = tx.Commit()
-	if err != nil {
+	if err = tx.Commit(); err != nil {
 		zap.L().Error("提交MySQL事务失败", zap.Error(err))
 		return err
 	}
@@ -291,3 +216,40 @@ func GetPostAuthor(postID int64) (int64, error) {
 	}
 	return userID, nil
 }
+
+// SetPostStatus 修改帖子状态，移除帖子时同时取消置顶
+func SetPostStatus(postID int64, status int32) error {
+	sqlStr := `update post set status = ?, pinned = IF(? = ?, 0, pinned) where post_id = ?`
+	_, err := db.Exec(sqlStr, status, status, models.PostStatusRemoved, postID)
+	return err
+}
+
+// SetPostPinned 置顶或取消置顶帖子
+func SetPostPinned(postID int64, pinned bool) error {
+	sqlStr := `update post set pinned = ?, pin_time = IF(?, NOW(), NULL) where post_id = ?`
+	_, err := db.Exec(sqlStr, pinned, pinned, postID)
+	return err
+}
+
+// SetPostLocked 锁定或解锁帖子
+func SetPostLocked(postID int64, locked bool) error {
+	_, err := db.Exec(`update post set locked = ? where post_id = ?`, locked, postID)
+	return err
+}
+
+// CountPinnedPosts 查询社区中置顶的帖子数量
+func CountPinnedPosts(communityID int64) (count int64, err error) {
+	err = db.Get(&count, `select count(*) from post where community_id = ? and pinned = 1`, communityID)
+	return
+}
+
+// GetPinnedPosts 查询社区中置顶的帖子，最近置顶的在前
+func GetPinnedPosts(communityID int64) ([]*models.Post, error) {
+	posts := make([]*models.Post, 0)
+	sqlStr := `SELECT post_id, title, content, author_id, community_id, post_type, status, pinned, locked, create_time
+				FROM post
+				WHERE community_id = ? AND pinned = 1 AND status NOT IN (?, ?)
+				ORDER BY pin_time DESC`
+	err := db.Select(&posts, sqlStr, communityID, models.PostStatusRemoved, models.PostStatusHidden)
+	return posts, err
+}
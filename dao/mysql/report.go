@@ -0,0 +1,134 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"database/sql"
+	"fmt"
+)
+
+// reportOpenKey 对象待处理举报单的唯一标识
+func reportOpenKey(targetType string, targetID int64) string {
+	return fmt.Sprintf("%s:%d", targetType, targetID)
+}
+
+// AddReport 提交举报：对象没有待处理的举报单时新建一个，同一用户在同一举报单上的重复举报只更新理由
+// 返回举报单id、举报人数以及是否是新的举报人
+func AddReport(rc *models.ReportCase, r *models.Report) (caseID, count int64, added bool, err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, 0, false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	sqlStr := `INSERT INTO report_case (target_type, target_id, community_id, target_user_id, snapshot, open_key)
+				VALUES (?, ?, ?, ?, ?, ?)
+				ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)`
+	result, err := tx.Exec(sqlStr, rc.TargetType, rc.TargetID, rc.CommunityID, rc.TargetUserID, rc.Snapshot,
+		reportOpenKey(rc.TargetType, rc.TargetID))
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if caseID, err = result.LastInsertId(); err != nil {
+		return 0, 0, false, err
+	}
+	sqlStr = `INSERT INTO report (case_id, reporter_id, reason, detail) VALUES (?, ?, ?, ?)
+				ON DUPLICATE KEY UPDATE reason = VALUES(reason), detail = VALUES(detail)`
+	if result, err = tx.Exec(sqlStr, caseID, r.ReporterID, r.Reason, r.Detail); err != nil {
+		return 0, 0, false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, 0, false, err
+	}
+	// 新插入时影响1行，更新已有举报时影响2行
+	added = affected == 1
+	if added {
+		if _, err = tx.Exec(`UPDATE report_case SET report_count = report_count + 1 WHERE id = ?`, caseID); err != nil {
+			return 0, 0, false, err
+		}
+	}
+	if err = tx.Get(&count, `SELECT report_count FROM report_case WHERE id = ?`, caseID); err != nil {
+		return 0, 0, false, err
+	}
+	return caseID, count, added, tx.Commit()
+}
+
+// SetReportCaseHidden 标记举报单的内容已被自动隐藏
+func SetReportCaseHidden(caseID int64) error {
+	_, err := db.Exec(`UPDATE report_case SET hidden = 1 WHERE id = ?`, caseID)
+	return err
+}
+
+// GetReportCase 查询举报单，不存在时返回nil
+func GetReportCase(caseID int64) (*models.ReportCase, error) {
+	rc := new(models.ReportCase)
+	sqlStr := `SELECT id, target_type, target_id, community_id, target_user_id, snapshot, report_count, hidden,
+				status, resolver_id, note, resolve_time, create_time, update_time
+				FROM report_case WHERE id = ?`
+	err := db.Get(rc, sqlStr, caseID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return rc, err
+}
+
+// GetReports 查询举报单中的全部举报
+func GetReports(caseID int64) ([]*models.Report, error) {
+	reports := make([]*models.Report, 0)
+	sqlStr := `SELECT case_id, reporter_id, reason, detail, create_time FROM report WHERE case_id = ? ORDER BY id`
+	err := db.Select(&reports, sqlStr, caseID)
+	return reports, err
+}
+
+// GetReportCases 分页查询审核队列，举报人数多的在前；communityID 为0时查询全站
+func GetReportCases(communityID int64, status int8, offset, limit int64) ([]*models.ReportCase, error) {
+	cases := make([]*models.ReportCase, 0)
+	sqlStr := `SELECT id, target_type, target_id, community_id, target_user_id, snapshot, report_count, hidden,
+				status, resolver_id, note, resolve_time, create_time, update_time
+				FROM report_case WHERE status = ?`
+	args := []interface{}{status}
+	if communityID != 0 {
+		sqlStr += ` AND community_id = ?`
+		args = append(args, communityID)
+	}
+	sqlStr += ` ORDER BY report_count DESC, id DESC LIMIT ?, ?`
+	args = append(args, (offset-1)*limit, limit)
+	err := db.Select(&cases, sqlStr, args...)
+	return cases, err
+}
+
+// CloseReportCase 处理待处理的举报单，返回举报单是否仍处于待处理状态
+// 处理后对象可以再次被举报，产生新的举报单
+func CloseReportCase(caseID int64, status int8, resolverID int64, note string) (bool, error) {
+	sqlStr := `UPDATE report_case SET status = ?, resolver_id = ?, note = ?, resolve_time = NOW(), open_key = NULL
+				WHERE id = ? AND status = ?`
+	result, err := db.Exec(sqlStr, status, resolverID, note, caseID, models.ReportCaseOpen)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ChangePostStatus 帖子处于 from 状态时改为 to，返回是否修改
+func ChangePostStatus(postID int64, from, to int32) (bool, error) {
+	result, err := db.Exec(`UPDATE post SET status = ? WHERE post_id = ? AND status = ?`, to, postID, from)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ChangeCommentStatus 评论处于 from 状态时改为 to，返回是否修改
+func ChangeCommentStatus(commentID int64, from, to int8) (bool, error) {
+	result, err := db.Exec(`UPDATE comments SET status = ? WHERE comment_id = ? AND status = ?`, to, commentID, from)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
@@ -0,0 +1,59 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"database/sql"
+	"time"
+)
+
+// SaveUserSanction 保存用户的全站处罚，已有同类处罚时更新原因和到期时间
+func SaveUserSanction(s *models.UserSanction) error {
+	sqlStr := `INSERT INTO user_sanction (user_id, type, reason, note, operator_id, expire_time) VALUES (?, ?, ?, ?, ?, ?)
+				ON DUPLICATE KEY UPDATE reason = VALUES(reason), note = VALUES(note), operator_id = VALUES(operator_id),
+				expire_time = VALUES(expire_time), create_time = NOW()`
+	_, err := db.Exec(sqlStr, s.UserID, s.Type, s.Reason, s.Note, s.OperatorID, s.ExpireTime)
+	return err
+}
+
+// DeleteUserSanction 解除用户的全站处罚，返回是否存在处罚记录
+func DeleteUserSanction(userID int64, typ string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM user_sanction WHERE user_id = ? AND type = ?`, userID, typ)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetUserSanction 查询用户尚未到期的某类处罚，没有时返回nil
+func GetUserSanction(userID int64, typ string, now time.Time) (*models.UserSanction, error) {
+	s := new(models.UserSanction)
+	sqlStr := `SELECT user_id, type, reason, note, operator_id, expire_time, create_time
+				FROM user_sanction
+				WHERE user_id = ? AND type = ? AND (expire_time IS NULL OR expire_time > ?)`
+	err := db.Get(s, sqlStr, userID, typ, now)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+// GetUserSanctions 查询用户尚未到期的全部处罚
+func GetUserSanctions(userID int64, now time.Time) ([]*models.UserSanction, error) {
+	list := make([]*models.UserSanction, 0)
+	sqlStr := `SELECT user_id, type, reason, note, operator_id, expire_time, create_time
+				FROM user_sanction
+				WHERE user_id = ? AND (expire_time IS NULL OR expire_time > ?)`
+	err := db.Select(&list, sqlStr, userID, now)
+	return list, err
+}
+
+// GetActiveSanctions 查询某类尚未到期的全部处罚
+func GetActiveSanctions(typ string, now time.Time) ([]*models.UserSanction, error) {
+	list := make([]*models.UserSanction, 0)
+	sqlStr := `SELECT user_id, type, reason, note, operator_id, expire_time, create_time
+				FROM user_sanction
+				WHERE type = ? AND (expire_time IS NULL OR expire_time > ?)`
+	err := db.Select(&list, sqlStr, typ, now)
+	return list, err
+}
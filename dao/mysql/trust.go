@@ -0,0 +1,46 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// GetUserTrustInfo 查询计算信任等级需要的注册时间、邮箱及邮箱是否已验证
+func GetUserTrustInfo(userID int64) (createTime time.Time, email string, emailVerified bool, err error) {
+	var info struct {
+		CreateTime    time.Time `db:"create_time"`
+		Email         string    `db:"email"`
+		EmailVerified bool      `db:"email_verified"`
+	}
+	sqlStr := `select create_time, COALESCE(email, '') as email, email_verified from user where user_id = ?`
+	if err = db.Get(&info, sqlStr, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrorUserNotExist
+		}
+		return
+	}
+	return info.CreateTime, info.Email, info.EmailVerified, nil
+}
+
+// GetUserKarma 用户的声望：正常状态的帖子和评论获得的赞减踩之和
+func GetUserKarma(userID int64) (int64, error) {
+	var karma int64
+	sqlStr := `select
+		(select COALESCE(SUM(likes - dislikes), 0) from post where author_id = ? and status = ?) +
+		(select COALESCE(SUM(likes - dislikes), 0) from comments where user_id = ? and status = ?)`
+	err := db.Get(&karma, sqlStr, userID, models.PostStatusNormal, userID, models.CommentStatusNormal)
+	return karma, err
+}
+
+// SetEmailVerified 邮箱仍是验证时的邮箱才标记为已验证，返回是否更新成功
+func SetEmailVerified(userID int64, email string) (bool, error) {
+	sqlStr := `update user set email_verified = 1 where user_id = ? and email = ?`
+	ret, err := db.Exec(sqlStr, userID, email)
+	if err != nil {
+		return false, err
+	}
+	n, err := ret.RowsAffected()
+	return n > 0, err
+}
@@ -3,8 +3,10 @@ package mysql
 import (
 	"bluebell/models"
 	"database/sql"
+	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
+	"time"
 )
 
 // encryptPassword 使用 bcrypt 加密密码
@@ -110,6 +112,12 @@ func IsTokenBlacklisted(token string) bool {
 	return count > 0
 }
 
+// PurgeBlacklist 删除 before 之前加入黑名单的Token，返回删除的数量
+func PurgeBlacklist(before time.Time) (int64, error) {
+	result := gormdb.Where("created_at < ?", before).Delete(&models.JWTBlacklist{})
+	return result.RowsAffected, result.Error
+}
+
 // CheckPassword 验证密码是否正确
 func CheckPassword(userID int64, password string) (flag bool, err error) {
 	//fmt.Println(userID)
@@ -141,7 +149,7 @@ func ModifyPassword(userID int64, password string) (err error) {
 	if err != nil {
 		return
 	}
-	sqlStr := `UPDATE user SET password=? WHERE id=?`
+	sqlStr := `UPDATE user SET password=? WHERE user_id=?`
 	_, err = db.Exec(sqlStr, encrypwd, userID)
 	return
 }
@@ -168,6 +176,22 @@ func GetUsersByName(username string) ([]*models.UserSafe, error) {
 	return users, nil
 }
 
+// GetUsersByNames 根据用户名精确查询一批用户
+func GetUsersByNames(names []string) ([]*models.UserSafe, error) {
+	users := make([]*models.UserSafe, 0)
+	if len(names) == 0 {
+		return users, nil
+	}
+	query, args, err := sqlx.In("SELECT user_id, username FROM user WHERE username IN (?)", names)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Select(&users, db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 // GetUsers 获得全部用户
 func GetUsers() ([]*models.UserSafe, error) {
 	users := make([]*models.UserSafe, 0)
@@ -179,13 +203,13 @@ func GetUsers() ([]*models.UserSafe, error) {
 	return users, nil
 }
 
-// GetPostListByUserID 根据用户查询其全部帖子
+// GetPostListByUserID 根据用户查询其全部帖子，不包括被移除和隐藏的帖子
 func GetPostListByUserID(userID int64) (posts []*models.Post, err error) {
 	posts = make([]*models.Post, 0)
-	sqlStr := `SELECT post_id, community_id, status, title, content, create_time FROM post
-				WHERE author_id = ?
+	sqlStr := `SELECT post_id, author_id, community_id, status, title, content, create_time FROM post
+				WHERE author_id = ? AND status NOT IN (?, ?)
 				ORDER BY create_time DESC;`
-	err = db.Select(&posts, sqlStr, userID)
+	err = db.Select(&posts, sqlStr, userID, models.PostStatusRemoved, models.PostStatusHidden)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,86 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+)
+
+// SavePostVote 在一个事务中追加投票事件，并保存用户对帖子的当前投票，取消投票时删除当前投票
+func SavePostVote(userID, postID int64, direction int8) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// 锁住当前投票，得到投票前的方向
+	var old int8
+	sqlStr := `select direction from post_vote where post_id = ? and user_id = ? for update`
+	if err = tx.Get(&old, sqlStr, postID, userID); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	sqlStr = `insert into post_vote_event (post_id, user_id, old_direction, direction) values (?, ?, ?, ?)`
+	if _, err = tx.Exec(sqlStr, postID, userID, old, direction); err != nil {
+		return err
+	}
+
+	if direction == 0 {
+		sqlStr = `delete from post_vote where post_id = ? and user_id = ?`
+		_, err = tx.Exec(sqlStr, postID, userID)
+	} else {
+		sqlStr = `insert into post_vote (post_id, user_id, direction) values (?, ?, ?)
+				on duplicate key update direction = values(direction)`
+		_, err = tx.Exec(sqlStr, postID, userID, direction)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetUserVotedPosts 分页查询用户投过票的帖子，direction为0时查询全部
+func GetUserVotedPosts(userID int64, p *models.ParamVoteList) ([]*models.ApiVotedPost, error) {
+	posts := make([]*models.ApiVotedPost, 0)
+	sqlStr := `SELECT v.direction, v.update_time AS vote_time,
+					p.post_id, p.title, p.content, p.author_id, p.community_id, p.create_time
+				FROM post_vote v
+				JOIN post p ON p.post_id = v.post_id
+				WHERE v.user_id = ? AND (? = 0 OR v.direction = ?)
+				ORDER BY v.update_time DESC
+				LIMIT ?,?;`
+	err := db.Select(&posts, sqlStr, userID, p.Direction, p.Direction, (p.Offset-1)*p.Limit, p.Limit)
+	return posts, err
+}
+
+// GetPostsForRebuild 按post_id顺序分批查询帖子及其同步过的热度和票数，不包括被移除和隐藏的帖子
+func GetPostsForRebuild(afterID int64, limit int) ([]*models.PostRankRow, error) {
+	rows := make([]*models.PostRankRow, 0, limit)
+	sqlStr := `SELECT p.post_id, p.community_id, p.create_time, COALESCE(p.likes, 0) AS likes, COALESCE(p.dislikes, 0) AS dislikes, h.hot_score
+				FROM post p
+				LEFT JOIN post_hot_scores h ON h.post_id = p.post_id
+				WHERE p.post_id > ? AND p.status NOT IN (?, ?)
+				ORDER BY p.post_id
+				LIMIT ?;`
+	err := db.Select(&rows, sqlStr, afterID, models.PostStatusRemoved, models.PostStatusHidden, limit)
+	return rows, err
+}
+
+// GetPostVotesByPostIDs 查询一批帖子的全部投票
+func GetPostVotesByPostIDs(postIDs []int64) ([]*models.PostVote, error) {
+	votes := make([]*models.PostVote, 0)
+	if len(postIDs) == 0 {
+		return votes, nil
+	}
+	query, args, err := sqlx.In(`select post_id, user_id, direction, update_time from post_vote where post_id in (?)`, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Select(&votes, db.Rebind(query), args...)
+	return votes, err
+}
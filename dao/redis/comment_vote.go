@@ -0,0 +1,142 @@
+package redis
+
+import (
+	"bluebell/models"
+	"context"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+)
+
+// 评论投票
+/*
+	与帖子投票相同：comment:voted:<comment_id> 记录每个用户的投票，
+	comment:vote_count:<comment_id> 维护 up/down 计数，投票后评论被标记到 comment:dirty，
+	由同步任务把点赞数和点踩数写回 comments 表。
+*/
+
+// commentVoteScript 原子地完成一次评论投票
+// KEYS[1] 评论投票zset  KEYS[2] 评论票数hash  KEYS[3] 待同步zset  KEYS[4] 待同步版本号hash
+// ARGV[1] 用户id  ARGV[2] 投票方向  ARGV[3] 评论id  ARGV[4] 当前时间
+// ARGV[5] MySQL中的点赞数  ARGV[6] MySQL中的点踩数，票数hash不存在时用于初始化
+// 返回 {是否改变, 赞成票数, 反对票数}
+var commentVoteScript = redis.NewScript(`
+local v = tonumber(ARGV[2])
+local old = tonumber(redis.call('ZSCORE', KEYS[1], ARGV[1]) or 0)
+
+-- 票数hash不存在时，有投票zset则从中统计，否则以 MySQL 中的票数为准
+if redis.call('EXISTS', KEYS[2]) == 0 then
+	if redis.call('EXISTS', KEYS[1]) == 1 then
+		redis.call('HSET', KEYS[2], 'up', redis.call('ZCOUNT', KEYS[1], 1, 1), 'down', redis.call('ZCOUNT', KEYS[1], -1, -1))
+	else
+		redis.call('HSET', KEYS[2], 'up', ARGV[5], 'down', ARGV[6])
+	end
+end
+
+-- 不允许重复投票
+if old == v then
+	return {0, tonumber(redis.call('HGET', KEYS[2], 'up')), tonumber(redis.call('HGET', KEYS[2], 'down'))}
+end
+
+if v == 0 then
+	redis.call('ZREM', KEYS[1], ARGV[1])
+else
+	redis.call('ZADD', KEYS[1], v, ARGV[1])
+end
+if old == 1 then
+	redis.call('HINCRBY', KEYS[2], 'up', -1)
+elseif old == -1 then
+	redis.call('HINCRBY', KEYS[2], 'down', -1)
+end
+if v == 1 then
+	redis.call('HINCRBY', KEYS[2], 'up', 1)
+elseif v == -1 then
+	redis.call('HINCRBY', KEYS[2], 'down', 1)
+end
+
+-- 标记评论待同步到 MySQL，分数为最近一次投票时间，版本号用于同步时判断期间是否有新投票
+redis.call('ZADD', KEYS[3], ARGV[4], ARGV[3])
+redis.call('HINCRBY', KEYS[4], ARGV[3], 1)
+return {1, tonumber(redis.call('HGET', KEYS[2], 'up')), tonumber(redis.call('HGET', KEYS[2], 'down'))}
+`)
+
+// VoteForComment 用户为评论投票，原子地更新用户投票及票数，并标记评论待同步
+// likes、dislikes 为 MySQL 中的票数，仅在 redis 中还没有该评论的票数时使用
+// 返回本次投票是否改变了用户对该评论的投票，以及投票后的赞成票数和反对票数
+func VoteForComment(c context.Context, userID, commentID string, v float64, now int64, likes, dislikes int) (changed bool, ups, downs int64, err error) {
+	keys := []string{
+		getRedisKey(KeyCommentVotedZSetPrefix + commentID),
+		getRedisKey(KeyCommentVoteCountPrefix + commentID),
+		getRedisKey(KeyCommentDirtyZSet),
+		getRedisKey(KeyCommentDirtyVersion),
+	}
+	res, err := commentVoteScript.Run(c, rdb, keys, userID, v, commentID, now, likes, dislikes).Int64Slice()
+	if err != nil {
+		return false, 0, 0, err
+	}
+	return res[0] == 1, res[1], res[2], nil
+}
+
+// FillCommentVoteCounts 用 redis 中的票数覆盖评论的点赞数和点踩数
+// redis 中的票数比 comments 表更新，没有投票记录的评论保留 MySQL 中的票数
+func FillCommentVoteCounts(c context.Context, comments []*models.Comment) error {
+	if len(comments) == 0 {
+		return nil
+	}
+	// 使用pipeline 减少 Redis 请求的 RTT
+	pipe := rdb.Pipeline()
+	cmds := make([]*redis.SliceCmd, len(comments))
+	for i, comment := range comments {
+		cmds[i] = pipe.HMGet(c, getRedisKey(KeyCommentVoteCountPrefix+strconv.FormatInt(comment.CommentID, 10)), "up", "down")
+	}
+	if _, err := pipe.Exec(c); err != nil {
+		return err
+	}
+	for i, cmd := range cmds {
+		vals := cmd.Val()
+		if vals[0] == nil || vals[1] == nil {
+			continue
+		}
+		var counts struct {
+			Up   int `redis:"up"`
+			Down int `redis:"down"`
+		}
+		if err := cmd.Scan(&counts); err != nil {
+			return err
+		}
+		comments[i].Likes, comments[i].Dislikes = counts.Up, counts.Down
+	}
+	return nil
+}
+
+// GetDirtyComments 获取最早待同步的count个评论及其当前的投票版本号
+func GetDirtyComments(c context.Context, count int64) ([]*models.DirtyItem, error) {
+	return getDirtyItems(c, getRedisKey(KeyCommentDirtyZSet), getRedisKey(KeyCommentDirtyVersion), count)
+}
+
+// GetCommentSyncData 获取评论需要同步到 MySQL 的点赞数和点踩数
+func GetCommentSyncData(c context.Context, commentIDs []string) ([]*models.CommentSyncData, error) {
+	if len(commentIDs) == 0 {
+		return nil, nil
+	}
+	pipe := rdb.Pipeline()
+	cmds := make([]*redis.SliceCmd, len(commentIDs))
+	for i, id := range commentIDs {
+		cmds[i] = pipe.HMGet(c, getRedisKey(KeyCommentVoteCountPrefix+id), "up", "down")
+	}
+	if _, err := pipe.Exec(c); err != nil {
+		return nil, err
+	}
+	data := make([]*models.CommentSyncData, len(commentIDs))
+	for i, cmd := range cmds {
+		data[i] = &models.CommentSyncData{CommentID: commentIDs[i]}
+		if err := cmd.Scan(data[i]); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// AckDirtyComments 将已同步的评论移出待同步集合，返回移除的数量
+func AckDirtyComments(c context.Context, batch []*models.DirtyItem) (int64, error) {
+	return ackDirtyItems(c, getRedisKey(KeyCommentDirtyZSet), getRedisKey(KeyCommentDirtyVersion), batch)
+}
@@ -0,0 +1,83 @@
+package redis
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// AddCommunityPost 将新帖子加入社区的热度zset
+func AddCommunityPost(c context.Context, communityID, postID int64, hot float64) error {
+	key := getRedisKey(KeyCommunityScoreZSetPrefix + strconv.FormatInt(communityID, 10))
+	return rdb.ZAdd(c, key, redis.Z{Score: hot, Member: postID}).Err()
+}
+
+// RestoreCommunityPost 将审核通过的帖子加入社区的热度zset，已在zset中的帖子保留原来的热度
+func RestoreCommunityPost(c context.Context, communityID, postID int64, hot float64) error {
+	key := getRedisKey(KeyCommunityScoreZSetPrefix + strconv.FormatInt(communityID, 10))
+	return rdb.ZAddNX(c, key, redis.Z{Score: hot, Member: postID}).Err()
+}
+
+// GetHomeFeedIDs 按热度分页获取用户订阅社区中的帖子id
+// 合并结果缓存ttl时间，翻页时使用同一份结果，过期后重新合并各社区的热度zset
+func GetHomeFeedIDs(c context.Context, userID int64, communityIDs []int64, offset, limit int64, ttl time.Duration) ([]string, error) {
+	key := getRedisKey(KeyHomeFeedZSetPrefix + strconv.FormatInt(userID, 10))
+	exist, err := rdb.Exists(c, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if exist == 0 {
+		keys := make([]string, len(communityIDs))
+		for i, id := range communityIDs {
+			keys[i] = getRedisKey(KeyCommunityScoreZSetPrefix + strconv.FormatInt(id, 10))
+		}
+		pipe := rdb.TxPipeline()
+		pipe.ZUnionStore(c, key, &redis.ZStore{Keys: keys, Aggregate: "MAX"})
+		pipe.Expire(c, key, ttl)
+		if _, err := pipe.Exec(c); err != nil {
+			return nil, err
+		}
+	}
+	return getIDsFromKey(c, key, offset, limit)
+}
+
+// ClearHomeFeed 订阅的社区变化后清除用户的首页缓存
+func ClearHomeFeed(c context.Context, userID int64) error {
+	return rdb.Del(c, getRedisKey(KeyHomeFeedZSetPrefix+strconv.FormatInt(userID, 10))).Err()
+}
+
+// GetRecommendedCommunityIDs 获取缓存的相似用户推荐的社区，按分数从高到低排列，没有缓存时ok为false
+func GetRecommendedCommunityIDs(c context.Context, userID int64) (ids []int64, ok bool, err error) {
+	members, err := rdb.ZRevRange(c, getRedisKey(KeyCommunityRecommendZSetPrefix+strconv.FormatInt(userID, 10)), 0, -1).Result()
+	if err != nil || len(members) == 0 {
+		return nil, false, err
+	}
+	ids = make([]int64, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			return nil, false, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, true, nil
+}
+
+// SaveRecommendedCommunities 缓存相似用户推荐的社区及分数
+func SaveRecommendedCommunities(c context.Context, userID int64, scores map[int64]float64, ttl time.Duration) error {
+	if len(scores) == 0 {
+		return nil
+	}
+	key := getRedisKey(KeyCommunityRecommendZSetPrefix + strconv.FormatInt(userID, 10))
+	members := make([]redis.Z, 0, len(scores))
+	for id, score := range scores {
+		members = append(members, redis.Z{Score: score, Member: id})
+	}
+	pipe := rdb.TxPipeline()
+	pipe.Del(c, key)
+	pipe.ZAdd(c, key, members...)
+	pipe.Expire(c, key, ttl)
+	_, err := pipe.Exec(c)
+	return err
+}
@@ -4,14 +4,49 @@ package redis
 redis key 尽量使用命名空间的方式，方便查询和拆分
 */
 const (
-	keyPrefix             = "bluebell:"
-	KeyPostTimeZSet       = "post:time"        // zset: 帖子及发帖时间
-	KeyPostScoreZSet      = "post:score"       // zset: 帖子及投票的分数
-	KeyPostVotedZSetPreix = "post:voted:"      // zset: 记录用户及投票类型, 参数帖子post_id
-	KeyPostUpdateTimeZSet = "post:update_time" // zset: 帖子及给帖子上次投票时间
+	keyPrefix              = "bluebell:"
+	KeyPostTimeZSet        = "post:time"        // zset: 帖子及发帖时间
+	KeyPostScoreZSet       = "post:score"       // zset: 帖子及投票的分数
+	KeyPostVotedZSetPreix  = "post:voted:"      // zset: 记录用户及投票类型, 参数帖子post_id
+	KeyPostDirtyZSet       = "post:dirty"       // zset: 待同步到MySQL的帖子及最近一次投票时间
+	KeyPostDirtyVersion    = "post:dirty_ver"   // hash: 待同步帖子的投票版本号, 每次投票加1
+	KeyPostVotePending     = "post:vote_retry"  // set: 写入MySQL失败等待重放的用户投票, 成员为post_id:user_id
+	KeyPostVoteCountPrefix = "post:vote_count:" // hash: 帖子的赞成票up及反对票down数量, 参数帖子post_id
 
-	LastSyncTimeHotDLikesKey = "last_hot_sync_time" // string: 记录上次点赞数同步时间的 Redis Key
+	KeyPostTopZSetPrefix     = "post:top:"          // zset: 时间窗口内帖子及净赞成票, 参数窗口day/week/month
+	KeyPostControversialZSet = "post:controversial" // zset: 帖子及争议度
+	KeyPostBestZSet          = "post:best"          // zset: 帖子及Wilson置信区间下界
+	KeyPostGravityZSet       = "post:gravity"       // zset: 帖子及重力衰减分数
 
+	KeyCommentVotedZSetPrefix = "comment:voted:"      // zset: 记录用户及对评论的投票类型, 参数评论comment_id
+	KeyCommentVoteCountPrefix = "comment:vote_count:" // hash: 评论的赞成票up及反对票down数量, 参数评论comment_id
+	KeyCommentDirtyZSet       = "comment:dirty"       // zset: 待同步到MySQL的评论及最近一次投票时间
+	KeyCommentDirtyVersion    = "comment:dirty_ver"   // hash: 待同步评论的投票版本号, 每次投票加1
+
+	KeyCommunityScoreZSetPrefix     = "community:score:"     // zset: 社区内的帖子及热度, 参数社区community_id
+	KeyCommunityRecommendZSetPrefix = "community:recommend:" // zset: 相似用户推荐给用户的社区及分数（短期缓存）, 参数用户user_id
+	KeyHomeFeedZSetPrefix           = "feed:home:"           // zset: 用户订阅社区的帖子热度合并结果（短期缓存）, 参数用户user_id
+
+	KeyRealtimeUserChannelPrefix = "rt:user:"   // pub/sub: 发给用户的实时事件, 参数用户user_id
+	KeyRealtimePostChannelPrefix = "rt:post:"   // pub/sub: 帖子上的实时事件, 参数帖子post_id
+	KeyRealtimeTicketPrefix      = "rt:ticket:" // string: 建立事件流的一次性凭证及对应的用户id, 参数凭证
+
+	KeyNewConversationPrefix = "dm:new_conv:" // string: 用户在当前时间窗口内发起的新会话数量, 参数用户user_id
+
+	KeyTrustLevelPrefix  = "trust:level:"  // string: 用户信任等级的短期缓存, 参数用户user_id
+	KeyQuotaPrefix       = "quota:"        // string: 用户在当前小时内某种操作的次数, 参数操作及用户action:user_id
+	KeySpamContentPrefix = "spam:content:" // string: 相同内容最近出现的次数, 参数内容的哈希
+	KeySpamLinkPrefix    = "spam:link:"    // string: 相同链接最近出现的次数, 参数链接的哈希
+	KeyEmailCodePrefix   = "email:code:"   // string: 邮箱验证码及对应的邮箱, 参数用户user_id
+
+	KeyUserSuspended      = "user:suspended"        // zset: 被封禁的用户, 分数为到期时间
+	KeyUserShadowBanned   = "user:shadow_banned"    // zset: 被影子封禁的用户, 分数为到期时间
+	KeyUserTokenNotBefore = "user:token_not_before" // hash: 用户在该时间之前签发的Token全部失效
+
+	KeySyncCheckpointHash = "sync:vote:checkpoint"    // hash: 投票同步的进度及延迟
+	KeySyncLock           = "sync:vote:lock"          // string: 投票同步锁，防止多个实例同时同步
+	KeyRebuildCheckpoint  = "rebuild:checkpoint"      // string: 重建排序数据时最后处理完的post_id
+	KeyCommunityScoreDone = "rebuild:community_score" // string: 各社区热度zset已包含全部帖子，完成过一次完整的重建后设置
 )
 
 // 给redis key加上前缀
@@ -0,0 +1,18 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// IncrNewConversation 记录用户发起了一个新会话，返回当前时间窗口内发起的新会话数量
+func IncrNewConversation(c context.Context, userID int64, window time.Duration) (int64, error) {
+	// 时间窗口从第一次发起新会话开始
+	return incrWindow(c, getRedisKey(KeyNewConversationPrefix+strconv.FormatInt(userID, 10)), 1, window)
+}
+
+// DecrNewConversation 新会话创建失败时退还计数
+func DecrNewConversation(c context.Context, userID int64) error {
+	return decrWindow(c, getRedisKey(KeyNewConversationPrefix+strconv.FormatInt(userID, 10)), 1)
+}
@@ -0,0 +1,41 @@
+package redis
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"testing"
+	"time"
+)
+
+func TestDecrNewConversation(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(Close)
+	c := context.Background()
+	key := getRedisKey(KeyNewConversationPrefix + "1")
+
+	for i := 0; i < 2; i++ {
+		if _, err := IncrNewConversation(c, 1, time.Hour); err != nil {
+			t.Fatalf("IncrNewConversation error: %v", err)
+		}
+	}
+	if err := DecrNewConversation(c, 1); err != nil {
+		t.Fatalf("DecrNewConversation error: %v", err)
+	}
+	if got, _ := mr.Get(key); got != "1" {
+		t.Errorf("count after refund = %q, want 1", got)
+	}
+	if ttl := mr.TTL(key); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("ttl after refund = %v, want the original window", ttl)
+	}
+
+	// 时间窗口过期后退还不会重新创建计数
+	mr.FastForward(time.Hour + time.Second)
+	if err := DecrNewConversation(c, 1); err != nil {
+		t.Fatalf("DecrNewConversation error: %v", err)
+	}
+	if mr.Exists(key) {
+		t.Error("refund after the window expired recreated the counter")
+	}
+}
@@ -2,12 +2,13 @@ package redis
 
 import (
 	"bluebell/models"
+	"context"
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
 	"strconv"
 )
 
-func getIDsFromKey(c *gin.Context, key string, offset int64, limit int64) ([]string, error) {
+func getIDsFromKey(c context.Context, key string, offset int64, limit int64) ([]string, error) {
 	start := (offset - 1) * limit
 	end := start + limit - 1
 	// ZREVRANGE 按照分数从大到小查询指定数量的元素
@@ -16,12 +17,36 @@ func getIDsFromKey(c *gin.Context, key string, offset int64, limit int64) ([]str
 
 // GetPostIdsInOrder 从redis获取帖子ids并以[]string返回
 func GetPostIdsInOrder(c *gin.Context, p *models.ParamPostList) ([]string, error) {
-	// 查询key
-	key := getRedisKey(KeyPostTimeZSet)
+	// 查询key，按社区查询热度排序时直接使用社区的热度zset
+	key := getOrderKey(p.Order, p.Window)
+	if p.Order == models.OrderScore && p.Community_id != 0 {
+		key = getRedisKey(KeyCommunityScoreZSetPrefix + strconv.FormatInt(p.Community_id, 10))
+	}
 	// 确定查询起始点并查询
 	return getIDsFromKey(c, key, p.Offset, p.Limit)
 }
 
+// getOrderKey 根据排序方式得到对应的zset
+func getOrderKey(order, window string) string {
+	switch order {
+	case models.OrderScore:
+		return getRedisKey(KeyPostScoreZSet)
+	case models.OrderTop:
+		if window == "" {
+			window = models.WindowDay
+		}
+		return getRedisKey(KeyPostTopZSetPrefix + window)
+	case models.OrderControversial:
+		return getRedisKey(KeyPostControversialZSet)
+	case models.OrderBest:
+		return getRedisKey(KeyPostBestZSet)
+	case models.OrderGravity:
+		return getRedisKey(KeyPostGravityZSet)
+	default:
+		return getRedisKey(KeyPostTimeZSet)
+	}
+}
+
 // 从redis获取create_time
 func GetPostCreateTime(c *gin.Context, postid int64) (float64, error) {
 	key := getRedisKey(KeyPostTimeZSet)
@@ -0,0 +1,84 @@
+package redis
+
+import (
+	"bluebell/models"
+	"context"
+	"github.com/redis/go-redis/v9"
+)
+
+// PruneTopWindow 将发帖时间早于threshold的帖子移出对应时间窗口，返回移除的数量
+func PruneTopWindow(c context.Context, window string, threshold int64) (int64, error) {
+	key := getRedisKey(KeyPostTopZSetPrefix + window)
+	ids, err := rdb.ZRange(c, key, 0, -1).Result()
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	// 发帖时间都记录在 post:time 中
+	ctimes, err := rdb.ZMScore(c, getRedisKey(KeyPostTimeZSet), ids...).Result()
+	if err != nil {
+		return 0, err
+	}
+	expired := make([]interface{}, 0)
+	for i, id := range ids {
+		if int64(ctimes[i]) < threshold {
+			expired = append(expired, id)
+		}
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+	return rdb.ZRem(c, key, expired...).Result()
+}
+
+// GetGravityPosts 获取参与重力衰减排序的帖子及其投票统计
+func GetGravityPosts(c context.Context) ([]*models.PostVoteStat, error) {
+	ids, err := rdb.ZRange(c, getRedisKey(KeyPostGravityZSet), 0, -1).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	ctimes, err := rdb.ZMScore(c, getRedisKey(KeyPostTimeZSet), ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	ups, downs, err := getVoteCounts(c, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]*models.PostVoteStat, len(ids))
+	for i, id := range ids {
+		stats[i] = &models.PostVoteStat{
+			PostID:     id,
+			Ups:        ups[i],
+			Downs:      downs[i],
+			CreateTime: int64(ctimes[i]),
+		}
+	}
+	return stats, nil
+}
+
+// UpdateGravityScore 更新帖子的重力衰减分数
+func UpdateGravityScore(c context.Context, postid string, score float64) error {
+	return rdb.ZAdd(c, getRedisKey(KeyPostGravityZSet), redis.Z{Score: score, Member: postid}).Err()
+}
+
+// RemoveGravityPost 将帖子移出重力衰减排序
+func RemoveGravityPost(c context.Context, postid string) error {
+	return rdb.ZRem(c, getRedisKey(KeyPostGravityZSet), postid).Err()
+}
+
+// RemovePostRanking 将帖子移出全部排序及所在社区的热度排序，帖子的投票数据保留
+func RemovePostRanking(c context.Context, postid, communityID string) error {
+	pipe := rdb.TxPipeline()
+	pipe.ZRem(c, getRedisKey(KeyPostScoreZSet), postid)
+	pipe.ZRem(c, getRedisKey(KeyPostControversialZSet), postid)
+	pipe.ZRem(c, getRedisKey(KeyPostBestZSet), postid)
+	pipe.ZRem(c, getRedisKey(KeyPostGravityZSet), postid)
+	for _, window := range []string{models.WindowDay, models.WindowWeek, models.WindowMonth} {
+		pipe.ZRem(c, getRedisKey(KeyPostTopZSetPrefix+window), postid)
+	}
+	pipe.ZRem(c, getRedisKey(KeyCommunityScoreZSetPrefix+communityID), postid)
+	_, err := pipe.Exec(c)
+	return err
+}
@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"bluebell/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// 实时事件
+/*
+	事件发布到 redis 的频道，每个 bluebell 实例只用一个订阅连接，
+	按本实例上客户端的需要订阅或退订频道，再把收到的事件分发给本实例上的客户端。
+*/
+
+// RealtimeUserChannel 发给某个用户的事件频道
+func RealtimeUserChannel(userID int64) string {
+	return getRedisKey(KeyRealtimeUserChannelPrefix + strconv.FormatInt(userID, 10))
+}
+
+// RealtimePostChannel 某个帖子上的事件频道
+func RealtimePostChannel(postID int64) string {
+	return getRedisKey(KeyRealtimePostChannelPrefix + strconv.FormatInt(postID, 10))
+}
+
+// PublishRealtime 发布一个实时事件
+func PublishRealtime(c context.Context, channel, typ string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(&models.RealtimeEvent{Type: typ, Data: raw})
+	if err != nil {
+		return err
+	}
+	return rdb.Publish(c, channel, payload).Err()
+}
+
+// NewRealtimeSubscriber 创建一个还没有订阅任何频道的订阅连接
+func NewRealtimeSubscriber(c context.Context) *redis.PubSub {
+	return rdb.Subscribe(c)
+}
+
+// SaveStreamTicket 保存建立事件流的一次性凭证
+func SaveStreamTicket(c context.Context, ticket string, userID int64, ttl time.Duration) error {
+	return rdb.Set(c, getRedisKey(KeyRealtimeTicketPrefix+ticket), userID, ttl).Err()
+}
+
+// TakeStreamTicket 取出并删除一次性凭证，返回对应的用户id；凭证不存在或已过期时 ok 为 false
+func TakeStreamTicket(c context.Context, ticket string) (userID int64, ok bool, err error) {
+	userID, err = rdb.GetDel(c, getRedisKey(KeyRealtimeTicketPrefix+ticket)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, false, nil
+	}
+	return userID, err == nil, err
+}
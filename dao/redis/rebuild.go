@@ -0,0 +1,114 @@
+package redis
+
+import (
+	"bluebell/models"
+	"context"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+)
+
+// mergeVotersScript 把 MySQL 中的投票合并到帖子投票zset，不删除 redis 中已有的投票，同一用户的投票以 redis 为准
+// 只存在于 redis 的投票（post_vote 表出现之前的投票）因此不会丢失
+// KEYS[1] 帖子投票zset  ARGV 依次为用户id和投票方向
+// 返回合并后的 {赞成票数, 反对票数}
+var mergeVotersScript = redis.NewScript(`
+for i = 1, #ARGV, 2 do
+	redis.call('ZADD', KEYS[1], 'NX', ARGV[i + 1], ARGV[i])
+end
+return {redis.call('ZCOUNT', KEYS[1], 1, 1), redis.call('ZCOUNT', KEYS[1], -1, -1)}
+`)
+
+// MergePostVoters 把一批帖子在 MySQL 中的投票合并到 redis，有投票的帖子用合并后的票数更新 Ups、Downs 并标记 Voted；
+// 没有投票的帖子保持原来的票数
+func MergePostVoters(c context.Context, posts []*models.PostRankState) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	pipe := rdb.Pipeline()
+	cmds := make([]*redis.Cmd, len(posts))
+	for i, p := range posts {
+		args := make([]interface{}, 0, len(p.Voters)*2)
+		for userID, direction := range p.Voters {
+			if direction != 0 {
+				args = append(args, userID, direction)
+			}
+		}
+		// 管道中无法在 NOSCRIPT 时重试，直接用 EVAL
+		cmds[i] = mergeVotersScript.Eval(c, pipe, []string{getRedisKey(KeyPostVotedZSetPreix + p.PostID)}, args...)
+	}
+	if _, err := pipe.Exec(c); err != nil {
+		return err
+	}
+	for i, p := range posts {
+		counts, err := cmds[i].Int64Slice()
+		if err != nil {
+			return err
+		}
+		if counts[0]+counts[1] > 0 {
+			p.Ups, p.Downs, p.Voted = counts[0], counts[1], true
+		}
+	}
+	return nil
+}
+
+// RestorePostRanking 将一批帖子的发帖时间、票数及各排序分数写回 redis，投票先由 MergePostVoters 合并
+func RestorePostRanking(c context.Context, posts []*models.PostRankState) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	pipe := rdb.Pipeline()
+	for _, p := range posts {
+		pipe.ZAdd(c, getRedisKey(KeyPostTimeZSet), redis.Z{Score: float64(p.CreateTime), Member: p.PostID})
+		pipe.HSet(c, getRedisKey(KeyPostVoteCountPrefix+p.PostID), "up", p.Ups, "down", p.Downs)
+
+		pipe.ZAdd(c, getRedisKey(KeyPostScoreZSet), redis.Z{Score: p.Scores.Hot, Member: p.PostID})
+		pipe.ZAdd(c, getRedisKey(KeyCommunityScoreZSetPrefix+p.CommunityID), redis.Z{Score: p.Scores.Hot, Member: p.PostID})
+		pipe.ZAdd(c, getRedisKey(KeyPostControversialZSet), redis.Z{Score: p.Scores.Controversial, Member: p.PostID})
+		pipe.ZAdd(c, getRedisKey(KeyPostBestZSet), redis.Z{Score: p.Scores.Best, Member: p.PostID})
+		pipe.ZAdd(c, getRedisKey(KeyPostGravityZSet), redis.Z{Score: p.Scores.Gravity, Member: p.PostID})
+		for _, window := range p.Scores.TopWindows {
+			pipe.ZAdd(c, getRedisKey(KeyPostTopZSetPrefix+window), redis.Z{Score: p.Scores.Net, Member: p.PostID})
+		}
+	}
+	_, err := pipe.Exec(c)
+	return err
+}
+
+// CountPostTime 获取 post:time 中的帖子数量，为 0 说明 redis 中的排序数据已丢失
+func CountPostTime(c context.Context) (int64, error) {
+	return rdb.ZCard(c, getRedisKey(KeyPostTimeZSet)).Result()
+}
+
+// GetRebuildCheckpoint 获取上次重建处理到的post_id，没有进行中的重建时返回0
+func GetRebuildCheckpoint(c context.Context) (int64, error) {
+	val, err := rdb.Get(c, getRedisKey(KeyRebuildCheckpoint)).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(val, 10, 64)
+}
+
+// SetRebuildCheckpoint 记录重建处理到的post_id
+func SetRebuildCheckpoint(c context.Context, postID int64) error {
+	return rdb.Set(c, getRedisKey(KeyRebuildCheckpoint), strconv.FormatInt(postID, 10), 0).Err()
+}
+
+// ClearRebuildCheckpoint 重建完成后清除进度
+func ClearRebuildCheckpoint(c context.Context) error {
+	return rdb.Del(c, getRedisKey(KeyRebuildCheckpoint)).Err()
+}
+
+// IsCommunityScoreDone 各社区热度zset是否已包含全部帖子
+// 社区热度zset出现之前发布的帖子只在完整重建后才会加入
+func IsCommunityScoreDone(c context.Context) (bool, error) {
+	n, err := rdb.Exists(c, getRedisKey(KeyCommunityScoreDone)).Result()
+	return n > 0, err
+}
+
+// SetCommunityScoreDone 完整重建后标记各社区热度zset已包含全部帖子
+func SetCommunityScoreDone(c context.Context) error {
+	return rdb.Set(c, getRedisKey(KeyCommunityScoreDone), 1, 0).Err()
+}
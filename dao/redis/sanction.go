@@ -0,0 +1,93 @@
+package redis
+
+import (
+	"bluebell/models"
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// SanctionPermanent 永久处罚在有序集合中的到期时间（9999-12-31）
+const SanctionPermanent int64 = 253402300799
+
+// sanctionKey 处罚类型对应的有序集合
+func sanctionKey(typ string) string {
+	if typ == models.SanctionShadowBan {
+		return getRedisKey(KeyUserShadowBanned)
+	}
+	return getRedisKey(KeyUserSuspended)
+}
+
+// AddUserSanction 记录用户的处罚及到期时间
+func AddUserSanction(c context.Context, typ string, userID, expire int64) error {
+	return rdb.ZAdd(c, sanctionKey(typ), redis.Z{Score: float64(expire), Member: userID}).Err()
+}
+
+// RemoveUserSanction 删除用户的处罚
+func RemoveUserSanction(c context.Context, typ string, userID int64) error {
+	return rdb.ZRem(c, sanctionKey(typ), userID).Err()
+}
+
+// IsUserSanctioned 用户是否有尚未到期的某类处罚
+func IsUserSanctioned(c context.Context, typ string, userID int64, now time.Time) (bool, error) {
+	expire, err := rdb.ZScore(c, sanctionKey(typ), strconv.FormatInt(userID, 10)).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return int64(expire) > now.Unix(), nil
+}
+
+// GetSanctionedUsers 查询尚未到期的某类处罚的全部用户
+func GetSanctionedUsers(c context.Context, typ string, now time.Time) ([]int64, error) {
+	members, err := rdb.ZRangeByScore(c, sanctionKey(typ), &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(now.Unix(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// ReplaceSanctionedUsers 用数据库中的记录重建某类处罚的有序集合，参数为用户id到到期时间的映射
+func ReplaceSanctionedUsers(c context.Context, typ string, expires map[int64]int64) error {
+	key := sanctionKey(typ)
+	pipe := rdb.TxPipeline()
+	pipe.Del(c, key)
+	if len(expires) > 0 {
+		members := make([]redis.Z, 0, len(expires))
+		for userID, expire := range expires {
+			members = append(members, redis.Z{Score: float64(expire), Member: userID})
+		}
+		pipe.ZAdd(c, key, members...)
+	}
+	_, err := pipe.Exec(c)
+	return err
+}
+
+// SetTokenNotBefore 让用户在该时间之前签发的Token全部失效
+func SetTokenNotBefore(c context.Context, userID int64, t time.Time) error {
+	return rdb.HSet(c, getRedisKey(KeyUserTokenNotBefore), strconv.FormatInt(userID, 10), t.Unix()).Err()
+}
+
+// GetTokenNotBefore 查询用户Token的最早有效签发时间，没有限制时返回0
+func GetTokenNotBefore(c context.Context, userID int64) (int64, error) {
+	t, err := rdb.HGet(c, getRedisKey(KeyUserTokenNotBefore), strconv.FormatInt(userID, 10)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return t, err
+}
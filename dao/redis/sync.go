@@ -0,0 +1,170 @@
+package redis
+
+import (
+	"bluebell/models"
+	"context"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 投票同步
+/*
+	投票脚本把帖子写入 post:dirty，分数为最近一次投票时间，同时把 post:dirty_ver 中帖子的版本号加1。
+	同步任务按分数从小到大分批取出帖子并记下版本号，写入 MySQL 提交成功后才把帖子移出 post:dirty，
+	同步中途失败或进程崩溃时，未确认的帖子仍留在 post:dirty 中，下次同步会从这里继续。
+	投票时间只精确到秒，同一秒内的新投票无法通过时间区分，所以确认时比较版本号。
+*/
+
+// GetDirtyPosts 获取最早待同步的count个帖子及其当前的投票版本号
+// 需要在读取同步数据之前调用，之后的新投票会使版本号变化，确认时不会被移出待同步集合
+func GetDirtyPosts(c context.Context, count int64) ([]*models.DirtyItem, error) {
+	return getDirtyItems(c, getRedisKey(KeyPostDirtyZSet), getRedisKey(KeyPostDirtyVersion), count)
+}
+
+// getDirtyItems 获取待同步zset中最早的count个成员及其版本号，没有版本号的成员版本号为0
+func getDirtyItems(c context.Context, dirtyKey, versionKey string, count int64) ([]*models.DirtyItem, error) {
+	ids, err := rdb.ZRange(c, dirtyKey, 0, count-1).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	vals, err := rdb.HMGet(c, versionKey, ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*models.DirtyItem, len(ids))
+	for i, id := range ids {
+		items[i] = &models.DirtyItem{ID: id}
+		if v, ok := vals[i].(string); ok {
+			items[i].Version, _ = strconv.ParseInt(v, 10, 64)
+		}
+	}
+	return items, nil
+}
+
+// GetPostSyncData 获取帖子需要同步到 MySQL 的热度、点赞数和点踩数
+func GetPostSyncData(c context.Context, postIDs []string) ([]*models.PostSyncData, error) {
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+	ups, downs, err := getVoteCounts(c, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	hots, err := rdb.ZMScore(c, getRedisKey(KeyPostScoreZSet), postIDs...).Result()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]*models.PostSyncData, len(postIDs))
+	for i, id := range postIDs {
+		data[i] = &models.PostSyncData{
+			PostID:   id,
+			Hot:      hots[i],
+			Likes:    ups[i],
+			Dislikes: downs[i],
+		}
+	}
+	return data, nil
+}
+
+// ackScript 确认帖子或评论已同步
+// 同步期间又有新投票的成员版本号会变化，这样的成员保留在待同步集合中等待下次同步
+// KEYS[1] 待同步zset  KEYS[2] 待同步版本号hash  ARGV 依次为id和取出时的版本号
+var ackScript = redis.NewScript(`
+local removed = 0
+for i = 1, #ARGV, 2 do
+	local version = tonumber(redis.call('HGET', KEYS[2], ARGV[i]) or 0)
+	if version == tonumber(ARGV[i + 1]) then
+		removed = removed + redis.call('ZREM', KEYS[1], ARGV[i])
+		redis.call('HDEL', KEYS[2], ARGV[i])
+	end
+end
+return removed
+`)
+
+// AckDirtyPosts 将已同步的帖子移出待同步集合，返回移除的数量
+func AckDirtyPosts(c context.Context, batch []*models.DirtyItem) (int64, error) {
+	return ackDirtyItems(c, getRedisKey(KeyPostDirtyZSet), getRedisKey(KeyPostDirtyVersion), batch)
+}
+
+// ackDirtyItems 版本号未变化的成员移出待同步集合，返回移除的数量
+func ackDirtyItems(c context.Context, dirtyKey, versionKey string, batch []*models.DirtyItem) (int64, error) {
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	args := make([]interface{}, 0, len(batch)*2)
+	for _, item := range batch {
+		args = append(args, item.ID, item.Version)
+	}
+	return ackScript.Run(c, rdb, []string{dirtyKey, versionKey}, args...).Int64()
+}
+
+// AddPendingPostVote 记录写入 MySQL 失败的用户投票，由同步任务按 redis 中的当前投票重放
+func AddPendingPostVote(c context.Context, postID, userID string) error {
+	return rdb.SAdd(c, getRedisKey(KeyPostVotePending), postID+":"+userID).Err()
+}
+
+// PopPendingPostVotes 取出并移除最多count个等待重放的用户投票，返回帖子id和用户id
+// 重放期间的新投票失败时会重新加入集合，不会被这次取出覆盖
+func PopPendingPostVotes(c context.Context, count int64) (postIDs, userIDs []string, err error) {
+	members, err := rdb.SPopN(c, getRedisKey(KeyPostVotePending), count).Result()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, m := range members {
+		postID, userID, ok := strings.Cut(m, ":")
+		if !ok {
+			continue
+		}
+		postIDs, userIDs = append(postIDs, postID), append(userIDs, userID)
+	}
+	return postIDs, userIDs, nil
+}
+
+// GetDirtyStats 获取待同步帖子数量及最早一次未同步投票的时间
+func GetDirtyStats(c context.Context) (pending int64, oldest int64, err error) {
+	key := getRedisKey(KeyPostDirtyZSet)
+	if pending, err = rdb.ZCard(c, key).Result(); err != nil || pending == 0 {
+		return
+	}
+	first, err := rdb.ZRangeWithScores(c, key, 0, 0).Result()
+	if err != nil || len(first) == 0 {
+		return
+	}
+	return pending, int64(first[0].Score), nil
+}
+
+// SaveSyncCheckpoint 记录同步进度
+func SaveSyncCheckpoint(c context.Context, cp *models.VoteSyncCheckpoint) error {
+	return rdb.HSet(c, getRedisKey(KeySyncCheckpointHash), cp).Err()
+}
+
+// GetSyncCheckpoint 读取同步进度
+func GetSyncCheckpoint(c context.Context) (*models.VoteSyncCheckpoint, error) {
+	cp := new(models.VoteSyncCheckpoint)
+	if err := rdb.HGetAll(c, getRedisKey(KeySyncCheckpointHash)).Scan(cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// unlockScript 只释放自己持有的锁
+var unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// AcquireSyncLock 获取同步锁，成功时返回锁的持有标记
+func AcquireSyncLock(c context.Context, ttl time.Duration) (string, bool, error) {
+	token := strconv.FormatInt(time.Now().UnixNano(), 10)
+	ok, err := rdb.SetNX(c, getRedisKey(KeySyncLock), token, ttl).Result()
+	return token, ok, err
+}
+
+// ReleaseSyncLock 释放同步锁
+func ReleaseSyncLock(c context.Context, token string) error {
+	return unlockScript.Run(c, rdb, []string{getRedisKey(KeySyncLock)}, token).Err()
+}
@@ -0,0 +1,92 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GetTrustLevel 查询缓存的用户信任等级，没有缓存时 ok 为 false
+func GetTrustLevel(c context.Context, userID int64) (level int, ok bool, err error) {
+	level, err = rdb.Get(c, getRedisKey(KeyTrustLevelPrefix+strconv.FormatInt(userID, 10))).Int()
+	if errors.Is(err, redis.Nil) {
+		return 0, false, nil
+	}
+	return level, err == nil, err
+}
+
+// SetTrustLevel 缓存用户信任等级
+func SetTrustLevel(c context.Context, userID int64, level int, ttl time.Duration) error {
+	return rdb.Set(c, getRedisKey(KeyTrustLevelPrefix+strconv.FormatInt(userID, 10)), level, ttl).Err()
+}
+
+// ClearTrustLevel 删除缓存的用户信任等级，下次使用时重新计算
+func ClearTrustLevel(c context.Context, userID int64) error {
+	return rdb.Del(c, getRedisKey(KeyTrustLevelPrefix+strconv.FormatInt(userID, 10))).Err()
+}
+
+// incrWindowScript 计数加 n，key 没有过期时间（第一次计数）时设置，时间窗口从第一次计数开始
+// 不使用 EXPIRE NX，兼容 Redis 7 以下的版本
+// KEYS[1] 计数的key  ARGV[1] 增加的数量  ARGV[2] 窗口长度（毫秒）
+var incrWindowScript = redis.NewScript(`
+local count = redis.call('INCRBY', KEYS[1], ARGV[1])
+if redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return count
+`)
+
+// incrWindow 计数加 n，时间窗口从第一次计数开始，返回窗口内的总数
+func incrWindow(c context.Context, key string, n int64, window time.Duration) (int64, error) {
+	return incrWindowScript.Run(c, rdb, []string{key}, n, window.Milliseconds()).Int64()
+}
+
+// decrWindowScript 计数减 n，key 已过期时不再创建，保留原来的时间窗口
+// KEYS[1] 计数的key  ARGV[1] 减少的数量
+var decrWindowScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+return redis.call('DECRBY', KEYS[1], ARGV[1])
+`)
+
+// decrWindow 退还 n 次计数，用于计数后操作失败的情况
+func decrWindow(c context.Context, key string, n int64) error {
+	return decrWindowScript.Run(c, rdb, []string{key}, n).Err()
+}
+
+// IncrQuota 记录用户的一次操作，返回当前时间窗口内该操作的总数
+func IncrQuota(c context.Context, action string, userID, n int64, window time.Duration) (int64, error) {
+	return incrWindow(c, getRedisKey(KeyQuotaPrefix+action+":"+strconv.FormatInt(userID, 10)), n, window)
+}
+
+// MarkContentSeen 记录内容出现了一次，返回时间窗口内相同内容出现的次数
+func MarkContentSeen(c context.Context, hash string, window time.Duration) (int64, error) {
+	return incrWindow(c, getRedisKey(KeySpamContentPrefix+hash), 1, window)
+}
+
+// MarkLinkSeen 记录链接出现了一次，返回时间窗口内相同链接出现的次数
+func MarkLinkSeen(c context.Context, hash string, window time.Duration) (int64, error) {
+	return incrWindow(c, getRedisKey(KeySpamLinkPrefix+hash), 1, window)
+}
+
+// SetEmailCode 保存邮箱验证码及对应的邮箱
+func SetEmailCode(c context.Context, userID int64, email, code string, ttl time.Duration) error {
+	return rdb.Set(c, getRedisKey(KeyEmailCodePrefix+strconv.FormatInt(userID, 10)), code+":"+email, ttl).Err()
+}
+
+// TakeEmailCode 取出并删除邮箱验证码，没有时返回空字符串
+func TakeEmailCode(c context.Context, userID int64) (code, email string, err error) {
+	val, err := rdb.GetDel(c, getRedisKey(KeyEmailCodePrefix+strconv.FormatInt(userID, 10))).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	code, email, _ = strings.Cut(val, ":")
+	return code, email, nil
+}
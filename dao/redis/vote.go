@@ -3,12 +3,9 @@ package redis
 import (
 	"bluebell/models"
 	"context"
-	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
-	"go.uber.org/zap"
 	"strconv"
-	"time"
 )
 
 //const (
@@ -19,46 +16,122 @@ import (
 //var ErrVoteTimeExpire = errors.New("投票时间已过")
 //var ErrVoteRepeat = errors.New("不允许重复投票")
 
-// VoteForPost 用户为帖子投票，更新用户投票redis
-func VoteForPost(c *gin.Context, userID, postID string, v float64) error {
-	// 更新当前用户对当前帖子的投票
-	if v == 0 {
-		rdb.ZRem(c, getRedisKey(KeyPostVotedZSetPreix+postID), userID)
-		return nil
-	} else {
-		// 查询当前用户对当前帖子之前的投票纪录
-		pipline := rdb.TxPipeline()
-		ov, err := rdb.ZScore(c, getRedisKey(KeyPostVotedZSetPreix+postID), userID).Result()
-		if err == redis.Nil {
-			ov = 0
-		} else if err != nil {
-			return err
-		}
-		// 不允许重复投票
-		if ov == v {
-			return nil
-		}
-		// 更新投票数
-		pipline.ZAdd(c, getRedisKey(KeyPostVotedZSetPreix+postID), redis.Z{
-			Score:  v,
-			Member: userID,
-		})
-
-		_, err = pipline.Exec(c)
-		return err
+// voteScript 原子地完成一次投票
+// KEYS[1] 帖子投票zset  KEYS[2] 帖子票数hash  KEYS[3] 热度zset  KEYS[4] 争议度zset
+// KEYS[5] best zset  KEYS[6] 重力衰减zset  KEYS[7] 待同步zset  KEYS[8] 社区热度zset
+// KEYS[9] 待同步版本号hash  KEYS[10...] 帖子所处的top时间窗口zset
+// ARGV[1] 用户id  ARGV[2] 投票方向  ARGV[3] 帖子id  ARGV[4] 发帖时间  ARGV[5] 当前时间
+// ARGV[6] hot_epoch  ARGV[7] hot_decay  ARGV[8] wilson_z  ARGV[9] gravity  ARGV[10] gravity_offset_hours
+// 返回 {是否改变, 赞成票数, 反对票数}
+var voteScript = redis.NewScript(`
+local v = tonumber(ARGV[2])
+local old = tonumber(redis.call('ZSCORE', KEYS[1], ARGV[1]) or 0)
+
+-- 旧帖子没有票数hash，先从投票zset统计一次
+if redis.call('EXISTS', KEYS[2]) == 0 then
+	redis.call('HSET', KEYS[2], 'up', redis.call('ZCOUNT', KEYS[1], 1, 1), 'down', redis.call('ZCOUNT', KEYS[1], -1, -1))
+end
+
+-- 不允许重复投票
+if old == v then
+	return {0, tonumber(redis.call('HGET', KEYS[2], 'up')), tonumber(redis.call('HGET', KEYS[2], 'down'))}
+end
+
+-- 更新当前用户对当前帖子的投票及票数
+if v == 0 then
+	redis.call('ZREM', KEYS[1], ARGV[1])
+else
+	redis.call('ZADD', KEYS[1], v, ARGV[1])
+end
+if old == 1 then
+	redis.call('HINCRBY', KEYS[2], 'up', -1)
+elseif old == -1 then
+	redis.call('HINCRBY', KEYS[2], 'down', -1)
+end
+if v == 1 then
+	redis.call('HINCRBY', KEYS[2], 'up', 1)
+elseif v == -1 then
+	redis.call('HINCRBY', KEYS[2], 'down', 1)
+end
+
+local ups = tonumber(redis.call('HGET', KEYS[2], 'up'))
+local downs = tonumber(redis.call('HGET', KEYS[2], 'down'))
+local ctime = tonumber(ARGV[4])
+local now = tonumber(ARGV[5])
+
+-- Reddit 热度
+local hot = math.log10(math.max(ups - downs, 1)) + (ctime - tonumber(ARGV[6])) / tonumber(ARGV[7])
+
+-- 争议度
+local controversial = 0
+if ups > 0 and downs > 0 then
+	local balance = downs / ups
+	if ups < downs then
+		balance = ups / downs
+	end
+	controversial = math.pow(ups + downs, balance)
+end
+
+-- Wilson 置信区间下界
+local best = 0
+local n = ups + downs
+if n > 0 then
+	local z = tonumber(ARGV[8])
+	local p = ups / n
+	best = (p + z * z / (2 * n) - z * math.sqrt((p * (1 - p) + z * z / (4 * n)) / n)) / (1 + z * z / n)
+end
+
+-- Hacker News 重力衰减
+local age = math.max(now - ctime, 0) / 3600
+local gravity = (ups - downs) / math.pow(age + tonumber(ARGV[10]), tonumber(ARGV[9]))
+
+redis.call('ZADD', KEYS[3], hot, ARGV[3])
+redis.call('ZADD', KEYS[4], controversial, ARGV[3])
+redis.call('ZADD', KEYS[5], best, ARGV[3])
+redis.call('ZADD', KEYS[6], gravity, ARGV[3])
+redis.call('ZADD', KEYS[8], hot, ARGV[3])
+for i = 10, #KEYS do
+	redis.call('ZADD', KEYS[i], ups - downs, ARGV[3])
+end
+
+-- 标记帖子待同步到 MySQL，分数为最近一次投票时间，版本号用于同步时判断期间是否有新投票
+redis.call('ZADD', KEYS[7], now, ARGV[3])
+redis.call('HINCRBY', KEYS[9], ARGV[3], 1)
+return {1, ups, downs}
+`)
+
+// VoteForPost 用户为帖子投票，原子地更新用户投票、票数及各排序分数，并标记帖子待同步
+// 返回本次投票是否改变了用户对该帖子的投票，以及投票后的赞成票数和反对票数
+func VoteForPost(c context.Context, userID, postID, communityID string, v float64, createTime int64, rp *models.RankParams) (changed bool, ups, downs int64, err error) {
+	keys := []string{
+		getRedisKey(KeyPostVotedZSetPreix + postID),
+		getRedisKey(KeyPostVoteCountPrefix + postID),
+		getRedisKey(KeyPostScoreZSet),
+		getRedisKey(KeyPostControversialZSet),
+		getRedisKey(KeyPostBestZSet),
+		getRedisKey(KeyPostGravityZSet),
+		getRedisKey(KeyPostDirtyZSet),
+		getRedisKey(KeyCommunityScoreZSetPrefix + communityID),
+		getRedisKey(KeyPostDirtyVersion),
 	}
+	for _, window := range rp.TopWindows {
+		keys = append(keys, getRedisKey(KeyPostTopZSetPrefix+window))
+	}
+	res, err := voteScript.Run(c, rdb, keys, userID, v, postID, createTime, rp.Now,
+		rp.HotEpoch, rp.HotDecay, rp.WilsonZ, rp.Gravity, rp.GravityOffset).Int64Slice()
+	if err != nil {
+		return false, 0, 0, err
+	}
+	return res[0] == 1, res[1], res[2], nil
 }
 
-// 获取赞同投票数
-func GetPostVoteByID(c *gin.Context, postid string) (int64, error) {
-	key := getRedisKey(KeyPostVotedZSetPreix + postid)
-	return rdb.ZCount(c, key, "1", "1").Result()
-}
-
-// 获取反对投票数
-func GetPostVoteAgainstByID(c *gin.Context, postid string) (int64, error) {
-	key := getRedisKey(KeyPostVotedZSetPreix + postid)
-	return rdb.ZCount(c, key, "-1", "-1").Result()
+// postIDsOf 得到帖子列表对应的id字符串
+func postIDsOf(ps []*models.Post) []string {
+	ids := make([]string, len(ps))
+	for i, p := range ps {
+		ids[i] = strconv.FormatInt(p.ID, 10)
+	}
+	return ids
 }
 
 // GetPostVoteData 从redis获取全部帖子的投赞成票数量
@@ -66,32 +139,8 @@ func GetPostVoteData(c *gin.Context, ps []*models.Post) ([]int64, error) {
 	if len(ps) == 0 {
 		return nil, nil
 	}
-	// 使用pipeline 减少 Redis 请求的 RTT
-	pipe := rdb.Pipeline()
-	cmds := make([]*redis.IntCmd, len(ps))
-
-	for i, p := range ps {
-		id := strconv.FormatInt(p.ID, 10)
-		key := getRedisKey(KeyPostVotedZSetPreix + id)
-		cmds[i] = pipe.ZCount(c, key, "1", "1")
-	}
-
-	_, err := pipe.Exec(c)
-	if err != nil {
-		return nil, err
-	}
-
-	// 统计每个帖子的票数
-	data := make([]int64, len(ps))
-	for i, cmd := range cmds {
-		vote, err := cmd.Result()
-		if err != nil {
-			return nil, err
-		}
-		data[i] = vote
-	}
-
-	return data, nil
+	ups, _, err := getVoteCounts(c, postIDsOf(ps))
+	return ups, err
 }
 
 // GetPostVoteAgainstData 从redis获取全部帖子的投反对票数量
@@ -99,156 +148,34 @@ func GetPostVoteAgainstData(c *gin.Context, ps []*models.Post) ([]int64, error)
 	if len(ps) == 0 {
 		return nil, nil
 	}
+	_, downs, err := getVoteCounts(c, postIDsOf(ps))
+	return downs, err
+}
+
+// GetUserVotes 获取用户对一批帖子的投票方向，没有投票时为0
+func GetUserVotes(c context.Context, userID string, postIDs []string) ([]int8, error) {
+	votes := make([]int8, len(postIDs))
+	if len(postIDs) == 0 {
+		return votes, nil
+	}
 	// 使用pipeline 减少 Redis 请求的 RTT
 	pipe := rdb.Pipeline()
-	cmds := make([]*redis.IntCmd, len(ps))
-
-	for i, p := range ps {
-		id := strconv.FormatInt(p.ID, 10)
-		key := getRedisKey(KeyPostVotedZSetPreix + id)
-		cmds[i] = pipe.ZCount(c, key, "-1", "-1")
+	cmds := make([]*redis.FloatCmd, len(postIDs))
+	for i, id := range postIDs {
+		cmds[i] = pipe.ZScore(c, getRedisKey(KeyPostVotedZSetPreix+id), userID)
 	}
-
-	_, err := pipe.Exec(c)
-	if err != nil {
+	if _, err := pipe.Exec(c); err != nil && err != redis.Nil {
 		return nil, err
 	}
-
-	// 统计每个帖子的票数
-	data := make([]int64, len(ps))
 	for i, cmd := range cmds {
-		vote, err := cmd.Result()
-		if err != nil {
-			return nil, err
-		}
-		data[i] = vote
-	}
-
-	return data, nil
-}
-
-// UpdateScore 更新帖子得分
-func UpdateScore(c *gin.Context, postid string, score float64) error {
-	return rdb.ZAdd(c, getRedisKey(KeyPostScoreZSet), redis.Z{
-		Score: score, Member: postid}).Err()
-}
-
-// UpdateVoteTime 更新帖子投票的时间
-func UpdateVoteTime(c *gin.Context, postid string, time float64) error {
-	return rdb.ZAdd(c, getRedisKey(KeyPostUpdateTimeZSet), redis.Z{
-		Score: time, Member: postid}).Err()
-}
-
-// 获取上次同步的时间戳
-func GetLastSyncTime(c context.Context, syncKey string) (string, error) {
-	return rdb.Get(c, syncKey).Result()
-}
-
-// 设置上次同步的时间戳
-func SetLastSyncTime(c context.Context, syncKey string, lastSyncTime int64) error {
-	return rdb.Set(c, syncKey, strconv.FormatInt(lastSyncTime, 10), 0).Err()
-}
-
-// 获取最近 10 分钟点赞更新过的帖子
-func GetTimeAndScore(c context.Context, lastSyncTime, currentTime int64) ([]redis.Z, error) {
-	postUpdateScores, err := rdb.ZRangeByScoreWithScores(c, getRedisKey(KeyPostUpdateTimeZSet), &redis.ZRangeBy{
-		Min:    fmt.Sprintf("%f", float64(lastSyncTime)),
-		Max:    fmt.Sprintf("%f", float64(currentTime)),
-		Offset: 0,
-		Count:  10000, // 限制每次最多同步 10000 条数据，防止负载过高
-	}).Result()
-	return postUpdateScores, err
-}
-
-// Redis 过期策略：定期清理 30 天前的热度数据
-func CleanOldRedisData() {
-	c := context.Background()
-	thresholdTime := time.Now().Unix() - 30*24*3600 // 30 天前的时间戳
-	_, err := rdb.ZRemRangeByScore(c, getRedisKey(KeyPostUpdateTimeZSet), "-inf", strconv.FormatInt(thresholdTime, 10)).Result()
-	if err != nil {
-		zap.L().Error("清理 Redis 过期数据失败", zap.Error(err))
-	} else {
-		zap.L().Error("清理 Redis 过期数据完成")
-	}
-}
-
-// 得到传入的各个帖子的点赞数
-func GetLikesByIds(c context.Context, postUpdateScores []redis.Z) []redis.Z {
-	result := []redis.Z{}
-
-	for _, item := range postUpdateScores {
-		post_id, ok := item.Member.(string)
-		if !ok {
-			zap.L().Warn("更新不完整", zap.Any("item", item))
-			continue
-		}
-		key := getRedisKey(KeyPostVotedZSetPreix + post_id)
-		// 统计 score=1 的总数量
-		countOne, err := rdb.ZCount(c, key, "1", "1").Result()
-		if err != nil {
-			zap.L().Warn("更新不完整", zap.Any("item", item))
-			continue
-		}
-		// 将该 ID 加入结果，score 设为 score=1 的总数量
-		result = append(result, redis.Z{
-			Member: post_id,
-			Score:  float64(countOne),
-		})
-	}
-
-	return result
-}
-
-// 得到传入的各个帖子的热度
-func GetHotsByIds(c context.Context, postUpdateScores []redis.Z) []redis.Z {
-	result := []redis.Z{}
-
-	for _, item := range postUpdateScores {
-		post_id, ok := item.Member.(string)
-		if !ok {
-			zap.L().Warn("更新不完整", zap.Any("item", item))
-			continue
-		}
-		key := getRedisKey(KeyPostScoreZSet)
-		// 统计post_id的热度
-		hot, err := rdb.ZScore(c, key, post_id).Result()
-		if err != nil {
-			zap.L().Warn("更新不完整", zap.Any("item", item))
-			continue
-		}
-		// 将该 ID 加入结果，score 设为 hot
-		result = append(result, redis.Z{
-			Member: post_id,
-			Score:  hot,
-		})
-	}
-
-	return result
-}
-
-// 得到传入的各个帖子的点赞数
-func GetDisLikesByIds(c context.Context, postUpdateScores []redis.Z) []redis.Z {
-	result := []redis.Z{}
-
-	for _, item := range postUpdateScores {
-		post_id, ok := item.Member.(string)
-		if !ok {
-			zap.L().Warn("更新不完整", zap.Any("item", item))
+		v, err := cmd.Result()
+		if err == redis.Nil {
 			continue
 		}
-		key := getRedisKey(KeyPostVotedZSetPreix + post_id)
-		// 统计 score=1 的总数量
-		countOne, err := rdb.ZCount(c, key, "-1", "-1").Result()
 		if err != nil {
-			zap.L().Warn("更新不完整", zap.Any("item", item))
-			continue
+			return nil, err
 		}
-		// 将该 ID 加入结果，score 设为 score=1 的总数量
-		result = append(result, redis.Z{
-			Member: post_id,
-			Score:  float64(countOne),
-		})
+		votes[i] = int8(v)
 	}
-
-	return result
+	return votes, nil
 }
@@ -0,0 +1,105 @@
+package redis
+
+import (
+	"bluebell/models"
+	"context"
+	"github.com/redis/go-redis/v9"
+	"strings"
+)
+
+// 帖子票数
+/*
+	投票脚本在更新投票zset的同时维护 post:vote_count:<post_id> 的 up/down 计数，
+	读取票数时直接 HGET，不再对每个帖子的投票zset执行 ZCOUNT。
+	RepairVoteCounters 根据投票zset重建计数，用于修复可能出现的偏差。
+*/
+
+// getVoteCounts 批量获取帖子的赞成票和反对票数量，计数不存在时视为 0
+func getVoteCounts(c context.Context, postIDs []string) (ups, downs []int64, err error) {
+	ups = make([]int64, len(postIDs))
+	downs = make([]int64, len(postIDs))
+	if len(postIDs) == 0 {
+		return
+	}
+	// 使用pipeline 减少 Redis 请求的 RTT
+	pipe := rdb.Pipeline()
+	cmds := make([]*redis.SliceCmd, len(postIDs))
+	for i, id := range postIDs {
+		cmds[i] = pipe.HMGet(c, getRedisKey(KeyPostVoteCountPrefix+id), "up", "down")
+	}
+	if _, err = pipe.Exec(c); err != nil {
+		return nil, nil, err
+	}
+	for i, cmd := range cmds {
+		var counts struct {
+			Up   int64 `redis:"up"`
+			Down int64 `redis:"down"`
+		}
+		if err = cmd.Scan(&counts); err != nil {
+			return nil, nil, err
+		}
+		ups[i], downs[i] = counts.Up, counts.Down
+	}
+	return
+}
+
+// GetPostVoteCount 获取单个帖子的赞成票和反对票数量
+func GetPostVoteCount(c context.Context, postid string) (ups, downs int64, err error) {
+	us, ds, err := getVoteCounts(c, []string{postid})
+	if err != nil {
+		return 0, 0, err
+	}
+	return us[0], ds[0], nil
+}
+
+// repairScript 根据投票zset原子地重建一个帖子的票数
+// KEYS[1] 帖子投票zset  KEYS[2] 帖子票数hash
+// 返回 {原赞成票数, 原反对票数, 赞成票数, 反对票数}
+var repairScript = redis.NewScript(`
+local oldUp = tonumber(redis.call('HGET', KEYS[2], 'up') or 0)
+local oldDown = tonumber(redis.call('HGET', KEYS[2], 'down') or 0)
+local up = redis.call('ZCOUNT', KEYS[1], 1, 1)
+local down = redis.call('ZCOUNT', KEYS[1], -1, -1)
+redis.call('HSET', KEYS[2], 'up', up, 'down', down)
+return {oldUp, oldDown, up, down}
+`)
+
+// scanPostIDs 扫描带有指定前缀的key，返回其中的帖子id
+func scanPostIDs(c context.Context, prefix string, ids map[string]struct{}) error {
+	iter := rdb.Scan(c, 0, getRedisKey(prefix)+"*", 1000).Iterator()
+	for iter.Next(c) {
+		ids[strings.TrimPrefix(iter.Val(), getRedisKey(prefix))] = struct{}{}
+	}
+	return iter.Err()
+}
+
+// RepairVoteCounters 根据投票zset重建全部帖子的票数，返回计数存在偏差的帖子及检查的帖子数量
+func RepairVoteCounters(c context.Context) ([]*models.VoteCountDrift, int, error) {
+	// 有投票zset或票数hash的帖子都需要检查
+	ids := make(map[string]struct{})
+	if err := scanPostIDs(c, KeyPostVotedZSetPreix, ids); err != nil {
+		return nil, 0, err
+	}
+	if err := scanPostIDs(c, KeyPostVoteCountPrefix, ids); err != nil {
+		return nil, 0, err
+	}
+
+	drifts := make([]*models.VoteCountDrift, 0)
+	for id := range ids {
+		keys := []string{getRedisKey(KeyPostVotedZSetPreix + id), getRedisKey(KeyPostVoteCountPrefix + id)}
+		res, err := repairScript.Run(c, rdb, keys).Int64Slice()
+		if err != nil {
+			return drifts, len(ids), err
+		}
+		if res[0] != res[2] || res[1] != res[3] {
+			drifts = append(drifts, &models.VoteCountDrift{
+				PostID:   id,
+				OldUps:   res[0],
+				OldDowns: res[1],
+				Ups:      res[2],
+				Downs:    res[3],
+			})
+		}
+	}
+	return drifts, len(ids), nil
+}
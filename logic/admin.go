@@ -0,0 +1,333 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"bluebell/pkg/snowflake"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"math/big"
+	"time"
+)
+
+// 站点管理员
+/*
+	1. 配置 admin.user_ids 中的用户是固定的管理员，不能通过接口取消
+	2. 其他管理员由管理员在后台设置，保存在 user 表的 role 字段
+*/
+
+var (
+	ErrAdminRoleProtected = errors.New("不能修改该用户的角色")
+	ErrCommunityNameExist = errors.New("社区名称已存在")
+	ErrCommunityNotExist  = errors.New("社区不存在")
+	ErrPasswordRequired   = errors.New("创建用户需要设置密码")
+)
+
+// resetPasswordLength 管理员重置密码时生成的临时密码长度
+const resetPasswordLength = 12
+
+// isConfigAdmin 用户是否是配置 admin.user_ids 指定的管理员
+func isConfigAdmin(userID int64) bool {
+	for _, id := range viper.GetIntSlice("admin.user_ids") {
+		if int64(id) == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSiteAdmin 用户是否是站点管理员：配置指定的管理员，或全站角色为管理员的用户
+func IsSiteAdmin(userID int64) bool {
+	if userID == 0 {
+		return false
+	}
+	if isConfigAdmin(userID) {
+		return true
+	}
+	role, err := mysql.GetUserRole(userID)
+	if err != nil {
+		zap.L().Error("mysql.GetUserRole failed", zap.Int64("userID", userID), zap.Error(err))
+		return false
+	}
+	return role == models.UserRoleAdmin
+}
+
+// canReviewCommunity 用户能否处理社区中的举报：站点管理员或社区版主
+// communityID 为0表示不属于任何社区的对象，只有管理员可以处理
+func canReviewCommunity(communityID, userID int64) (bool, error) {
+	if IsSiteAdmin(userID) {
+		return true, nil
+	}
+	if communityID == 0 {
+		return false, nil
+	}
+	return isModerator(communityID, userID)
+}
+
+// SearchUsers 管理员按用户名、邮箱或用户id分页搜索用户
+func SearchUsers(operatorID int64, p *models.ParamAdminUserList) ([]*models.AdminUser, error) {
+	if !IsSiteAdmin(operatorID) {
+		return nil, ErrNotSiteAdmin
+	}
+	if p.Offset <= 0 {
+		p.Offset = 1
+	}
+	if p.Limit <= 0 {
+		p.Limit = 20
+	}
+	users, err := mysql.SearchUsers(p.Query, p.Offset, p.Limit)
+	if err != nil {
+		zap.L().Error("mysql.SearchUsers failed", zap.Error(err))
+	}
+	return users, err
+}
+
+// GetAdminUserDetail 管理员查看用户的完整记录：基本信息、信任等级、处罚、发布的内容数量、被举报情况和最近的操作
+func GetAdminUserDetail(operatorID, userID int64) (*models.AdminUserDetail, error) {
+	if !IsSiteAdmin(operatorID) {
+		return nil, ErrNotSiteAdmin
+	}
+	user, err := mysql.GetAdminUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	detail := &models.AdminUserDetail{AdminUser: user}
+	if detail.Trust, err = computeUserTrust(userID); err != nil {
+		zap.L().Error("computeUserTrust failed", zap.Int64("userID", userID), zap.Error(err))
+		return nil, err
+	}
+	if detail.Sanctions, err = mysql.GetUserSanctions(userID, time.Now()); err != nil {
+		zap.L().Error("mysql.GetUserSanctions failed", zap.Int64("userID", userID), zap.Error(err))
+		return nil, err
+	}
+	if detail.PostCount, detail.CommentCount, err = mysql.CountUserContent(userID); err != nil {
+		zap.L().Error("mysql.CountUserContent failed", zap.Int64("userID", userID), zap.Error(err))
+		return nil, err
+	}
+	if detail.OpenReports, err = mysql.CountOpenReportsAgainst(userID); err != nil {
+		zap.L().Error("mysql.CountOpenReportsAgainst failed", zap.Int64("userID", userID), zap.Error(err))
+		return nil, err
+	}
+	detail.RecentAudit, err = mysql.GetAuditLogs(&models.ParamAuditLogList{ActorID: userID, Offset: 1, Limit: 20})
+	if err != nil {
+		zap.L().Error("mysql.GetAuditLogs failed", zap.Int64("userID", userID), zap.Error(err))
+		return nil, err
+	}
+	return detail, nil
+}
+
+// SetUserRole 管理员设置用户的全站角色；不能修改自己和配置指定的管理员
+func SetUserRole(operatorID, userID int64, role int8) error {
+	if !IsSiteAdmin(operatorID) {
+		return ErrNotSiteAdmin
+	}
+	if operatorID == userID || isConfigAdmin(userID) {
+		return ErrAdminRoleProtected
+	}
+	if _, err := mysql.GetAdminUser(userID); err != nil {
+		return err
+	}
+	if err := mysql.SetUserRole(userID, role); err != nil {
+		zap.L().Error("mysql.SetUserRole failed", zap.Int64("userID", userID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// randomPassword 生成随机的临时密码，去掉了容易混淆的字符
+func randomPassword() (string, error) {
+	const letters = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	password := make([]byte, resetPasswordLength)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(letters))))
+		if err != nil {
+			return "", err
+		}
+		password[i] = letters[n.Int64()]
+	}
+	return string(password), nil
+}
+
+// resetPassword 修改用户密码，用户已签发的Token全部失效；password 为空时生成随机的临时密码
+func resetPassword(userID int64, password string) (string, error) {
+	if password == "" {
+		var err error
+		if password, err = randomPassword(); err != nil {
+			return "", err
+		}
+	}
+	if err := mysql.ModifyPassword(userID, password); err != nil {
+		zap.L().Error("mysql.ModifyPassword failed", zap.Int64("userID", userID), zap.Error(err))
+		return "", err
+	}
+	if err := redis.SetTokenNotBefore(context.Background(), userID, time.Now()); err != nil {
+		zap.L().Error("redis.SetTokenNotBefore failed", zap.Int64("userID", userID), zap.Error(err))
+	}
+	return password, nil
+}
+
+// ResetUserPassword 管理员把用户密码重置为随机的临时密码，用户已签发的Token全部失效
+// 不能重置管理员的密码，否则管理员可以借临时密码登录其他管理员的账号；管理员的密码只能在命令行中重置
+func ResetUserPassword(operatorID, userID int64) (string, error) {
+	if !IsSiteAdmin(operatorID) {
+		return "", ErrNotSiteAdmin
+	}
+	user, err := mysql.GetAdminUser(userID)
+	if err != nil {
+		return "", err
+	}
+	if user.Role == models.UserRoleAdmin || isConfigAdmin(userID) {
+		return "", ErrAdminRoleProtected
+	}
+	return resetPassword(userID, "")
+}
+
+// ResetPasswordByName 运维在命令行中重置用户密码，password 为空时生成随机的临时密码
+func ResetPasswordByName(username, password string) (string, error) {
+	userID, err := mysql.GetExistUser(username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", mysql.ErrorUserNotExist
+	}
+	if err != nil {
+		return "", err
+	}
+	newPassword, err := resetPassword(userID, password)
+	if err != nil {
+		return "", err
+	}
+	recordCLIAudit(models.AuditPasswordReset, userID, "cli")
+	return newPassword, nil
+}
+
+// CreateAdminUser 运维在命令行中创建管理员；用户名已存在时把该用户设为管理员，不修改密码
+func CreateAdminUser(username, password string) (userID int64, created bool, err error) {
+	userID, err = mysql.GetExistUser(username)
+	switch {
+	case err == nil:
+	case errors.Is(err, sql.ErrNoRows):
+		if password == "" {
+			return 0, false, ErrPasswordRequired
+		}
+		userID = snowflake.GenID()
+		user := &models.User{
+			UserID:    userID,
+			Username:  username,
+			Password:  password,
+			CreatedAt: time.Now(),
+			AvatarURL: DefaultAvatarURL,
+		}
+		if err = mysql.InsertUser(user); err != nil {
+			zap.L().Error("mysql.InsertUser failed", zap.String("username", username), zap.Error(err))
+			return 0, false, err
+		}
+		created = true
+	default:
+		return 0, false, err
+	}
+	if err = mysql.SetUserRole(userID, models.UserRoleAdmin); err != nil {
+		zap.L().Error("mysql.SetUserRole failed", zap.Int64("userID", userID), zap.Error(err))
+		return 0, false, err
+	}
+	detail := fmt.Sprintf("cli role=%d", models.UserRoleAdmin)
+	if created {
+		detail += " created"
+	}
+	recordCLIAudit(models.AuditUserRoleChange, userID, detail)
+	return userID, created, nil
+}
+
+// SetCommunityOwnerByName 运维在命令行中设置社区的创建者，用于补全没有创建者的旧社区或转移社区
+func SetCommunityOwnerByName(communityName, username string) (communityID, userID int64, err error) {
+	comm, err := mysql.GetCommunityByName(communityName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, ErrCommunityNotExist
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	userID, err = mysql.GetExistUser(username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, mysql.ErrorUserNotExist
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	if err = mysql.SetCommunityOwner(comm.ID, userID); err != nil {
+		zap.L().Error("mysql.SetCommunityOwner failed", zap.Int64("communityID", comm.ID), zap.Int64("userID", userID), zap.Error(err))
+		return 0, 0, err
+	}
+	RecordAudit(&models.AuditLog{
+		Action:     models.AuditCommunityUpdate,
+		TargetType: models.AuditTargetCommunity,
+		TargetID:   comm.ID,
+		Success:    true,
+		Detail:     fmt.Sprintf("cli owner=%d previous=%d", userID, comm.OwnerID),
+	})
+	return comm.ID, userID, nil
+}
+
+// recordCLIAudit 记录运维在命令行中对用户的操作，命令行没有登录用户，操作人记为0
+func recordCLIAudit(action string, userID int64, detail string) {
+	RecordAudit(&models.AuditLog{
+		Action:     action,
+		TargetType: models.AuditTargetUser,
+		TargetID:   userID,
+		Success:    true,
+		Detail:     detail,
+	})
+}
+
+// AdminDeletePost 管理员移除任意社区中的帖子
+func AdminDeletePost(c context.Context, operatorID, postID int64, reason string) error {
+	if !IsSiteAdmin(operatorID) {
+		return ErrNotSiteAdmin
+	}
+	post, err := getPost(postID)
+	if err != nil {
+		return err
+	}
+	return removePost(c, post, operatorID, reason)
+}
+
+// AdminDeleteComment 管理员移除任意评论及其全部回复
+func AdminDeleteComment(operatorID, commentID int64, reason string) error {
+	if !IsSiteAdmin(operatorID) {
+		return ErrNotSiteAdmin
+	}
+	return removeReportedComment(commentID, operatorID, reason)
+}
+
+// AdminUpdateCommunity 管理员修改任意社区的名称、简介和设置，只修改传入的字段
+func AdminUpdateCommunity(operatorID, communityID int64, p *models.ParamAdminUpdateCommunity) error {
+	if !IsSiteAdmin(operatorID) {
+		return ErrNotSiteAdmin
+	}
+	if _, err := mysql.GetCommunityById(communityID); err != nil {
+		zap.L().Error("mysql.GetCommunityById failed", zap.Int64("communityID", communityID), zap.Error(err))
+		return err
+	}
+	if p.Name != nil {
+		if _, err := filterSensitive(SensitiveFieldCommunity, p.Name); err != nil {
+			return err
+		}
+		existing, err := mysql.GetCommunityByName(*p.Name)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			zap.L().Error("mysql.GetCommunityByName failed", zap.Error(err))
+			return err
+		}
+		if err == nil && existing.ID != communityID {
+			return ErrCommunityNameExist
+		}
+		if err := mysql.UpdateCommunityName(communityID, *p.Name); err != nil {
+			zap.L().Error("mysql.UpdateCommunityName failed", zap.Int64("communityID", communityID), zap.Error(err))
+			return err
+		}
+	}
+	return updateCommunity(communityID, operatorID, &p.ParamUpdateCommunity)
+}
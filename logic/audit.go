@@ -0,0 +1,42 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/models"
+	"go.uber.org/zap"
+)
+
+// RecordAudit 追加一条审计日志；写入数据库失败时完整记录到日志文件，避免丢失
+func RecordAudit(l *models.AuditLog) {
+	if err := mysql.AddAuditLog(l); err != nil {
+		zap.L().Error("mysql.AddAuditLog failed",
+			zap.Int64("actorID", l.ActorID),
+			zap.String("action", l.Action),
+			zap.String("targetType", l.TargetType),
+			zap.Int64("targetID", l.TargetID),
+			zap.Bool("success", l.Success),
+			zap.Int64("code", l.Code),
+			zap.String("detail", l.Detail),
+			zap.String("ip", l.IP),
+			zap.String("userAgent", l.UserAgent),
+			zap.Error(err))
+	}
+}
+
+// GetAuditLogs 管理员分页查询审计日志
+func GetAuditLogs(userID int64, p *models.ParamAuditLogList) ([]*models.AuditLog, error) {
+	if !IsSiteAdmin(userID) {
+		return nil, ErrNotSiteAdmin
+	}
+	if p.Offset <= 0 {
+		p.Offset = 1
+	}
+	if p.Limit <= 0 {
+		p.Limit = 20
+	}
+	logs, err := mysql.GetAuditLogs(p)
+	if err != nil {
+		zap.L().Error("mysql.GetAuditLogs failed", zap.Error(err))
+	}
+	return logs, err
+}
@@ -2,27 +2,89 @@ package logic
 
 import (
 	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
 	"bluebell/models"
 	"bluebell/pkg/snowflake"
+	"context"
+	"errors"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"sort"
+	"strconv"
+	"time"
 )
 
 // CreateComment 创建评论逻辑
 func CreateComment(postID, parentID, userID int64, content string) (int64, error) {
+	// 被移除或隐藏的帖子不能评论，锁定的帖子只有版主可以评论，
+	// 受限和私密社区只有成员可以评论，被禁言的用户不能在社区中评论
+	post, err := getPost(postID)
+	if err != nil {
+		return 0, err
+	}
+	if postUnavailable(post) {
+		return 0, ErrPostRemoved
+	}
+	if post.Locked {
+		mod, err := isModerator(post.CommunityID, userID)
+		if err != nil {
+			return 0, err
+		}
+		if !mod {
+			return 0, ErrPostLocked
+		}
+	}
+	if _, err := checkCommunityWrite(post.CommunityID, userID); err != nil {
+		return 0, err
+	}
+
+	reviewWords, err := filterSensitive(SensitiveFieldComment, &content)
+	if err != nil {
+		return 0, err
+	}
+	// 检查评论配额、重复内容和重复链接
+	spamReason, err := checkContentSpam(context.Background(), userID, quotaActionComment, content)
+	if err != nil {
+		return 0, err
+	}
+	reason, detail := reviewReason(reviewWords, spamReason)
+
 	// 生成评论id
 	commentID := snowflake.GenID()
 
-	// 调用 DAO 层，保存评论到数据库
-	commentID, err := mysql.CreateComment(commentID, postID, parentID, userID, content)
-	if err != nil {
+	// 调用 DAO 层，保存评论到数据库，命中敏感词或疑似垃圾内容的评论直接以隐藏状态保存
+	status := models.CommentStatusNormal
+	if reason != "" {
+		status = models.CommentStatusHidden
+	}
+	if _, err := mysql.CreateComment(commentID, postID, parentID, userID, content, status); err != nil {
 		zap.L().Error("mysql.CreateComment failed", zap.Error(err))
 		return 0, err
 	}
 
+	if reason != "" {
+		// 等待版主审核的评论不通知也不推送
+		holdForReview(&models.ReportCase{
+			TargetType:   models.ReportTargetComment,
+			TargetID:     commentID,
+			CommunityID:  post.CommunityID,
+			TargetUserID: userID,
+			Snapshot:     content,
+		}, reason, detail)
+	} else if isShadowBanned(context.Background(), userID) {
+		// 被影子封禁的用户的评论只有自己能看到，不通知也不推送
+		zap.L().Debug("影子封禁用户发表评论", zap.Int64("userID", userID), zap.Int64("commentID", commentID))
+	} else {
+		// 保存评论中的@提及，失败不影响评论
+		if err := saveMentions(models.MentionSourceComment, commentID, postID, userID, content); err != nil {
+			zap.L().Error("saveMentions failed", zap.Int64("commentID", commentID), zap.Error(err))
+		}
+		notifyReply(commentID, postID, parentID, userID)
+		publishNewComment(commentID)
+	}
+
 	// 更新帖子用户行为信息
-	var behavior *models.UserPostBehavior
-	behavior, err = mysql.CheckBehavior(userID, postID)
+	behavior, err := mysql.CheckBehavior(userID, postID)
 	if err == gorm.ErrRecordNotFound {
 		// 没有找到记录, 创建记录
 		zap.L().Info("不存在该用户-帖子行为", zap.Int64("postID", postID), zap.Int64("userID", userID))
@@ -30,7 +92,7 @@ func CreateComment(postID, parentID, userID int64, content string) (int64, error
 			zap.L().Error("mysql.CreateBehavior failed", zap.Error(err))
 			return 0, err
 		}
-		return 0, nil // 返回 nil 表示没有记录
+		return commentID, nil
 
 	} else if err != nil {
 		zap.L().Error("mysql.CheckBehavior failed", zap.Error(err))
@@ -49,39 +111,311 @@ func CreateComment(postID, parentID, userID int64, content string) (int64, error
 }
 
 // GetCommentByPostID 查看某个帖子的顶级评论
-func GetCommentByPostID(postID int64) ([]*models.Comment, error) {
+func GetCommentByPostID(c context.Context, userID, postID int64, sortBy string) ([]*models.Comment, error) {
+	if err := checkPostRead(postID, userID); err != nil {
+		return nil, err
+	}
 	comments, err := mysql.GetCommentByPostID(postID)
 	if err != nil {
 		zap.L().Error("mysql.getCommentByPostID failed", zap.Error(err))
 		return nil, err
 	}
+	comments = filterShadowBannedComments(c, userID, comments)
+	if err := sortComments(c, comments, sortBy); err != nil {
+		return nil, err
+	}
+	maskDeletedComments(comments)
+	fillCommentMentions(comments)
 	return comments, nil
 }
 
 // GetChildComments 获取指定父评论下的所有子评论
-func GetChildComments(parentID int64) ([]*models.Comment, error) {
-	if comments, err := mysql.GetChildCommentsByParentID(parentID); err != nil {
+func GetChildComments(c context.Context, userID, parentID int64, sortBy string) ([]*models.Comment, error) {
+	parent, err := mysql.GetCommentByID(parentID)
+	if err != nil {
+		zap.L().Error("mysql.GetCommentByID failed", zap.Int64("commentID", parentID), zap.Error(err))
+		return nil, err
+	}
+	if err := checkPostRead(parent.PostID, userID); err != nil {
+		return nil, err
+	}
+	if parent.UserID != userID && isShadowBanned(c, parent.UserID) {
+		return nil, mysql.ErrorCommentNotExist
+	}
+	comments, err := mysql.GetChildCommentsByParentID(parentID)
+	if err != nil {
 		zap.L().Error("mysql.GetChildCommentsByParentID failed", zap.Error(err))
 		return nil, err
+	}
+	comments = filterShadowBannedComments(c, userID, comments)
+	if err := sortComments(c, comments, sortBy); err != nil {
+		return nil, err
+	}
+	maskDeletedComments(comments)
+	fillCommentMentions(comments)
+	return comments, nil
+}
+
+// sortComments 使用 redis 中最新的票数对评论排序
+func sortComments(c context.Context, comments []*models.Comment, sortBy string) error {
+	if err := redis.FillCommentVoteCounts(c, comments); err != nil {
+		zap.L().Error("redis.FillCommentVoteCounts failed", zap.Error(err))
+		return err
+	}
+	sortCommentGroup(comments, sortBy)
+	return nil
+}
+
+// sortCommentGroup 对同一父评论下的评论排序，置顶评论总在最前面，多个置顶评论按置顶时间倒序
+// MySQL 已按发表时间倒序返回，使用稳定排序使分数相同的评论仍按时间倒序
+func sortCommentGroup(comments []*models.Comment, sortBy string) {
+	var score func(*models.Comment) float64
+	switch sortBy {
+	case models.CommentSortBest:
+		score = func(cm *models.Comment) float64 { return computeWilsonScore(int64(cm.Likes), int64(cm.Dislikes)) }
+	case models.CommentSortTop:
+		score = func(cm *models.Comment) float64 { return float64(cm.Likes - cm.Dislikes) }
+	case models.CommentSortControversial:
+		score = func(cm *models.Comment) float64 {
+			return computeControversialScore(int64(cm.Likes), int64(cm.Dislikes))
+		}
+	default:
+		score = func(*models.Comment) float64 { return 0 }
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		a, b := comments[i], comments[j]
+		if a.IsTop != b.IsTop {
+			return a.IsTop > b.IsTop
+		}
+		if a.IsTop == 1 && a.TopTime != nil && b.TopTime != nil && !a.TopTime.Equal(*b.TopTime) {
+			return a.TopTime.After(*b.TopTime)
+		}
+		return score(a) > score(b)
+	})
+}
+
+// VoteForComment 为评论投票
+func VoteForComment(c context.Context, userID int64, p *models.ParamCommentVote) error {
+	zap.L().Debug("VoteForComment", zap.Int64("userID", userID), zap.Int64("commentID", p.CommentID), zap.Int8("direction", p.Direction))
+	comment, err := mysql.GetCommentByID(p.CommentID)
+	if err != nil {
+		zap.L().Error("mysql.GetCommentByID failed", zap.Int64("commentID", p.CommentID), zap.Error(err))
+		return err
+	}
+	if comment.Status != models.CommentStatusNormal {
+		return ErrCommentDeleted
+	}
+	post, err := getPost(comment.PostID)
+	if err != nil {
+		return err
+	}
+	if err := checkCommunityRead(post.CommunityID, userID); err != nil {
+		return err
+	}
+	if err := checkCommunityArchived(post.CommunityID); err != nil {
+		return err
+	}
+	if err := checkVoteQuota(c, userID); err != nil {
+		return err
+	}
+
+	changed, ups, downs, err := redis.VoteForComment(c, strconv.FormatInt(userID, 10), strconv.FormatInt(p.CommentID, 10),
+		float64(p.Direction), time.Now().Unix(), comment.Likes, comment.Dislikes)
+	if err != nil {
+		zap.L().Error("redis.VoteForComment failed", zap.Error(err))
+		return err
+	}
+	if !changed {
+		zap.L().Debug("重复投票", zap.Int64("userID", userID), zap.Int64("commentID", p.CommentID))
+		return nil
+	}
+
+	// 向正在浏览帖子的用户推送新的票数
+	publishPostEvent(comment.PostID, models.EventCommentVote, &models.VoteCountEvent{
+		PostID:    comment.PostID,
+		CommentID: comment.CommentID,
+		Ups:       ups,
+		Downs:     downs,
+	})
+	if p.Direction == 1 {
+		// 只通知赞成票
+		notifyAggregated(&models.Notification{
+			UserID:    comment.UserID,
+			ActorID:   userID,
+			Type:      models.NotificationCommentVote,
+			PostID:    comment.PostID,
+			CommentID: comment.CommentID,
+		})
+	}
+	return nil
+}
+
+// 评论删除后保留为墓碑，子评论仍挂在原处，评论树的结构不变
+const (
+	commentDeletedContent = "[deleted]"
+	commentRemovedContent = "[removed]"
+	commentHiddenContent  = "[hidden]"
+)
+
+var (
+	ErrNotCommentAuthor    = errors.New("not the comment author")
+	ErrNoCommentPermission = errors.New("no permission to remove the comment")
+	ErrCommentDeleted      = errors.New("the comment has been deleted")
+)
+
+// maskDeletedComments 隐藏已删除评论的内容和作者
+func maskDeletedComments(comments []*models.Comment) {
+	for _, cm := range comments {
+		switch cm.Status {
+		case models.CommentStatusDeleted:
+			cm.Content, cm.UserID = commentDeletedContent, 0
+		case models.CommentStatusRemoved:
+			cm.Content, cm.UserID = commentRemovedContent, 0
+		case models.CommentStatusHidden:
+			cm.Content, cm.UserID = commentHiddenContent, 0
+		}
+	}
+}
+
+// notifyReply 通知帖子作者收到评论，或父评论作者收到回复
+func notifyReply(commentID, postID, parentID, userID int64) {
+	n := &models.Notification{ActorID: userID, PostID: postID, CommentID: commentID}
+	if parentID == 0 {
+		author, err := mysql.GetPostAuthor(postID)
+		if err != nil {
+			zap.L().Error("mysql.GetPostAuthor failed", zap.Int64("postID", postID), zap.Error(err))
+			return
+		}
+		n.UserID, n.Type = author, models.NotificationPostReply
 	} else {
-		return comments, nil
+		parent, err := mysql.GetCommentByID(parentID)
+		if err != nil {
+			zap.L().Error("mysql.GetCommentByID failed", zap.Int64("commentID", parentID), zap.Error(err))
+			return
+		}
+		// 已删除的评论没有作者可以通知
+		if parent.Status != models.CommentStatusNormal {
+			return
+		}
+		n.UserID, n.Type = parent.UserID, models.NotificationCommentReply
 	}
+	notify(n)
 }
 
-// DeleteComment 删除评论
-func DeleteComment(commentID int64) error {
-	// 删除 comment_id 对应的评论
-	if err := mysql.DeleteCommentByID(commentID); err != nil {
-		zap.L().Error("mysql.DeleteCommentByID failed", zap.Error(err))
+// publishNewComment 向正在浏览帖子的用户推送新评论
+func publishNewComment(commentID int64) {
+	comment, err := mysql.GetCommentByID(commentID)
+	if err != nil {
+		zap.L().Error("mysql.GetCommentByID failed", zap.Int64("commentID", commentID), zap.Error(err))
+		return
+	}
+	fillCommentMentions([]*models.Comment{comment})
+	publishPostEvent(comment.PostID, models.EventComment, comment)
+}
+
+// EditComment 作者编辑自己的评论
+func EditComment(userID int64, p *models.ParamEditComment) error {
+	comment, err := mysql.GetCommentByID(p.CommentID)
+	if err != nil {
+		zap.L().Error("mysql.GetCommentByID failed", zap.Int64("commentID", p.CommentID), zap.Error(err))
+		return err
+	}
+	if comment.UserID != userID {
+		return ErrNotCommentAuthor
+	}
+	if comment.Status != models.CommentStatusNormal {
+		return ErrCommentDeleted
+	}
+	post, err := getPost(comment.PostID)
+	if err != nil {
 		return err
 	}
+	if err := checkCommunityArchived(post.CommunityID); err != nil {
+		return err
+	}
+	reviewWords, err := filterSensitive(SensitiveFieldComment, &p.Content)
+	if err != nil {
+		return err
+	}
+	// 编辑后命中敏感词的评论与新内容一起改为隐藏状态，等待版主审核
+	reason, detail := reviewReason(reviewWords, "")
+	status := models.CommentStatusNormal
+	if reason != "" {
+		status = models.CommentStatusHidden
+	}
+	if err := mysql.UpdateCommentContent(p.CommentID, p.Content, status); err != nil {
+		zap.L().Error("mysql.UpdateCommentContent failed", zap.Error(err))
+		return err
+	}
+	if reason != "" {
+		holdForReview(&models.ReportCase{
+			TargetType:   models.ReportTargetComment,
+			TargetID:     p.CommentID,
+			CommunityID:  post.CommunityID,
+			TargetUserID: userID,
+			Snapshot:     p.Content,
+		}, reason, detail)
+	} else if !isShadowBanned(context.Background(), userID) {
+		// 编辑时新增的@提及同样保存并通知，失败不影响编辑
+		if err := saveEditedMentions(models.MentionSourceComment, p.CommentID, comment.PostID, userID, comment.Content, p.Content); err != nil {
+			zap.L().Error("saveEditedMentions failed", zap.Int64("commentID", p.CommentID), zap.Error(err))
+		}
+	}
+	return nil
+}
 
-	// 删除评论该评论下的子评论
-	if err := mysql.DeleteCommentByParentID(commentID); err != nil {
-		zap.L().Error("mysql.DeleteCommentByParentID failed", zap.Error(err))
+// DeleteComment 删除评论
+// 1. 作者删除自己的评论，只删除这一条，其他人的回复保留
+// 2. 帖子作者或社区版主移除帖子下的评论，连同任意层级的回复一起移除
+func DeleteComment(commentID, userID int64, reason string) error {
+	comment, err := mysql.GetCommentByID(commentID)
+	if err != nil {
+		zap.L().Error("mysql.GetCommentByID failed", zap.Int64("commentID", commentID), zap.Error(err))
 		return err
 	}
 
+	if comment.UserID == userID {
+		if comment.Status != models.CommentStatusNormal {
+			return nil
+		}
+		if err := mysql.SetCommentStatus(commentID, models.CommentStatusDeleted); err != nil {
+			zap.L().Error("mysql.SetCommentStatus failed", zap.Error(err))
+			return err
+		}
+		return nil
+	}
+
+	// 帖子作者和社区版主可以移除评论及其全部子评论，版主的操作记录在管理日志中
+	post, err := getPost(comment.PostID)
+	if err != nil {
+		return err
+	}
+	byModerator := false
+	if post.AuthorID != userID {
+		if byModerator, err = isModerator(post.CommunityID, userID); err != nil {
+			return err
+		}
+		if !byModerator {
+			return ErrNoCommentPermission
+		}
+	}
+	removed, err := mysql.RemoveCommentSubtree(comment.PostID, comment.Path)
+	if err != nil {
+		zap.L().Error("mysql.RemoveCommentSubtree failed", zap.Error(err))
+		return err
+	}
+	zap.L().Info("移除评论", zap.Int64("commentID", commentID), zap.Int64("operator", userID), zap.Int64("removed", removed))
+	if byModerator && removed > 0 {
+		logModeration(&models.ModerationLog{
+			CommunityID:  post.CommunityID,
+			ModeratorID:  userID,
+			Action:       models.ModActionRemoveComment,
+			TargetUserID: comment.UserID,
+			PostID:       comment.PostID,
+			CommentID:    commentID,
+			Reason:       reason,
+		})
+	}
 	return nil
 }
 
@@ -93,7 +427,22 @@ func PinComment(commentID, userID int64) error {
 		return err
 	}
 	// 是本人的帖子下的评论，执行置顶操作
-	return mysql.PinCommentByID(commentID)
+	if err := mysql.PinCommentByID(commentID); err != nil {
+		zap.L().Error("mysql.PinCommentByID failed", zap.Error(err))
+		return err
+	}
+	if comment, err := mysql.GetCommentByID(commentID); err != nil {
+		zap.L().Error("mysql.GetCommentByID failed", zap.Int64("commentID", commentID), zap.Error(err))
+	} else if comment.Status == models.CommentStatusNormal {
+		notify(&models.Notification{
+			UserID:    comment.UserID,
+			ActorID:   userID,
+			Type:      models.NotificationCommentPinned,
+			PostID:    comment.PostID,
+			CommentID: commentID,
+		})
+	}
+	return nil
 }
 
 // UnpinComment 取消置顶帖子的处理逻辑
@@ -0,0 +1,46 @@
+package logic
+
+import (
+	"bluebell/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSortCommentGroup(t *testing.T) {
+	t1 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	// MySQL 按发表时间倒序返回，id 越大越新
+	newComments := func() []*models.Comment {
+		return []*models.Comment{
+			{CommentID: 6, Likes: 1, Dislikes: 0},
+			{CommentID: 5, Likes: 10, Dislikes: 10},
+			{CommentID: 4, Likes: 1, Dislikes: 0, IsTop: 1, TopTime: &t1},
+			{CommentID: 3, Likes: 50, Dislikes: 5},
+			{CommentID: 2, Likes: 0, Dislikes: 0, IsTop: 1, TopTime: &t2},
+			{CommentID: 1, Likes: 8, Dislikes: 2},
+		}
+	}
+	tests := []struct {
+		sortBy string
+		want   []int64
+	}{
+		{models.CommentSortNew, []int64{2, 4, 6, 5, 3, 1}},
+		{models.CommentSortTop, []int64{2, 4, 3, 1, 6, 5}},
+		{models.CommentSortBest, []int64{2, 4, 3, 1, 5, 6}},
+		{models.CommentSortControversial, []int64{2, 4, 5, 1, 3, 6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			comments := newComments()
+			sortCommentGroup(comments, tt.sortBy)
+			got := make([]int64, 0, len(comments))
+			for _, cm := range comments {
+				got = append(got, cm.CommentID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortCommentGroup(%s) = %v, want %v", tt.sortBy, got, tt.want)
+			}
+		})
+	}
+}
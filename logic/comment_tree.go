@@ -0,0 +1,132 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+)
+
+// 评论树
+/*
+	评论保存由根评论到自身的物化路径 path，一棵子树的全部评论通过 path 前缀一次查出。
+	每层最多返回 limit 条评论，超出的部分以及超过最大层数的子评论返回继续加载的令牌，
+	令牌记录父评论id和已返回的数量，携带令牌再次请求即可从该位置继续加载。
+*/
+
+var ErrInvalidCursor = errors.New("无效的加载令牌")
+
+// encodeCommentCursor 生成继续加载parentID下第offset条之后子评论的令牌
+func encodeCommentCursor(parentID int64, offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", parentID, offset)))
+}
+
+// decodeCommentCursor 解析继续加载的令牌
+func decodeCommentCursor(cursor string) (parentID int64, offset int, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+	if _, err = fmt.Sscanf(string(b), "%d:%d", &parentID, &offset); err != nil || parentID < 0 || offset < 0 {
+		return 0, 0, ErrInvalidCursor
+	}
+	return parentID, offset, nil
+}
+
+// GetCommentTree 获取帖子的评论树
+func GetCommentTree(c context.Context, userID, postID int64, p *models.ParamCommentTree) (*models.ApiCommentTree, error) {
+	if err := checkPostRead(postID, userID); err != nil {
+		return nil, err
+	}
+	var (
+		parentID int64
+		offset   int
+		path     string
+		depth    int // 返回的第一层评论的层级
+	)
+	if p.Cursor != "" {
+		var err error
+		if parentID, offset, err = decodeCommentCursor(p.Cursor); err != nil {
+			return nil, err
+		}
+	}
+	if parentID != 0 {
+		parent, err := mysql.GetCommentByID(parentID)
+		if err != nil {
+			zap.L().Error("mysql.GetCommentByID failed", zap.Int64("commentID", parentID), zap.Error(err))
+			return nil, err
+		}
+		// 被影子封禁的用户的评论及其回复，其他人看不到
+		if parent.PostID != postID || (parent.UserID != userID && isShadowBanned(c, parent.UserID)) {
+			return nil, mysql.ErrorCommentNotExist
+		}
+		path, depth = parent.Path, parent.Depth+1
+	}
+
+	// 多查一层，用于统计最深一层评论还有多少未加载的子评论
+	comments, err := mysql.GetCommentSubtree(postID, path, depth+p.MaxDepth)
+	if err != nil {
+		zap.L().Error("mysql.GetCommentSubtree failed", zap.Int64("postID", postID), zap.Error(err))
+		return nil, err
+	}
+	if err := redis.FillCommentVoteCounts(c, comments); err != nil {
+		zap.L().Error("redis.FillCommentVoteCounts failed", zap.Error(err))
+		return nil, err
+	}
+
+	// 去掉被影子封禁的用户的评论，其回复没有父节点，也不会出现在树中
+	comments = filterShadowBannedComments(c, userID, comments)
+	maskDeletedComments(comments)
+	fillCommentMentions(comments)
+
+	children := make(map[int64][]*models.Comment)
+	for _, cm := range comments {
+		if cm.CommentID == parentID {
+			continue
+		}
+		var pid int64
+		if cm.ParentID != nil {
+			pid = *cm.ParentID
+		}
+		children[pid] = append(children[pid], cm)
+	}
+	for _, group := range children {
+		sortCommentGroup(group, p.Sort)
+	}
+
+	// build 构建pid下第level层（从0开始）的评论，返回评论节点、未加载的数量和继续加载的令牌
+	var build func(pid int64, level, offset int) ([]*models.ApiCommentNode, int, string)
+	build = func(pid int64, level, offset int) ([]*models.ApiCommentNode, int, string) {
+		group := children[pid]
+		if offset > len(group) {
+			offset = len(group)
+		}
+		end := offset + p.Limit
+		if end > len(group) {
+			end = len(group)
+		}
+		nodes := make([]*models.ApiCommentNode, 0, end-offset)
+		for _, cm := range group[offset:end] {
+			node := &models.ApiCommentNode{Comment: cm, Replies: []*models.ApiCommentNode{}}
+			if level+1 < p.MaxDepth {
+				node.Replies, node.MoreCount, node.More = build(cm.CommentID, level+1, 0)
+			} else if n := len(children[cm.CommentID]); n > 0 {
+				node.MoreCount, node.More = n, encodeCommentCursor(cm.CommentID, 0)
+			}
+			nodes = append(nodes, node)
+		}
+		var more string
+		if end < len(group) {
+			more = encodeCommentCursor(pid, end)
+		}
+		return nodes, len(group) - end, more
+	}
+
+	tree := new(models.ApiCommentTree)
+	tree.Comments, tree.MoreCount, tree.More = build(parentID, 0, offset)
+	return tree, nil
+}
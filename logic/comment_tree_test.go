@@ -0,0 +1,49 @@
+package logic
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestCommentCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		parentID int64
+		offset   int
+	}{
+		{0, 0},
+		{0, 20},
+		{1234567890123, 5},
+	}
+	for _, tt := range tests {
+		cursor := encodeCommentCursor(tt.parentID, tt.offset)
+		parentID, offset, err := decodeCommentCursor(cursor)
+		if err != nil {
+			t.Fatalf("decodeCommentCursor(%q) error: %v", cursor, err)
+		}
+		if parentID != tt.parentID || offset != tt.offset {
+			t.Errorf("decodeCommentCursor(%q) = (%d, %d), want (%d, %d)", cursor, parentID, offset, tt.parentID, tt.offset)
+		}
+	}
+}
+
+func TestDecodeCommentCursorInvalid(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "!!!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte("1:23"))},
+		{"missing offset", encode("12")},
+		{"not numbers", encode("a:b")},
+		{"negative parent", encode("-1:2")},
+		{"negative offset", encode("1:-2")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCommentCursor(tt.cursor); err != ErrInvalidCursor {
+				t.Errorf("decodeCommentCursor(%q) error = %v, want ErrInvalidCursor", tt.cursor, err)
+			}
+		})
+	}
+}
@@ -2,8 +2,13 @@ package logic
 
 import (
 	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
 	"bluebell/models"
+	"context"
+	"database/sql"
+	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"time"
 )
 
 // GetCommunity 获得全部的社区
@@ -15,12 +20,26 @@ func GetCommunity() ([]*models.Community, error) {
 
 // GetCommunityDetail 根据社区id查询社区信息
 func GetCommunityDetail(id int64) (*models.CommunityDetail, error) {
-	return mysql.GetCommunityById(id)
+	detail, err := mysql.GetCommunityById(id)
+	if err != nil {
+		return nil, err
+	}
+	if detail.Settings, err = getCommunitySettings(id); err != nil {
+		return nil, err
+	}
+	return detail, nil
 }
 
 // CreateCommunity 创建社区
 func CreateCommunity(comm *models.CommunityDetail) error {
 	//fmt.Printf("id=%d, name=%s,intr=%s\n", comm.ID, comm.Name, comm.Introduction)
+	// 信任等级足够的用户才能创建社区
+	if err := checkCreateCommunity(context.Background(), comm.OwnerID); err != nil {
+		return err
+	}
+	if _, err := filterSensitive(SensitiveFieldCommunity, &comm.Name, &comm.Introduction); err != nil {
+		return err
+	}
 	if err := mysql.CreateCommunity(comm); err != nil {
 		zap.L().Error("CreateCommunity Failed", zap.Error(err))
 		return err
@@ -30,5 +49,122 @@ func CreateCommunity(comm *models.CommunityDetail) error {
 
 // GetCommunityByNameDetail 根据社区name查询社区信息
 func GetCommunityByNameDetail(username string) (*models.CommunityDetail, error) {
-	return mysql.GetCommunityByName(username)
+	detail, err := mysql.GetCommunityByName(username)
+	if err != nil {
+		return nil, err
+	}
+	if detail.Settings, err = getCommunitySettings(detail.ID); err != nil {
+		return nil, err
+	}
+	return detail, nil
+}
+
+// homeFeedTTL 首页合并结果的缓存时间，翻页期间使用同一份结果
+const homeFeedTTL = time.Minute
+
+// JoinCommunity 用户加入社区，返回是否需要等待版主批准
+// 受限和私密社区需要版主批准，版主邀请过的用户可以直接加入
+func JoinCommunity(c context.Context, communityID, userID int64) (pending bool, err error) {
+	if _, err := mysql.GetCommunityById(communityID); err != nil {
+		if err != sql.ErrNoRows {
+			zap.L().Error("mysql.GetCommunityById failed", zap.Error(err))
+		}
+		return false, err
+	}
+	settings, err := getCommunitySettings(communityID)
+	if err != nil {
+		return false, err
+	}
+	if settings.Visibility != models.CommunityPublic {
+		role, err := mysql.GetCommunityRole(communityID, userID)
+		if err != nil {
+			zap.L().Error("mysql.GetCommunityRole failed", zap.Error(err))
+			return false, err
+		}
+		if role != models.CommunityRoleNone {
+			return false, nil
+		}
+		req, err := mysql.GetJoinRequest(communityID, userID)
+		if err != nil {
+			zap.L().Error("mysql.GetJoinRequest failed", zap.Error(err))
+			return false, err
+		}
+		if req == nil || req.Status != models.JoinRequestApproved {
+			if err := mysql.CreateJoinRequest(communityID, userID); err != nil {
+				zap.L().Error("mysql.CreateJoinRequest failed", zap.Error(err))
+				return false, err
+			}
+			return true, nil
+		}
+	}
+	joined, err := mysql.JoinCommunity(communityID, userID)
+	if err != nil {
+		zap.L().Error("mysql.JoinCommunity failed", zap.Error(err))
+		return false, err
+	}
+	if joined {
+		if err := redis.ClearHomeFeed(c, userID); err != nil {
+			zap.L().Warn("redis.ClearHomeFeed failed", zap.Int64("userID", userID), zap.Error(err))
+		}
+	}
+	return false, nil
+}
+
+// LeaveCommunity 用户退出社区，创建者不能退出
+func LeaveCommunity(c context.Context, communityID, userID int64) error {
+	role, err := mysql.GetCommunityRole(communityID, userID)
+	if err != nil {
+		zap.L().Error("mysql.GetCommunityRole failed", zap.Error(err))
+		return err
+	}
+	if role == models.CommunityRoleOwner {
+		return ErrOwnerCannotLeave
+	}
+	left, err := mysql.LeaveCommunity(communityID, userID)
+	if err != nil {
+		zap.L().Error("mysql.LeaveCommunity failed", zap.Error(err))
+		return err
+	}
+	if left {
+		if err := redis.ClearHomeFeed(c, userID); err != nil {
+			zap.L().Warn("redis.ClearHomeFeed failed", zap.Int64("userID", userID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// GetUserCommunities 获取用户加入的社区
+func GetUserCommunities(userID int64) ([]*models.Community, error) {
+	communities, err := mysql.GetUserCommunities(userID)
+	if err != nil {
+		zap.L().Error("mysql.GetUserCommunities failed", zap.Error(err))
+	}
+	return communities, err
+}
+
+// GetHomeFeed 按热度分页获取用户订阅社区中的帖子
+// 合并各社区的热度zset得到结果，而不是从全站热度中筛选
+func GetHomeFeed(c *gin.Context, userID int64, p *models.ParamPostList) ([]*models.ApiPostDetail, error) {
+	communityIDs, err := mysql.GetUserCommunityIDs(userID)
+	if err != nil {
+		zap.L().Error("mysql.GetUserCommunityIDs failed", zap.Error(err))
+		return nil, err
+	}
+	if len(communityIDs) == 0 {
+		return nil, nil
+	}
+	ids, err := redis.GetHomeFeedIDs(c, userID, communityIDs, p.Offset, p.Limit, homeFeedTTL)
+	if err != nil {
+		zap.L().Error("redis.GetHomeFeedIDs failed", zap.Error(err))
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	ps, err := mysql.GetPostsListByIds(ids)
+	if err != nil {
+		zap.L().Error("mysql.GetPostsListByIds failed", zap.Error(err))
+		return nil, err
+	}
+	return buildPostDetails(c, userID, ps)
 }
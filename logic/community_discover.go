@@ -0,0 +1,251 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"context"
+	"go.uber.org/zap"
+	"math"
+	"time"
+)
+
+// 社区发现
+/*
+	1. 搜索：按名称和简介匹配，名称以关键词开头的排在最前；自动补全只匹配名称前缀
+	2. 热门：用每天的统计数据比较最近 trendingRecentDays 天和此前 trendingBaselineDays 天的日均活跃度，
+	   增长越快越靠前，平滑项避免很小的社区因为几次活动就排到最前
+	3. 可能喜欢：按 user_post_behavior 把用户的行为分数汇总到社区，找出在各社区行为分布最相似的用户，
+	   推荐他们活跃而当前用户还没有加入的社区；没有行为数据时推荐热门社区；
+	   只有在同一社区有行为的用户才可能相似，计算时只加载这些用户的行为，结果按用户缓存一段时间
+	4. 用户不能浏览的私密社区不出现在任何结果中
+*/
+
+const (
+	defaultDiscoverLimit = 10
+	trendingRecentDays   = 2
+	trendingBaselineDays = 7
+	trendingSmoothing    = 5.0 // 日均活跃度的平滑项
+	similarUserCount     = 10  // 推荐社区时参考的相似用户数量
+)
+
+// recommendCacheTTL 相似用户推荐结果的缓存时间
+const recommendCacheTTL = 30 * time.Minute
+
+// filterVisibleCommunities 去掉用户不能浏览的私密社区
+func filterVisibleCommunities(userID int64, communities []*models.Community) ([]*models.Community, error) {
+	if len(communities) == 0 {
+		return communities, nil
+	}
+	hidden, err := hiddenCommunities(userID)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*models.Community, 0, len(communities))
+	for _, comm := range communities {
+		if _, ok := hidden[comm.ID]; !ok {
+			list = append(list, comm)
+		}
+	}
+	return list, nil
+}
+
+// SearchCommunities 按名称和简介分页搜索社区
+func SearchCommunities(userID int64, p *models.ParamCommunitySearch) ([]*models.Community, error) {
+	if p.Offset <= 0 {
+		p.Offset = 1
+	}
+	if p.Limit <= 0 {
+		p.Limit = defaultDiscoverLimit
+	}
+	communities, err := mysql.SearchCommunities(p.Query, p.Offset, p.Limit)
+	if err != nil {
+		zap.L().Error("mysql.SearchCommunities failed", zap.String("q", p.Query), zap.Error(err))
+		return nil, err
+	}
+	return filterVisibleCommunities(userID, communities)
+}
+
+// SuggestCommunities 社区名称自动补全
+func SuggestCommunities(userID int64, p *models.ParamCommunitySuggest) ([]*models.Community, error) {
+	if p.Limit <= 0 {
+		p.Limit = defaultDiscoverLimit
+	}
+	communities, err := mysql.SuggestCommunities(p.Prefix, p.Limit)
+	if err != nil {
+		zap.L().Error("mysql.SuggestCommunities failed", zap.String("prefix", p.Prefix), zap.Error(err))
+		return nil, err
+	}
+	return filterVisibleCommunities(userID, communities)
+}
+
+// trendingCommunityIDs 按活跃度增长速度排序的社区id，不包含用户不能浏览的私密社区
+func trendingCommunityIDs(userID int64) ([]int64, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	recentSince := today.AddDate(0, 0, 1-trendingRecentDays)
+	since := recentSince.AddDate(0, 0, -trendingBaselineDays)
+	activity, err := mysql.GetCommunityActivity(since, recentSince)
+	if err != nil {
+		zap.L().Error("mysql.GetCommunityActivity failed", zap.Error(err))
+		return nil, err
+	}
+	hidden, err := hiddenCommunities(userID)
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[int64]float64, len(activity))
+	for _, a := range activity {
+		if _, ok := hidden[a.CommunityID]; ok || a.Recent == 0 {
+			continue
+		}
+		recent := float64(a.Recent) / trendingRecentDays
+		baseline := float64(a.Baseline) / trendingBaselineDays
+		scores[a.CommunityID] = recent * recent / (baseline + trendingSmoothing)
+	}
+	ids, _ := GetTopK(scores, len(scores))
+	return ids, nil
+}
+
+// GetTrendingCommunities 获取活跃度增长最快的社区
+func GetTrendingCommunities(userID, limit int64) ([]*models.Community, error) {
+	if limit <= 0 {
+		limit = defaultDiscoverLimit
+	}
+	ids, err := trendingCommunityIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(ids)) > limit {
+		ids = ids[:limit]
+	}
+	communities, err := mysql.GetCommunitiesByIDs(ids)
+	if err != nil {
+		zap.L().Error("mysql.GetCommunitiesByIDs failed", zap.Error(err))
+	}
+	return communities, err
+}
+
+// communitySimilarity 两个用户在各社区行为分数向量的余弦相似度
+func communitySimilarity(vec1, vec2 map[int64]float64) float64 {
+	var dot, norm1, norm2 float64
+	for id, r1 := range vec1 {
+		dot += r1 * vec2[id]
+		norm1 += r1 * r1
+	}
+	for _, r2 := range vec2 {
+		norm2 += r2 * r2
+	}
+	if norm1 == 0 || norm2 == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(norm1) * math.Sqrt(norm2))
+}
+
+// similarUserCommunities 相似用户活跃的社区，按推荐分数从高到低排列，结果缓存 recommendCacheTTL
+func similarUserCommunities(c context.Context, userID int64) ([]int64, error) {
+	ids, ok, err := redis.GetRecommendedCommunityIDs(c, userID)
+	if err != nil {
+		zap.L().Error("redis.GetRecommendedCommunityIDs failed", zap.Error(err))
+		return nil, err
+	}
+	if ok {
+		return ids, nil
+	}
+
+	rates, err := mysql.GetSimilarUserCommunityRates(userID)
+	if err != nil {
+		zap.L().Error("mysql.GetSimilarUserCommunityRates failed", zap.Error(err))
+		return nil, err
+	}
+	// 用户-社区行为分数矩阵：userCommunityMatrix[userID][communityID] = rate
+	userCommunityMatrix := make(map[int64]map[int64]float64)
+	for _, r := range rates {
+		if userCommunityMatrix[r.UserID] == nil {
+			userCommunityMatrix[r.UserID] = make(map[int64]float64)
+		}
+		userCommunityMatrix[r.UserID][r.CommunityID] = r.Rate
+	}
+
+	scores := make(map[int64]float64)
+	if mine := userCommunityMatrix[userID]; len(mine) > 0 {
+		similarUsers := make(map[int64]float64, len(userCommunityMatrix))
+		for uid, vec := range userCommunityMatrix {
+			if uid == userID {
+				continue
+			}
+			if sim := communitySimilarity(mine, vec); sim > 0 {
+				similarUsers[uid] = sim
+			}
+		}
+		users, similar := GetTopK(similarUsers, similarUserCount)
+		for i, uid := range users {
+			for communityID, rate := range userCommunityMatrix[uid] {
+				scores[communityID] += rate * similar[i]
+			}
+		}
+	}
+	if err := redis.SaveRecommendedCommunities(c, userID, scores, recommendCacheTTL); err != nil {
+		zap.L().Error("redis.SaveRecommendedCommunities failed", zap.Error(err))
+	}
+	ids, _ = GetTopK(scores, len(scores))
+	return ids, nil
+}
+
+// GetRecommendedCommunities 根据用户的帖子行为推荐可能喜欢的社区，不包含已经加入的社区
+func GetRecommendedCommunities(c context.Context, userID, limit int64) ([]*models.Community, error) {
+	if limit <= 0 {
+		limit = defaultDiscoverLimit
+	}
+	joinedIDs, err := mysql.GetUserCommunityIDs(userID)
+	if err != nil {
+		zap.L().Error("mysql.GetUserCommunityIDs failed", zap.Error(err))
+		return nil, err
+	}
+	excluded, err := hiddenCommunities(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range joinedIDs {
+		excluded[id] = struct{}{}
+	}
+
+	ranked, err := similarUserCommunities(c, userID)
+	if err != nil {
+		return nil, err
+	}
+	// 加入的社区和可见性会变化，读取缓存后再排除
+	ids := make([]int64, 0, limit)
+	for _, id := range ranked {
+		if int64(len(ids)) >= limit {
+			break
+		}
+		if _, ok := excluded[id]; ok {
+			continue
+		}
+		excluded[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	// 没有相似用户或推荐数量不足时用热门社区补齐
+	if int64(len(ids)) < limit {
+		trending, err := trendingCommunityIDs(userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range trending {
+			if int64(len(ids)) >= limit {
+				break
+			}
+			if _, ok := excluded[id]; ok {
+				continue
+			}
+			excluded[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	communities, err := mysql.GetCommunitiesByIDs(ids)
+	if err != nil {
+		zap.L().Error("mysql.GetCommunitiesByIDs failed", zap.Error(err))
+	}
+	return communities, err
+}
@@ -0,0 +1,382 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"context"
+	"database/sql"
+	"errors"
+	"go.uber.org/zap"
+	"strings"
+)
+
+// 社区设置及可见性
+/*
+	1. 公开社区任何人可以浏览和发帖；受限社区任何人可以浏览，只有成员可以发帖和评论；
+	   私密社区只有成员可以浏览，帖子不出现在其他人的帖子列表和推荐中
+	2. 受限和私密社区不能直接加入：用户提交申请，由版主批准或拒绝；版主邀请过的用户可以直接加入
+	3. 社区可以限制允许发布的帖子类型
+	4. 创建者可以归档社区，归档后社区只读：可以浏览，但不能发帖、评论和投票
+*/
+
+var (
+	ErrCommunityPrivate    = errors.New("私密社区，只有成员可以浏览")
+	ErrCommunityRestricted = errors.New("只有社区成员可以发帖和评论")
+	ErrPostTypeNotAllowed  = errors.New("社区不允许发布该类型的帖子")
+	ErrJoinRequestNotExist = errors.New("加入申请不存在")
+	ErrCommunityArchived   = errors.New("社区已归档，只能浏览")
+)
+
+// defaultPostTypes 社区默认允许的帖子类型
+var defaultPostTypes = []string{models.PostTypeText, models.PostTypeImage, models.PostTypeLink}
+
+// getCommunitySettings 查询社区设置，没有修改过设置的社区使用默认设置
+func getCommunitySettings(communityID int64) (*models.CommunitySettings, error) {
+	settings, err := mysql.GetCommunitySettings(communityID)
+	if err != nil {
+		zap.L().Error("mysql.GetCommunitySettings failed", zap.Int64("communityID", communityID), zap.Error(err))
+		return nil, err
+	}
+	if settings == nil {
+		return &models.CommunitySettings{
+			CommunityID:      communityID,
+			AllowedPostTypes: defaultPostTypes,
+			Visibility:       models.CommunityPublic,
+		}, nil
+	}
+	settings.AllowedPostTypes = strings.Split(settings.PostTypes, ",")
+	return settings, nil
+}
+
+// GetCommunitySettings 获取社区设置
+func GetCommunitySettings(communityID int64) (*models.CommunitySettings, error) {
+	if _, err := mysql.GetCommunityById(communityID); err != nil {
+		if err != sql.ErrNoRows {
+			zap.L().Error("mysql.GetCommunityById failed", zap.Error(err))
+		}
+		return nil, err
+	}
+	return getCommunitySettings(communityID)
+}
+
+// UpdateCommunitySettings 版主修改社区设置
+func UpdateCommunitySettings(communityID, userID int64, p *models.ParamCommunitySettings) error {
+	if err := requireModerator(communityID, userID); err != nil {
+		return err
+	}
+	if _, err := filterSensitive(SensitiveFieldCommunity, &p.Rules); err != nil {
+		return err
+	}
+	settings := &models.CommunitySettings{
+		CommunityID: communityID,
+		Rules:       p.Rules,
+		Icon:        p.Icon,
+		Banner:      p.Banner,
+		PostTypes:   strings.Join(p.AllowedPostTypes, ","),
+		Visibility:  *p.Visibility,
+	}
+	if err := mysql.SaveCommunitySettings(settings); err != nil {
+		zap.L().Error("mysql.SaveCommunitySettings failed", zap.Error(err))
+		return err
+	}
+	logModeration(&models.ModerationLog{
+		CommunityID: communityID,
+		ModeratorID: userID,
+		Action:      models.ModActionUpdateSettings,
+	})
+	return nil
+}
+
+// UpdateCommunity 版主修改社区简介和设置，只修改传入的字段
+func UpdateCommunity(communityID, userID int64, p *models.ParamUpdateCommunity) error {
+	if err := requireModerator(communityID, userID); err != nil {
+		return err
+	}
+	return updateCommunity(communityID, userID, p)
+}
+
+// updateCommunity 修改社区简介和设置并记录管理日志，调用方负责检查权限
+func updateCommunity(communityID, userID int64, p *models.ParamUpdateCommunity) error {
+	for _, text := range []*string{p.Introduction, p.Rules} {
+		if text == nil {
+			continue
+		}
+		if _, err := filterSensitive(SensitiveFieldCommunity, text); err != nil {
+			return err
+		}
+	}
+	if p.Introduction != nil {
+		if err := mysql.UpdateCommunityIntroduction(communityID, *p.Introduction); err != nil {
+			zap.L().Error("mysql.UpdateCommunityIntroduction failed", zap.Error(err))
+			return err
+		}
+	}
+	if p.Rules != nil || p.Icon != nil || p.Banner != nil || p.AllowedPostTypes != nil || p.Visibility != nil {
+		settings, err := getCommunitySettings(communityID)
+		if err != nil {
+			return err
+		}
+		if p.Rules != nil {
+			settings.Rules = *p.Rules
+		}
+		if p.Icon != nil {
+			settings.Icon = *p.Icon
+		}
+		if p.Banner != nil {
+			settings.Banner = *p.Banner
+		}
+		if p.AllowedPostTypes != nil {
+			settings.AllowedPostTypes = p.AllowedPostTypes
+		}
+		if p.Visibility != nil {
+			settings.Visibility = *p.Visibility
+		}
+		settings.PostTypes = strings.Join(settings.AllowedPostTypes, ",")
+		if err := mysql.SaveCommunitySettings(settings); err != nil {
+			zap.L().Error("mysql.SaveCommunitySettings failed", zap.Error(err))
+			return err
+		}
+	}
+	logModeration(&models.ModerationLog{
+		CommunityID: communityID,
+		ModeratorID: userID,
+		Action:      models.ModActionUpdateSettings,
+	})
+	return nil
+}
+
+// ArchiveCommunity 创建者归档或取消归档社区
+func ArchiveCommunity(communityID, userID int64, archived bool) error {
+	if err := requireOwner(communityID, userID); err != nil {
+		return err
+	}
+	settings, err := getCommunitySettings(communityID)
+	if err != nil {
+		return err
+	}
+	if settings.Archived == archived {
+		return nil
+	}
+	if err := mysql.SetCommunityArchived(communityID, archived); err != nil {
+		zap.L().Error("mysql.SetCommunityArchived failed", zap.Error(err))
+		return err
+	}
+	action := models.ModActionArchive
+	if !archived {
+		action = models.ModActionUnarchive
+	}
+	logModeration(&models.ModerationLog{
+		CommunityID: communityID,
+		ModeratorID: userID,
+		Action:      action,
+	})
+	return nil
+}
+
+// checkCommunityArchived 社区已归档时返回 ErrCommunityArchived
+func checkCommunityArchived(communityID int64) error {
+	settings, err := getCommunitySettings(communityID)
+	if err != nil {
+		return err
+	}
+	if settings.Archived {
+		return ErrCommunityArchived
+	}
+	return nil
+}
+
+// checkCommunityRead 用户不能浏览私密社区时返回 ErrCommunityPrivate
+func checkCommunityRead(communityID, userID int64) error {
+	settings, err := getCommunitySettings(communityID)
+	if err != nil {
+		return err
+	}
+	if settings.Visibility != models.CommunityPrivate {
+		return nil
+	}
+	role, err := mysql.GetCommunityRole(communityID, userID)
+	if err != nil {
+		zap.L().Error("mysql.GetCommunityRole failed", zap.Error(err))
+		return err
+	}
+	if role == models.CommunityRoleNone {
+		return ErrCommunityPrivate
+	}
+	return nil
+}
+
+// checkPostRead 用户不能浏览帖子所在的社区时返回错误
+func checkPostRead(postID, userID int64) error {
+	post, err := getPost(postID)
+	if err != nil {
+		return err
+	}
+	return checkCommunityRead(post.CommunityID, userID)
+}
+
+// checkCommunityWrite 检查用户能否在社区中发帖和评论，返回社区设置
+// 归档的社区不能发帖和评论，受限和私密社区只有成员可以发帖和评论，被禁言的用户不能发帖和评论
+func checkCommunityWrite(communityID, userID int64) (*models.CommunitySettings, error) {
+	settings, err := getCommunitySettings(communityID)
+	if err != nil {
+		return nil, err
+	}
+	if settings.Archived {
+		return nil, ErrCommunityArchived
+	}
+	if settings.Visibility != models.CommunityPublic {
+		role, err := mysql.GetCommunityRole(communityID, userID)
+		if err != nil {
+			zap.L().Error("mysql.GetCommunityRole failed", zap.Error(err))
+			return nil, err
+		}
+		if role == models.CommunityRoleNone {
+			if settings.Visibility == models.CommunityPrivate {
+				return nil, ErrCommunityPrivate
+			}
+			return nil, ErrCommunityRestricted
+		}
+	}
+	if err := checkCommunityBan(communityID, userID); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// allowsPostType 社区是否允许发布该类型的帖子
+func allowsPostType(settings *models.CommunitySettings, postType string) bool {
+	for _, t := range settings.AllowedPostTypes {
+		if t == postType {
+			return true
+		}
+	}
+	return false
+}
+
+// hiddenCommunities 用户不能浏览的私密社区
+func hiddenCommunities(userID int64) (map[int64]struct{}, error) {
+	private, err := mysql.GetPrivateCommunityIDs()
+	if err != nil {
+		zap.L().Error("mysql.GetPrivateCommunityIDs failed", zap.Error(err))
+		return nil, err
+	}
+	hidden := make(map[int64]struct{}, len(private))
+	if len(private) == 0 {
+		return hidden, nil
+	}
+	joined, err := mysql.GetUserCommunityIDs(userID)
+	if err != nil {
+		zap.L().Error("mysql.GetUserCommunityIDs failed", zap.Error(err))
+		return nil, err
+	}
+	for _, id := range private {
+		hidden[id] = struct{}{}
+	}
+	for _, id := range joined {
+		delete(hidden, id)
+	}
+	return hidden, nil
+}
+
+// filterReadablePosts 去掉用户不能浏览的私密社区中的帖子，以及被影子封禁的用户发布的帖子
+func filterReadablePosts(userID int64, ps []*models.Post) ([]*models.Post, error) {
+	ps = filterShadowBannedPosts(context.Background(), userID, ps)
+	if len(ps) == 0 {
+		return ps, nil
+	}
+	hidden, err := hiddenCommunities(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(hidden) == 0 {
+		return ps, nil
+	}
+	list := make([]*models.Post, 0, len(ps))
+	for _, post := range ps {
+		if _, ok := hidden[post.CommunityID]; !ok {
+			list = append(list, post)
+		}
+	}
+	return list, nil
+}
+
+// InviteCommunityMember 版主邀请用户加入社区，用户加入受限或私密社区时不再需要批准
+func InviteCommunityMember(communityID, userID, inviteeID int64) error {
+	if err := requireModerator(communityID, userID); err != nil {
+		return err
+	}
+	if _, err := mysql.GetUserByID(inviteeID); err != nil {
+		if err == sql.ErrNoRows {
+			return mysql.ErrorUserNotExist
+		}
+		zap.L().Error("mysql.GetUserByID failed", zap.Error(err))
+		return err
+	}
+	role, err := mysql.GetCommunityRole(communityID, inviteeID)
+	if err != nil {
+		zap.L().Error("mysql.GetCommunityRole failed", zap.Error(err))
+		return err
+	}
+	if role != models.CommunityRoleNone {
+		return nil
+	}
+	if err := mysql.InviteCommunityUser(communityID, inviteeID, userID); err != nil {
+		zap.L().Error("mysql.InviteCommunityUser failed", zap.Error(err))
+		return err
+	}
+	logModeration(&models.ModerationLog{
+		CommunityID:  communityID,
+		ModeratorID:  userID,
+		Action:       models.ModActionInviteMember,
+		TargetUserID: inviteeID,
+	})
+	return nil
+}
+
+// ReviewJoinRequest 版主批准或拒绝加入申请，批准后用户成为社区成员
+func ReviewJoinRequest(c context.Context, communityID, userID, applicantID int64, approve bool) error {
+	if err := requireModerator(communityID, userID); err != nil {
+		return err
+	}
+	status, action := models.JoinRequestRejected, models.ModActionRejectMember
+	if approve {
+		status, action = models.JoinRequestApproved, models.ModActionApproveMember
+	}
+	exist, err := mysql.SetJoinRequestStatus(communityID, applicantID, status)
+	if err != nil {
+		zap.L().Error("mysql.SetJoinRequestStatus failed", zap.Error(err))
+		return err
+	}
+	if !exist {
+		return ErrJoinRequestNotExist
+	}
+	if approve {
+		if _, err := mysql.JoinCommunity(communityID, applicantID); err != nil {
+			zap.L().Error("mysql.JoinCommunity failed", zap.Error(err))
+			return err
+		}
+		if err := redis.ClearHomeFeed(c, applicantID); err != nil {
+			zap.L().Warn("redis.ClearHomeFeed failed", zap.Int64("userID", applicantID), zap.Error(err))
+		}
+	}
+	logModeration(&models.ModerationLog{
+		CommunityID:  communityID,
+		ModeratorID:  userID,
+		Action:       action,
+		TargetUserID: applicantID,
+	})
+	return nil
+}
+
+// GetJoinRequests 版主查看社区待处理的加入申请
+func GetJoinRequests(communityID, userID int64) ([]*models.CommunityJoinRequest, error) {
+	if err := requireModerator(communityID, userID); err != nil {
+		return nil, err
+	}
+	reqs, err := mysql.GetPendingJoinRequests(communityID)
+	if err != nil {
+		zap.L().Error("mysql.GetPendingJoinRequests failed", zap.Error(err))
+	}
+	return reqs, err
+}
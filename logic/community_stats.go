@@ -0,0 +1,122 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/models"
+	"database/sql"
+	"go.uber.org/zap"
+	"time"
+)
+
+// 社区统计
+/*
+	1. 定时任务每小时把当天和前一天的帖子、评论、投票、活跃用户和新成员按社区汇总到 community_daily_stats，
+	   查询统计数据时只读汇总表，不扫描原始数据
+	2. 汇总可以重复执行，结果覆盖已有数据；成员数只能取到当前值，所以只在汇总当天时更新
+	3. 没有任何活动的日期也有一行数据，图表不会断开
+*/
+
+// defaultStatsDays 默认查询最近多少天的统计数据
+const defaultStatsDays = 30
+
+// RollupCommunityStats 汇总各社区某天的统计数据
+func RollupCommunityStats(day time.Time) error {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+
+	memberCounts, err := mysql.GetCommunityMemberCounts()
+	if err != nil {
+		zap.L().Error("mysql.GetCommunityMemberCounts failed", zap.Error(err))
+		return err
+	}
+	counters := []struct {
+		name  string
+		count func(start, end time.Time) (map[int64]int64, error)
+	}{
+		{"posts", mysql.CountCommunityPosts},
+		{"comments", mysql.CountCommunityComments},
+		{"votes", mysql.CountCommunityVotes},
+		{"active_users", mysql.CountCommunityActiveUsers},
+		{"new_members", mysql.CountCommunityNewMembers},
+	}
+	counts := make([]map[int64]int64, len(counters))
+	for i, counter := range counters {
+		if counts[i], err = counter.count(start, end); err != nil {
+			zap.L().Error("count community stats failed", zap.String("metric", counter.name), zap.Error(err))
+			return err
+		}
+	}
+
+	dayStr := start.Format("2006-01-02")
+	stats := make([]*models.CommunityDailyStats, 0, len(memberCounts))
+	for communityID, memberCount := range memberCounts {
+		stats = append(stats, &models.CommunityDailyStats{
+			CommunityID: communityID,
+			Day:         dayStr,
+			Posts:       counts[0][communityID],
+			Comments:    counts[1][communityID],
+			Votes:       counts[2][communityID],
+			ActiveUsers: counts[3][communityID],
+			NewMembers:  counts[4][communityID],
+			MemberCount: memberCount,
+		})
+	}
+	today := time.Now().In(start.Location()).Format("2006-01-02")
+	if err := mysql.SaveCommunityDailyStats(stats, dayStr == today); err != nil {
+		zap.L().Error("mysql.SaveCommunityDailyStats failed", zap.String("day", dayStr), zap.Error(err))
+		return err
+	}
+	zap.L().Info("社区统计汇总完成", zap.String("day", dayStr), zap.Int("communities", len(stats)))
+	return nil
+}
+
+// RollupRecentCommunityStats 汇总前一天和当天的统计数据，前一天的数据在零点后补齐
+func RollupRecentCommunityStats() {
+	now := time.Now()
+	for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+		if err := RollupCommunityStats(day); err != nil {
+			zap.L().Error("RollupCommunityStats failed", zap.Time("day", day), zap.Error(err))
+		}
+	}
+}
+
+// GetCommunityStats 获取社区最近 days 天每天的统计数据，按日期升序
+func GetCommunityStats(communityID, userID int64, days int) ([]*models.CommunityDailyStats, error) {
+	if _, err := mysql.GetCommunityById(communityID); err != nil {
+		if err != sql.ErrNoRows {
+			zap.L().Error("mysql.GetCommunityById failed", zap.Error(err))
+		}
+		return nil, err
+	}
+	if err := checkCommunityRead(communityID, userID); err != nil {
+		return nil, err
+	}
+	if days <= 0 {
+		days = defaultStatsDays
+	}
+	now := time.Now()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1-days)
+	rows, err := mysql.GetCommunityDailyStats(communityID, since)
+	if err != nil {
+		zap.L().Error("mysql.GetCommunityDailyStats failed", zap.Error(err))
+		return nil, err
+	}
+
+	// 补齐还没有汇总数据的日期，成员数沿用前一天
+	byDay := make(map[string]*models.CommunityDailyStats, len(rows))
+	for _, r := range rows {
+		byDay[r.Day] = r
+	}
+	stats := make([]*models.CommunityDailyStats, 0, days)
+	var memberCount int64
+	for i := 0; i < days; i++ {
+		day := since.AddDate(0, 0, i).Format("2006-01-02")
+		s, ok := byDay[day]
+		if !ok {
+			s = &models.CommunityDailyStats{CommunityID: communityID, Day: day, MemberCount: memberCount}
+		}
+		memberCount = s.MemberCount
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
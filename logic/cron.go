@@ -1,7 +1,6 @@
 package logic
 
 import (
-	"bluebell/dao/redis"
 	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
 )
@@ -9,15 +8,35 @@ import (
 // 启动定时任务
 func StartCronJob() {
 	c := cron.New()
-	_, err := c.AddFunc("@every 600m", SyncHotsDLikesToMySQL) // 每 10 分钟同步增量热度数据
+	_, err := c.AddFunc("@every 10m", SyncHotsDLikesToMySQL) // 每 10 分钟同步增量热度数据
 	if err != nil {
 		zap.L().Error("更新热度定时任务创建失败", zap.Error(err))
 	}
 
-	_, err = c.AddFunc("@daily", redis.CleanOldRedisData) // 每天清理 Redis 过期数据
+	_, err = c.AddFunc("@every 10m", RefreshRankScores) // 每 10 分钟刷新随时间变化的排序分数
 	if err != nil {
-		zap.L().Error("清理热度定时任务创建失败", zap.Error(err))
+		zap.L().Error("刷新排序分数定时任务创建失败", zap.Error(err))
+	}
+
+	_, err = c.AddFunc("@hourly", func() { RepairVoteCounters() }) // 每小时校验并修复帖子票数
+	if err != nil {
+		zap.L().Error("修复票数定时任务创建失败", zap.Error(err))
+	}
+
+	_, err = c.AddFunc("@hourly", RollupRecentCommunityStats) // 每小时汇总社区统计数据
+	if err != nil {
+		zap.L().Error("汇总社区统计定时任务创建失败", zap.Error(err))
+	}
+
+	_, err = c.AddFunc("@every 10m", SyncUserSanctions) // 每 10 分钟按数据库重建封禁名单，清理到期的封禁
+	if err != nil {
+		zap.L().Error("同步封禁名单定时任务创建失败", zap.Error(err))
 	}
 	c.Start()
 
+	// 启动时修复一次，补齐还没有票数计数的旧帖子
+	go RepairVoteCounters()
+	// 启动时同步一次封禁名单，redis 数据丢失后立即恢复
+	go SyncUserSanctions()
+
 }
@@ -0,0 +1,75 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/models"
+	"database/sql"
+	"errors"
+	"go.uber.org/zap"
+)
+
+// 关注
+/*
+	1. 用户可以关注其他用户，被关注的用户收到新关注者通知，重复关注不再通知
+	2. 任意一方屏蔽了对方时不能关注
+*/
+
+var ErrFollowSelf = errors.New("不能关注自己")
+
+// FollowUser 关注用户
+func FollowUser(followerID, userID int64) error {
+	if followerID == userID {
+		return ErrFollowSelf
+	}
+	if _, err := mysql.GetUserByID(userID); err != nil {
+		if err == sql.ErrNoRows {
+			return mysql.ErrorUserNotExist
+		}
+		zap.L().Error("mysql.GetUserByID failed", zap.Error(err))
+		return err
+	}
+	blocked, err := mysql.IsBlockedEither(followerID, userID)
+	if err != nil {
+		zap.L().Error("mysql.IsBlockedEither failed", zap.Error(err))
+		return err
+	}
+	if blocked {
+		return ErrUserBlocked
+	}
+	created, err := mysql.FollowUser(userID, followerID)
+	if err != nil {
+		zap.L().Error("mysql.FollowUser failed", zap.Error(err))
+		return err
+	}
+	if created {
+		notify(&models.Notification{UserID: userID, ActorID: followerID, Type: models.NotificationFollow})
+	}
+	return nil
+}
+
+// UnfollowUser 取消关注用户
+func UnfollowUser(followerID, userID int64) error {
+	if err := mysql.UnfollowUser(userID, followerID); err != nil {
+		zap.L().Error("mysql.UnfollowUser failed", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetFollowers 查询关注了用户的全部用户
+func GetFollowers(userID int64) ([]*models.UserSafe, error) {
+	users, err := mysql.GetFollowers(userID)
+	if err != nil {
+		zap.L().Error("mysql.GetFollowers failed", zap.Error(err))
+	}
+	return users, err
+}
+
+// GetFollowing 查询用户关注的全部用户
+func GetFollowing(userID int64) ([]*models.UserSafe, error) {
+	users, err := mysql.GetFollowing(userID)
+	if err != nil {
+		zap.L().Error("mysql.GetFollowing failed", zap.Error(err))
+	}
+	return users, err
+}
@@ -0,0 +1,123 @@
+package logic
+
+import (
+	"bluebell/models"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 管理员手动触发的维护任务
+/*
+	1. 任务在后台运行，接口立即返回；同一任务在本实例上同时只运行一个
+	2. 运行状态只保存在本实例的内存中，投票同步另有 redis 锁，多个实例不会同时同步
+*/
+
+var (
+	ErrJobNotExist = errors.New("维护任务不存在")
+	ErrJobRunning  = errors.New("维护任务正在运行")
+)
+
+// maintenanceJobs 可以手动触发的维护任务，返回值是运行结果的说明
+var maintenanceJobs = map[string]func() (string, error){
+	"vote_sync": func() (string, error) {
+		SyncHotsDLikesToMySQL()
+		return "", nil
+	},
+	"rebuild_ranking": func() (string, error) {
+		total, err := RebuildRedisRanking(false)
+		return fmt.Sprintf("处理帖子 %d 个", total), err
+	},
+	"rebuild_ranking_full": func() (string, error) {
+		total, err := RebuildRedisRanking(true)
+		return fmt.Sprintf("处理帖子 %d 个", total), err
+	},
+	"repair_votes": func() (string, error) {
+		drifts, err := RepairVoteCounters()
+		return fmt.Sprintf("修复帖子票数 %d 个", len(drifts)), err
+	},
+	"refresh_rank": func() (string, error) {
+		RefreshRankScores()
+		return "", nil
+	},
+	"community_stats": func() (string, error) {
+		RollupRecentCommunityStats()
+		return "", nil
+	},
+	"sync_sanctions": func() (string, error) {
+		SyncUserSanctions()
+		return "", nil
+	},
+}
+
+var (
+	jobMu     sync.Mutex
+	jobStatus = make(map[string]*models.MaintenanceJob)
+)
+
+// RunMaintenanceJob 管理员在后台运行维护任务
+func RunMaintenanceJob(operatorID int64, name string) error {
+	if !IsSiteAdmin(operatorID) {
+		return ErrNotSiteAdmin
+	}
+	run, ok := maintenanceJobs[name]
+	if !ok {
+		return ErrJobNotExist
+	}
+	jobMu.Lock()
+	status, ok := jobStatus[name]
+	if !ok {
+		status = &models.MaintenanceJob{Name: name}
+		jobStatus[name] = status
+	}
+	if status.Running {
+		jobMu.Unlock()
+		return ErrJobRunning
+	}
+	start := time.Now()
+	status.Running, status.LastStart = true, &start
+	jobMu.Unlock()
+
+	zap.L().Info("开始运行维护任务", zap.String("job", name), zap.Int64("operator", operatorID))
+	go func() {
+		result, err := run()
+		finish := time.Now()
+		jobMu.Lock()
+		defer jobMu.Unlock()
+		status.Running, status.LastFinish, status.LastResult, status.LastError = false, &finish, result, ""
+		if err != nil {
+			status.LastError = err.Error()
+			zap.L().Error("维护任务运行失败", zap.String("job", name), zap.Error(err))
+			return
+		}
+		zap.L().Info("维护任务运行完成", zap.String("job", name), zap.String("result", result), zap.Duration("cost", finish.Sub(start)))
+	}()
+	return nil
+}
+
+// GetMaintenanceStatus 管理员查看全部维护任务的状态及投票同步进度
+func GetMaintenanceStatus(operatorID int64) (*models.MaintenanceStatus, error) {
+	if !IsSiteAdmin(operatorID) {
+		return nil, ErrNotSiteAdmin
+	}
+	jobs := make([]*models.MaintenanceJob, 0, len(maintenanceJobs))
+	jobMu.Lock()
+	for name := range maintenanceJobs {
+		job := &models.MaintenanceJob{Name: name}
+		if status, ok := jobStatus[name]; ok {
+			*job = *status
+		}
+		jobs = append(jobs, job)
+	}
+	jobMu.Unlock()
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+
+	checkpoint, err := GetVoteSyncStatus()
+	if err != nil {
+		return nil, err
+	}
+	return &models.MaintenanceStatus{Jobs: jobs, VoteSync: checkpoint}, nil
+}
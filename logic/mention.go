@@ -0,0 +1,187 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/models"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"regexp"
+)
+
+// @提及
+/*
+	发帖和评论时解析内容中的 @用户名，存在的用户保存为提及记录并收到通知；编辑评论时只处理新增的提及。
+	返回帖子和评论时，提及的用户名渲染为用户主页链接。
+	每条内容最多提及 mention.max_per_item 个用户，超出的部分忽略。
+*/
+
+// mentionPattern 匹配 @用户名，@ 前不能是字母数字，避免把邮箱当作提及
+var mentionPattern = regexp.MustCompile(`(^|[^\p{L}\p{N}_])@([\p{L}\p{N}_\-]{1,64})`)
+
+// mentionLink 用户主页的链接
+func mentionLink(username string) string {
+	return "/api/v1/user/" + username
+}
+
+// parseMentions 解析内容中提及的用户名，去重后最多返回 max 个
+func parseMentions(content string, max int) []string {
+	names := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, m := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		if len(names) >= max {
+			break
+		}
+		if _, ok := seen[m[2]]; ok {
+			continue
+		}
+		seen[m[2]] = struct{}{}
+		names = append(names, m[2])
+	}
+	return names
+}
+
+// mentionLimit 每条内容最多提及的用户数量
+func mentionLimit() int {
+	if viper.IsSet("mention.max_per_item") {
+		return viper.GetInt("mention.max_per_item")
+	}
+	return 10
+}
+
+// saveMentions 保存内容中的提及记录，并通知被提及的用户
+func saveMentions(sourceType int8, sourceID, postID, authorID int64, content string) error {
+	return saveMentionNames(sourceType, sourceID, postID, authorID, parseMentions(content, mentionLimit()))
+}
+
+// addedMentions 编辑后的内容中新增提及的用户名
+func addedMentions(oldContent, content string, max int) []string {
+	mentioned := make(map[string]struct{})
+	for _, name := range parseMentions(oldContent, max) {
+		mentioned[name] = struct{}{}
+	}
+	names := make([]string, 0)
+	for _, name := range parseMentions(content, max) {
+		if _, ok := mentioned[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// saveEditedMentions 内容编辑后保存新增的提及，编辑前已提及的用户不再重复通知
+func saveEditedMentions(sourceType int8, sourceID, postID, authorID int64, oldContent, content string) error {
+	return saveMentionNames(sourceType, sourceID, postID, authorID, addedMentions(oldContent, content, mentionLimit()))
+}
+
+// saveMentionNames 保存对一组用户名的提及记录，并通知被提及的用户
+func saveMentionNames(sourceType int8, sourceID, postID, authorID int64, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	users, err := mysql.GetUsersByNames(names)
+	if err != nil {
+		zap.L().Error("mysql.GetUsersByNames failed", zap.Error(err))
+		return err
+	}
+
+	mentions := make([]*models.Mention, 0, len(users))
+	ns := make([]*models.Notification, 0, len(users))
+	for _, u := range users {
+		if u.UserID == authorID {
+			continue
+		}
+		mentions = append(mentions, &models.Mention{
+			SourceType: sourceType,
+			SourceID:   sourceID,
+			PostID:     postID,
+			UserID:     u.UserID,
+			Username:   u.Username,
+			AuthorID:   authorID,
+		})
+		n := &models.Notification{UserID: u.UserID, ActorID: authorID, Type: models.NotificationMention, PostID: postID}
+		if sourceType == models.MentionSourceComment {
+			n.CommentID = sourceID
+		}
+		ns = append(ns, n)
+	}
+	if err := mysql.CreateMentions(mentions); err != nil {
+		zap.L().Error("mysql.CreateMentions failed", zap.Error(err))
+		return err
+	}
+	notify(ns...)
+	return nil
+}
+
+// renderMentions 将内容中被提及的用户名渲染为 markdown 链接
+func renderMentions(content string, mentions []*models.ApiMention) string {
+	if len(mentions) == 0 {
+		return content
+	}
+	links := make(map[string]string, len(mentions))
+	for _, m := range mentions {
+		links[m.Username] = m.Link
+	}
+	return mentionPattern.ReplaceAllStringFunc(content, func(s string) string {
+		m := mentionPattern.FindStringSubmatch(s)
+		link, ok := links[m[2]]
+		if !ok {
+			return s
+		}
+		return m[1] + "[@" + m[2] + "](" + link + ")"
+	})
+}
+
+// getMentions 查询一批内容中的提及，按内容id分组
+func getMentions(sourceType int8, ids []int64) (map[int64][]*models.ApiMention, error) {
+	mentions, err := mysql.GetMentionsBySources(sourceType, ids)
+	if err != nil {
+		zap.L().Error("mysql.GetMentionsBySources failed", zap.Error(err))
+		return nil, err
+	}
+	grouped := make(map[int64][]*models.ApiMention)
+	for _, m := range mentions {
+		grouped[m.SourceID] = append(grouped[m.SourceID], &models.ApiMention{
+			UserID:   m.UserID,
+			Username: m.Username,
+			Link:     mentionLink(m.Username),
+		})
+	}
+	return grouped, nil
+}
+
+// fillPostMentions 填充帖子中提及的用户及渲染后的内容，查询失败时只记录日志
+func fillPostMentions(posts []*models.Post) {
+	ids := make([]int64, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	grouped, err := getMentions(models.MentionSourcePost, ids)
+	if err != nil {
+		return
+	}
+	for _, p := range posts {
+		p.Mentions = grouped[p.ID]
+		p.RenderedContent = renderMentions(p.Content, p.Mentions)
+	}
+}
+
+// fillCommentMentions 填充评论中提及的用户及渲染后的内容，已删除的评论不填充
+func fillCommentMentions(comments []*models.Comment) {
+	ids := make([]int64, 0, len(comments))
+	for _, cm := range comments {
+		if cm.Status == models.CommentStatusNormal {
+			ids = append(ids, cm.CommentID)
+		}
+	}
+	grouped, err := getMentions(models.MentionSourceComment, ids)
+	if err != nil {
+		return
+	}
+	for _, cm := range comments {
+		if cm.Status != models.CommentStatusNormal {
+			continue
+		}
+		cm.Mentions = grouped[cm.CommentID]
+		cm.RenderedContent = renderMentions(cm.Content, cm.Mentions)
+	}
+}
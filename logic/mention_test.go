@@ -0,0 +1,84 @@
+package logic
+
+import (
+	"bluebell/models"
+	"reflect"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		max     int
+		want    []string
+	}{
+		{"none", "hello world", 10, []string{}},
+		{"start of content", "@alice hi", 10, []string{"alice"}},
+		{"after punctuation", "hi,@bob!", 10, []string{"bob"}},
+		{"chinese name", "你好 @小明 在吗", 10, []string{"小明"}},
+		{"email not mention", "mail me at a@example.com", 10, []string{}},
+		{"dedup", "@alice @bob @alice", 10, []string{"alice", "bob"}},
+		{"max", "@a @b @c @d", 2, []string{"a", "b"}},
+		{"dedup does not count", "@a @a @b", 2, []string{"a", "b"}},
+		{"name with dash and underscore", "@foo-bar_1.", 10, []string{"foo-bar_1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMentions(tt.content, tt.max); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMentions(%q, %d) = %v, want %v", tt.content, tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMentions(t *testing.T) {
+	mentions := []*models.ApiMention{
+		{UserID: 1, Username: "alice", Link: mentionLink("alice")},
+		{UserID: 2, Username: "小明", Link: mentionLink("小明")},
+	}
+	tests := []struct {
+		name     string
+		content  string
+		mentions []*models.ApiMention
+		want     string
+	}{
+		{"no mentions", "@alice hi", nil, "@alice hi"},
+		{"start of content", "@alice hi", mentions, "[@alice](/api/v1/user/alice) hi"},
+		{"keep prefix", "hi,@alice!", mentions, "hi,[@alice](/api/v1/user/alice)!"},
+		{"unknown user unchanged", "@bob hi @alice", mentions, "@bob hi [@alice](/api/v1/user/alice)"},
+		{"chinese name", "你好 @小明", mentions, "你好 [@小明](/api/v1/user/小明)"},
+		{"email unchanged", "a@alice.com", mentions, "a@alice.com"},
+		{"repeated", "@alice @alice", mentions, "[@alice](/api/v1/user/alice) [@alice](/api/v1/user/alice)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderMentions(tt.content, tt.mentions); got != tt.want {
+				t.Errorf("renderMentions(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddedMentions(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     string
+		content string
+		max     int
+		want    []string
+	}{
+		{"no change", "@alice hi", "@alice hello", 10, []string{}},
+		{"added", "@alice hi", "@alice @bob hi", 10, []string{"bob"}},
+		{"removed and added", "@alice hi", "@bob hi", 10, []string{"bob"}},
+		{"from empty", "hi", "@alice @alice", 10, []string{"alice"}},
+		{"max applies to new content", "", "@a @b @c", 2, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addedMentions(tt.old, tt.content, tt.max); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("addedMentions(%q, %q) = %v, want %v", tt.old, tt.content, got, tt.want)
+			}
+		})
+	}
+}
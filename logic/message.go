@@ -0,0 +1,255 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"bluebell/pkg/snowflake"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"time"
+)
+
+// 私信
+/*
+	1. 两个用户之间只有一个会话，会话记录双方各自已读到的消息id，用于计算未读数量和显示已读回执
+	2. 任意一方屏蔽了对方时不能发送私信
+	3. 给还没有会话的用户发私信会创建新会话，每个用户每天创建新会话的数量受 dm.new_conversation_per_day 限制
+	4. 消息保存后实时推送给在线的接收者
+*/
+
+var (
+	ErrMessageSelf           = errors.New("不能给自己发私信")
+	ErrUserBlocked           = errors.New("对方已屏蔽或被屏蔽")
+	ErrNewConversationLimit  = errors.New("发起新会话过于频繁")
+	ErrNotConversationMember = errors.New("not a member of the conversation")
+)
+
+// conversationUsers 会话中的两个用户，较小的用户id在前
+func conversationUsers(a, b int64) (int64, int64) {
+	if a < b {
+		return a, b
+	}
+	return b, a
+}
+
+// conversationPeer 会话中的另一个用户及其已读到的消息id，用户不属于该会话时返回错误
+func conversationPeer(conv *models.Conversation, userID int64) (peer, peerLastRead, myLastRead int64, err error) {
+	switch userID {
+	case conv.UserA:
+		return conv.UserB, conv.BLastReadID, conv.ALastReadID, nil
+	case conv.UserB:
+		return conv.UserA, conv.ALastReadID, conv.BLastReadID, nil
+	}
+	return 0, 0, 0, ErrNotConversationMember
+}
+
+// SendMessage 给用户发送私信
+func SendMessage(c context.Context, userID int64, p *models.ParamSendMessage) (*models.Message, error) {
+	if p.ToUserID == userID {
+		return nil, ErrMessageSelf
+	}
+	if _, err := mysql.GetUserByID(p.ToUserID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, mysql.ErrorUserNotExist
+		}
+		zap.L().Error("mysql.GetUserByID failed", zap.Error(err))
+		return nil, err
+	}
+	blocked, err := mysql.IsBlockedEither(userID, p.ToUserID)
+	if err != nil {
+		zap.L().Error("mysql.IsBlockedEither failed", zap.Error(err))
+		return nil, err
+	}
+	if blocked {
+		return nil, ErrUserBlocked
+	}
+
+	userA, userB := conversationUsers(userID, p.ToUserID)
+	exist, err := mysql.ConversationExists(userA, userB)
+	if err != nil {
+		zap.L().Error("mysql.ConversationExists failed", zap.Error(err))
+		return nil, err
+	}
+	if !exist {
+		// 限制给陌生人发起新会话的频率
+		limit := int64(20)
+		if viper.IsSet("dm.new_conversation_per_day") {
+			limit = viper.GetInt64("dm.new_conversation_per_day")
+		}
+		count, err := redis.IncrNewConversation(c, userID, 24*time.Hour)
+		if err != nil {
+			zap.L().Error("redis.IncrNewConversation failed", zap.Error(err))
+			return nil, err
+		}
+		if count > limit {
+			return nil, ErrNewConversationLimit
+		}
+	}
+
+	msg := &models.Message{
+		MessageID:  snowflake.GenID(),
+		SenderID:   userID,
+		Content:    p.Content,
+		CreateTime: time.Now(),
+	}
+	if _, err := mysql.SaveMessage(snowflake.GenID(), userA, userB, msg); err != nil {
+		zap.L().Error("mysql.SaveMessage failed", zap.Error(err))
+		// 会话没有创建成功，不占用发起新会话的次数
+		if !exist {
+			if err := redis.DecrNewConversation(c, userID); err != nil {
+				zap.L().Error("redis.DecrNewConversation failed", zap.Int64("userID", userID), zap.Error(err))
+			}
+		}
+		return nil, err
+	}
+	publishUserEvent(p.ToUserID, models.EventMessage, msg)
+	return msg, nil
+}
+
+// GetConversations 分页获取用户的会话列表
+func GetConversations(userID, offset, limit int64) ([]*models.ApiConversation, error) {
+	convs, err := mysql.GetConversations(userID, offset, limit)
+	if err != nil {
+		zap.L().Error("mysql.GetConversations failed", zap.Error(err))
+		return nil, err
+	}
+	convIDs := make([]int64, len(convs))
+	msgIDs := make([]int64, len(convs))
+	for i, conv := range convs {
+		convIDs[i], msgIDs[i] = conv.ConversationID, conv.LastMessageID
+	}
+	msgs, err := mysql.GetMessagesByIDs(msgIDs)
+	if err != nil {
+		zap.L().Error("mysql.GetMessagesByIDs failed", zap.Error(err))
+		return nil, err
+	}
+	lastMessages := make(map[int64]*models.Message, len(msgs))
+	for _, msg := range msgs {
+		lastMessages[msg.ConversationID] = msg
+	}
+	unread, err := mysql.CountUnreadByConversation(userID, convIDs)
+	if err != nil {
+		zap.L().Error("mysql.CountUnreadByConversation failed", zap.Error(err))
+		return nil, err
+	}
+
+	list := make([]*models.ApiConversation, 0, len(convs))
+	for _, conv := range convs {
+		peerID, peerLastRead, _, _ := conversationPeer(conv, userID)
+		peer, err := mysql.GetUserByID(peerID)
+		if err != nil {
+			zap.L().Error("mysql.GetUserByID failed", zap.Int64("userID", peerID), zap.Error(err))
+			return nil, err
+		}
+		list = append(list, &models.ApiConversation{
+			ConversationID:  conv.ConversationID,
+			Peer:            peer,
+			LastMessage:     lastMessages[conv.ConversationID],
+			Unread:          unread[conv.ConversationID],
+			PeerLastReadID:  peerLastRead,
+			LastMessageTime: conv.LastMessageTime,
+		})
+	}
+	return list, nil
+}
+
+// getMemberConversation 获取用户所在的会话
+func getMemberConversation(userID, conversationID int64) (*models.Conversation, error) {
+	conv, err := mysql.GetConversationByID(conversationID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotConversationMember
+	}
+	if err != nil {
+		zap.L().Error("mysql.GetConversationByID failed", zap.Error(err))
+		return nil, err
+	}
+	if _, _, _, err := conversationPeer(conv, userID); err != nil {
+		return nil, err
+	}
+	return conv, nil
+}
+
+// GetMessages 按时间倒序分页获取会话中的消息
+func GetMessages(userID, conversationID int64, p *models.ParamMessageList) ([]*models.Message, error) {
+	if _, err := getMemberConversation(userID, conversationID); err != nil {
+		return nil, err
+	}
+	msgs, err := mysql.GetMessages(conversationID, p.Before, p.Limit)
+	if err != nil {
+		zap.L().Error("mysql.GetMessages failed", zap.Error(err))
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// MarkConversationRead 将会话标记为已读，并向对方推送已读回执
+func MarkConversationRead(userID, conversationID int64) error {
+	conv, err := getMemberConversation(userID, conversationID)
+	if err != nil {
+		return err
+	}
+	peerID, _, myLastRead, _ := conversationPeer(conv, userID)
+	lastReadID, err := mysql.MarkConversationRead(conversationID, userID)
+	if err != nil {
+		zap.L().Error("mysql.MarkConversationRead failed", zap.Error(err))
+		return err
+	}
+	if lastReadID != myLastRead {
+		publishUserEvent(peerID, models.EventMessageRead, &models.MessageReadEvent{
+			ConversationID: conversationID,
+			ReaderID:       userID,
+			LastReadID:     lastReadID,
+		})
+	}
+	return nil
+}
+
+// GetUnreadMessageCount 获取用户全部会话的未读消息数量
+func GetUnreadMessageCount(userID int64) (int64, error) {
+	count, err := mysql.CountUnreadMessages(userID)
+	if err != nil {
+		zap.L().Error("mysql.CountUnreadMessages failed", zap.Error(err))
+	}
+	return count, err
+}
+
+// BlockUser 屏蔽用户，屏蔽后双方不能互发私信
+func BlockUser(userID, blockedID int64) error {
+	if userID == blockedID {
+		return ErrMessageSelf
+	}
+	if _, err := mysql.GetUserByID(blockedID); err != nil {
+		if err == sql.ErrNoRows {
+			return mysql.ErrorUserNotExist
+		}
+		zap.L().Error("mysql.GetUserByID failed", zap.Error(err))
+		return err
+	}
+	if err := mysql.BlockUser(userID, blockedID); err != nil {
+		zap.L().Error("mysql.BlockUser failed", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// UnblockUser 取消屏蔽用户
+func UnblockUser(userID, blockedID int64) error {
+	if err := mysql.UnblockUser(userID, blockedID); err != nil {
+		zap.L().Error("mysql.UnblockUser failed", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetBlockedUsers 获取用户屏蔽的全部用户
+func GetBlockedUsers(userID int64) ([]*models.UserSafe, error) {
+	users, err := mysql.GetBlockedUsers(userID)
+	if err != nil {
+		zap.L().Error("mysql.GetBlockedUsers failed", zap.Error(err))
+	}
+	return users, err
+}
@@ -0,0 +1,107 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"crypto/sha256"
+	"encoding/hex"
+	"go.uber.org/zap"
+	"os"
+	"strings"
+)
+
+// 按建表脚本迁移数据库
+/*
+	1. 脚本中的语句以行尾的分号结束，-- 开头的行是注释
+	2. 默认跳过 DROP TABLE，已存在的表、字段和索引视为已迁移，可以在已有的库上反复执行；
+	   reset 为 true 时执行 DROP TABLE，删除脚本中的表后重建，数据全部丢失
+	3. 执行过的语句按校验和记录在 schema_migration 中，之后不再执行，补全历史数据的 UPDATE 只会执行一次
+*/
+
+// splitSchema 把建表脚本拆分为单条语句
+func splitSchema(script string) []string {
+	var (
+		stmts []string
+		b     strings.Builder
+	)
+	for _, line := range strings.Split(strings.ReplaceAll(script, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSpace(b.String()))
+			b.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(b.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
+
+// MigrateSchema 执行建表脚本，返回执行和跳过的语句数量
+func MigrateSchema(path string, reset bool) (applied, skipped int, err error) {
+	script, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := mysql.InitSchemaMigration(); err != nil {
+		zap.L().Error("mysql.InitSchemaMigration failed", zap.Error(err))
+		return 0, 0, err
+	}
+	// 表全部重建后，之前的记录不再有效
+	if reset {
+		if err := mysql.ClearSchemaApplied(); err != nil {
+			zap.L().Error("mysql.ClearSchemaApplied failed", zap.Error(err))
+			return 0, 0, err
+		}
+	}
+	done, err := mysql.GetAppliedSchema()
+	if err != nil {
+		zap.L().Error("mysql.GetAppliedSchema failed", zap.Error(err))
+		return 0, 0, err
+	}
+	for _, stmt := range splitSchema(string(script)) {
+		isDrop := strings.HasPrefix(strings.ToUpper(stmt), "DROP TABLE")
+		if !reset && isDrop {
+			skipped++
+			continue
+		}
+		sum := schemaChecksum(stmt)
+		if _, ok := done[sum]; ok && !isDrop {
+			skipped++
+			continue
+		}
+		exist, err := mysql.ExecSchema(stmt)
+		if err != nil {
+			zap.L().Error("mysql.ExecSchema failed", zap.String("stmt", stmt), zap.Error(err))
+			return applied, skipped, err
+		}
+		// DROP TABLE 只在 reset 时执行，不记录
+		if !isDrop {
+			if err := mysql.MarkSchemaApplied(sum, stmt); err != nil {
+				zap.L().Error("mysql.MarkSchemaApplied failed", zap.Error(err))
+				return applied, skipped, err
+			}
+			done[sum] = struct{}{}
+		}
+		if exist {
+			skipped++
+			continue
+		}
+		applied++
+	}
+	return applied, skipped, nil
+}
+
+// schemaChecksum 计算语句的校验和，忽略每行首尾的空白
+func schemaChecksum(stmt string) string {
+	lines := strings.Split(stmt, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
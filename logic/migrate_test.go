@@ -0,0 +1,62 @@
+package logic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{"empty", "", nil},
+		{"comments only", "-- comment\n  -- indented\n\n", nil},
+		{
+			name:   "single line statements",
+			script: "DROP TABLE IF EXISTS `user`;\nSELECT 1;\n",
+			want:   []string{"DROP TABLE IF EXISTS `user`;", "SELECT 1;"},
+		},
+		{
+			name:   "multi line statement with comments",
+			script: "-- 用户表\nCREATE TABLE `user` (\n  -- 主键\n  `id` bigint NOT NULL,\n\n  PRIMARY KEY (`id`)\n);\n",
+			want:   []string{"CREATE TABLE `user` (\n  `id` bigint NOT NULL,\n  PRIMARY KEY (`id`)\n);"},
+		},
+		{
+			name:   "crlf line endings",
+			script: "SELECT 1;\r\nSELECT\r\n  2;\r\n",
+			want:   []string{"SELECT 1;", "SELECT\n  2;"},
+		},
+		{
+			name:   "semicolon inside line does not split",
+			script: "INSERT INTO t VALUES ('a;b')\n;\n",
+			want:   []string{"INSERT INTO t VALUES ('a;b')\n;"},
+		},
+		{
+			name:   "trailing statement without semicolon",
+			script: "SELECT 1;\nSELECT 2",
+			want:   []string{"SELECT 1;", "SELECT 2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitSchema(tt.script); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitSchema() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemaChecksum(t *testing.T) {
+	a := schemaChecksum("CREATE TABLE t (\n  id int\n);")
+	if b := schemaChecksum("CREATE TABLE t (\n\tid int  \n);"); a != b {
+		t.Errorf("checksum changed with indentation: %s != %s", a, b)
+	}
+	if b := schemaChecksum("CREATE TABLE t (\n  id bigint\n);"); a == b {
+		t.Error("checksum unchanged after statement changed")
+	}
+	if len(a) != 64 {
+		t.Errorf("checksum length = %d, want 64", len(a))
+	}
+}
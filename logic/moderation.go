@@ -0,0 +1,378 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"strconv"
+	"time"
+)
+
+// 社区管理
+/*
+	1. 创建社区的用户成为社区的创建者，创建者可以从社区成员中任命或撤销版主
+	2. 版主可以移除帖子和评论、置顶帖子（每个社区最多 community.max_pinned_posts 个）、
+	   锁定帖子禁止评论，以及在一段时间内禁止用户在社区中发帖和评论
+	3. 版主的每一次操作都记录在社区的管理日志中，日志写入失败只记录日志，不影响操作本身
+*/
+
+var (
+	ErrNotCommunityMember  = errors.New("不是社区成员")
+	ErrNotCommunityOwner   = errors.New("不是社区创建者")
+	ErrNotModerator        = errors.New("不是社区版主")
+	ErrOwnerCannotLeave    = errors.New("创建者不能退出社区")
+	ErrModeratorProtected  = errors.New("不能对版主执行该操作")
+	ErrPinnedPostLimit     = errors.New("置顶帖子数量已达上限")
+	ErrPostRemoved         = errors.New("帖子已被移除")
+	ErrPostLocked          = errors.New("帖子已被锁定")
+	ErrBannedFromCommunity = errors.New("已被禁止在该社区发言")
+)
+
+// 被移除和被举报隐藏的帖子显示的标题和内容
+const (
+	postRemovedContent = "[removed]"
+	postHiddenContent  = "[hidden]"
+)
+
+// isModerator 用户是否是社区的版主或创建者
+func isModerator(communityID, userID int64) (bool, error) {
+	role, err := mysql.GetCommunityRole(communityID, userID)
+	if err != nil {
+		zap.L().Error("mysql.GetCommunityRole failed", zap.Int64("communityID", communityID), zap.Error(err))
+		return false, err
+	}
+	return role >= models.CommunityRoleModerator, nil
+}
+
+// requireModerator 用户不是社区的版主或创建者时返回 ErrNotModerator
+func requireModerator(communityID, userID int64) error {
+	ok, err := isModerator(communityID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotModerator
+	}
+	return nil
+}
+
+// getPost 查询帖子，帖子不存在时返回 mysql.ErrorPostNotExist
+func getPost(postID int64) (*models.Post, error) {
+	post, err := mysql.GetPostByID(postID)
+	if err == sql.ErrNoRows {
+		return nil, mysql.ErrorPostNotExist
+	}
+	if err != nil {
+		zap.L().Error("mysql.GetPostByID failed", zap.Int64("postID", postID), zap.Error(err))
+		return nil, err
+	}
+	return post, nil
+}
+
+// postUnavailable 帖子已被移除或因举报被隐藏
+func postUnavailable(post *models.Post) bool {
+	return post.Status == models.PostStatusRemoved || post.Status == models.PostStatusHidden
+}
+
+// getModeratedPost 查询版主要操作的帖子，并检查操作者是帖子所在社区的版主
+func getModeratedPost(postID, userID int64) (*models.Post, error) {
+	post, err := getPost(postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireModerator(post.CommunityID, userID); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// checkCommunityBan 用户在社区中被禁言时返回 ErrBannedFromCommunity
+func checkCommunityBan(communityID, userID int64) error {
+	ban, err := mysql.GetCommunityBan(communityID, userID, time.Now())
+	if err != nil {
+		zap.L().Error("mysql.GetCommunityBan failed", zap.Int64("communityID", communityID), zap.Error(err))
+		return err
+	}
+	if ban != nil {
+		return ErrBannedFromCommunity
+	}
+	return nil
+}
+
+// logModeration 记录版主操作
+func logModeration(l *models.ModerationLog) {
+	if err := mysql.AddModerationLog(l); err != nil {
+		zap.L().Error("mysql.AddModerationLog failed",
+			zap.Int64("communityID", l.CommunityID), zap.String("action", l.Action), zap.Error(err))
+	}
+}
+
+// AppointModerator 创建者任命社区成员为版主
+func AppointModerator(communityID, ownerID, userID int64) error {
+	if err := requireOwner(communityID, ownerID); err != nil {
+		return err
+	}
+	role, err := mysql.GetCommunityRole(communityID, userID)
+	if err != nil {
+		zap.L().Error("mysql.GetCommunityRole failed", zap.Error(err))
+		return err
+	}
+	switch role {
+	case models.CommunityRoleNone:
+		return ErrNotCommunityMember
+	case models.CommunityRoleModerator, models.CommunityRoleOwner:
+		return nil
+	}
+	if _, err := mysql.SetCommunityRole(communityID, userID, models.CommunityRoleModerator); err != nil {
+		zap.L().Error("mysql.SetCommunityRole failed", zap.Error(err))
+		return err
+	}
+	logModeration(&models.ModerationLog{
+		CommunityID:  communityID,
+		ModeratorID:  ownerID,
+		Action:       models.ModActionAppointModerator,
+		TargetUserID: userID,
+	})
+	return nil
+}
+
+// DismissModerator 创建者撤销版主
+func DismissModerator(communityID, ownerID, userID int64) error {
+	if err := requireOwner(communityID, ownerID); err != nil {
+		return err
+	}
+	role, err := mysql.GetCommunityRole(communityID, userID)
+	if err != nil {
+		zap.L().Error("mysql.GetCommunityRole failed", zap.Error(err))
+		return err
+	}
+	if role != models.CommunityRoleModerator {
+		return nil
+	}
+	if _, err := mysql.SetCommunityRole(communityID, userID, models.CommunityRoleMember); err != nil {
+		zap.L().Error("mysql.SetCommunityRole failed", zap.Error(err))
+		return err
+	}
+	logModeration(&models.ModerationLog{
+		CommunityID:  communityID,
+		ModeratorID:  ownerID,
+		Action:       models.ModActionDismissModerator,
+		TargetUserID: userID,
+	})
+	return nil
+}
+
+// requireOwner 用户不是社区创建者时返回 ErrNotCommunityOwner
+func requireOwner(communityID, userID int64) error {
+	role, err := mysql.GetCommunityRole(communityID, userID)
+	if err != nil {
+		zap.L().Error("mysql.GetCommunityRole failed", zap.Error(err))
+		return err
+	}
+	if role != models.CommunityRoleOwner {
+		return ErrNotCommunityOwner
+	}
+	return nil
+}
+
+// GetCommunityModerators 获取社区的创建者和版主
+func GetCommunityModerators(communityID int64) ([]*models.CommunityModerator, error) {
+	mods, err := mysql.GetCommunityModerators(communityID)
+	if err != nil {
+		zap.L().Error("mysql.GetCommunityModerators failed", zap.Error(err))
+	}
+	return mods, err
+}
+
+// RemovePost 版主移除帖子，帖子不再出现在帖子列表中
+func RemovePost(c context.Context, postID, userID int64, reason string) error {
+	post, err := getModeratedPost(postID, userID)
+	if err != nil {
+		return err
+	}
+	return removePost(c, post, userID, reason)
+}
+
+// removePost 移除帖子并从排序中删除，记录管理日志
+func removePost(c context.Context, post *models.Post, operatorID int64, reason string) error {
+	if post.Status == models.PostStatusRemoved {
+		return nil
+	}
+	if err := mysql.SetPostStatus(post.ID, models.PostStatusRemoved); err != nil {
+		zap.L().Error("mysql.SetPostStatus failed", zap.Error(err))
+		return err
+	}
+	if err := redis.RemovePostRanking(c, strconv.FormatInt(post.ID, 10), strconv.FormatInt(post.CommunityID, 10)); err != nil {
+		zap.L().Error("redis.RemovePostRanking failed", zap.Int64("postID", post.ID), zap.Error(err))
+	}
+	logModeration(&models.ModerationLog{
+		CommunityID:  post.CommunityID,
+		ModeratorID:  operatorID,
+		Action:       models.ModActionRemovePost,
+		TargetUserID: post.AuthorID,
+		PostID:       post.ID,
+		Reason:       reason,
+	})
+	return nil
+}
+
+// PinPost 版主置顶或取消置顶帖子，置顶的帖子显示在社区帖子列表的最前面
+func PinPost(postID, userID int64, pinned bool) error {
+	post, err := getModeratedPost(postID, userID)
+	if err != nil {
+		return err
+	}
+	if postUnavailable(post) {
+		return ErrPostRemoved
+	}
+	if post.Pinned == pinned {
+		return nil
+	}
+	if pinned {
+		limit := int64(2)
+		if viper.IsSet("community.max_pinned_posts") {
+			limit = viper.GetInt64("community.max_pinned_posts")
+		}
+		count, err := mysql.CountPinnedPosts(post.CommunityID)
+		if err != nil {
+			zap.L().Error("mysql.CountPinnedPosts failed", zap.Error(err))
+			return err
+		}
+		if count >= limit {
+			return ErrPinnedPostLimit
+		}
+	}
+	if err := mysql.SetPostPinned(postID, pinned); err != nil {
+		zap.L().Error("mysql.SetPostPinned failed", zap.Error(err))
+		return err
+	}
+	action := models.ModActionPinPost
+	if !pinned {
+		action = models.ModActionUnpinPost
+	}
+	logModeration(&models.ModerationLog{
+		CommunityID:  post.CommunityID,
+		ModeratorID:  userID,
+		Action:       action,
+		TargetUserID: post.AuthorID,
+		PostID:       postID,
+	})
+	return nil
+}
+
+// LockPost 版主锁定或解锁帖子，锁定后除版主外不能再评论
+func LockPost(postID, userID int64, locked bool) error {
+	post, err := getModeratedPost(postID, userID)
+	if err != nil {
+		return err
+	}
+	if post.Locked == locked {
+		return nil
+	}
+	if err := mysql.SetPostLocked(postID, locked); err != nil {
+		zap.L().Error("mysql.SetPostLocked failed", zap.Error(err))
+		return err
+	}
+	action := models.ModActionLockPost
+	if !locked {
+		action = models.ModActionUnlockPost
+	}
+	logModeration(&models.ModerationLog{
+		CommunityID:  post.CommunityID,
+		ModeratorID:  userID,
+		Action:       action,
+		TargetUserID: post.AuthorID,
+		PostID:       postID,
+	})
+	return nil
+}
+
+// BanCommunityUser 版主在一段时间内禁止用户在社区中发帖和评论
+func BanCommunityUser(communityID, userID int64, p *models.ParamCommunityBan) error {
+	if err := requireModerator(communityID, userID); err != nil {
+		return err
+	}
+	if _, err := mysql.GetUserByID(p.UserID); err != nil {
+		if err == sql.ErrNoRows {
+			return mysql.ErrorUserNotExist
+		}
+		zap.L().Error("mysql.GetUserByID failed", zap.Error(err))
+		return err
+	}
+	protected, err := isModerator(communityID, p.UserID)
+	if err != nil {
+		return err
+	}
+	if protected {
+		return ErrModeratorProtected
+	}
+	ban := &models.CommunityBan{
+		CommunityID: communityID,
+		UserID:      p.UserID,
+		OperatorID:  userID,
+		Reason:      p.Reason,
+		ExpireTime:  time.Now().Add(time.Duration(p.Hours) * time.Hour),
+	}
+	if err := mysql.BanCommunityUser(ban); err != nil {
+		zap.L().Error("mysql.BanCommunityUser failed", zap.Error(err))
+		return err
+	}
+	logModeration(&models.ModerationLog{
+		CommunityID:  communityID,
+		ModeratorID:  userID,
+		Action:       models.ModActionBanUser,
+		TargetUserID: p.UserID,
+		Reason:       p.Reason,
+	})
+	return nil
+}
+
+// UnbanCommunityUser 版主解除用户在社区中的禁言
+func UnbanCommunityUser(communityID, userID, bannedID int64) error {
+	if err := requireModerator(communityID, userID); err != nil {
+		return err
+	}
+	existed, err := mysql.UnbanCommunityUser(communityID, bannedID)
+	if err != nil {
+		zap.L().Error("mysql.UnbanCommunityUser failed", zap.Error(err))
+		return err
+	}
+	if existed {
+		logModeration(&models.ModerationLog{
+			CommunityID:  communityID,
+			ModeratorID:  userID,
+			Action:       models.ModActionUnbanUser,
+			TargetUserID: bannedID,
+		})
+	}
+	return nil
+}
+
+// GetCommunityBans 版主查看社区中尚未到期的禁言
+func GetCommunityBans(communityID, userID int64) ([]*models.CommunityBan, error) {
+	if err := requireModerator(communityID, userID); err != nil {
+		return nil, err
+	}
+	bans, err := mysql.GetCommunityBans(communityID, time.Now())
+	if err != nil {
+		zap.L().Error("mysql.GetCommunityBans failed", zap.Error(err))
+	}
+	return bans, err
+}
+
+// GetModerationLogs 版主分页查看社区的管理日志
+func GetModerationLogs(communityID, userID, offset, limit int64) ([]*models.ModerationLog, error) {
+	if err := requireModerator(communityID, userID); err != nil {
+		return nil, err
+	}
+	logs, err := mysql.GetModerationLogs(communityID, offset, limit)
+	if err != nil {
+		zap.L().Error("mysql.GetModerationLogs failed", zap.Error(err))
+	}
+	return logs, err
+}
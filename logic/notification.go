@@ -0,0 +1,158 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/models"
+	"go.uber.org/zap"
+)
+
+// 站内通知
+/*
+	各业务逻辑在事件发生时调用 notify 产生通知，通知写入失败只记录日志，不影响业务本身。
+	1. 用户可以按类型关闭通知，关闭的类型不再产生通知
+	2. 投票类通知在用户读之前合并为一条，count 记录合并的次数
+	3. 不给用户发送自己触发的通知
+	4. 通知保存后通过实时推送发给在线的用户（见 realtime.go）
+*/
+
+// filterNotifications 去掉发给自己的通知和接收者关闭了的通知
+func filterNotifications(ns []*models.Notification) []*models.Notification {
+	list := make([]*models.Notification, 0, len(ns))
+	for _, n := range ns {
+		if n.UserID == 0 || n.UserID == n.ActorID {
+			continue
+		}
+		list = append(list, n)
+	}
+	if len(list) == 0 {
+		return list
+	}
+
+	// 同一批通知的类型可能不同，按类型查询关闭了通知的用户
+	users := make(map[int8][]int64)
+	for _, n := range list {
+		users[n.Type] = append(users[n.Type], n.UserID)
+	}
+	disabled := make(map[int8]map[int64]struct{})
+	for typ, ids := range users {
+		off, err := mysql.GetNotificationDisabledUsers(typ, ids)
+		if err != nil {
+			zap.L().Error("mysql.GetNotificationDisabledUsers failed", zap.Int8("type", typ), zap.Error(err))
+			continue
+		}
+		disabled[typ] = make(map[int64]struct{}, len(off))
+		for _, id := range off {
+			disabled[typ][id] = struct{}{}
+		}
+	}
+	filtered := list[:0]
+	for _, n := range list {
+		if _, ok := disabled[n.Type][n.UserID]; !ok {
+			filtered = append(filtered, n)
+		}
+	}
+	return filtered
+}
+
+// notify 保存通知
+func notify(ns ...*models.Notification) {
+	list := filterNotifications(ns)
+	if err := mysql.CreateNotifications(list); err != nil {
+		zap.L().Error("mysql.CreateNotifications failed", zap.Int("num", len(list)), zap.Error(err))
+		return
+	}
+	for _, n := range list {
+		publishUserEvent(n.UserID, models.EventNotification, n)
+	}
+}
+
+// notifyAggregated 保存可合并的通知，同一对象上的未读通知合并为一条
+func notifyAggregated(n *models.Notification) {
+	for _, item := range filterNotifications([]*models.Notification{n}) {
+		if err := mysql.AddAggregatedNotification(item); err != nil {
+			zap.L().Error("mysql.AddAggregatedNotification failed", zap.Int8("type", item.Type), zap.Error(err))
+			continue
+		}
+		publishUserEvent(item.UserID, models.EventNotification, item)
+	}
+}
+
+// GetNotifications 分页获取用户的通知及未读数量
+func GetNotifications(userID int64, p *models.ParamNotificationList) (*models.ApiNotificationList, error) {
+	ns, err := mysql.GetNotifications(userID, p)
+	if err != nil {
+		zap.L().Error("mysql.GetNotifications failed", zap.Error(err))
+		return nil, err
+	}
+	unread, err := mysql.CountUnreadNotifications(userID)
+	if err != nil {
+		zap.L().Error("mysql.CountUnreadNotifications failed", zap.Error(err))
+		return nil, err
+	}
+	return &models.ApiNotificationList{Unread: unread, Notifications: ns}, nil
+}
+
+// GetUnreadNotificationCount 获取用户的未读通知数量
+func GetUnreadNotificationCount(userID int64) (int64, error) {
+	unread, err := mysql.CountUnreadNotifications(userID)
+	if err != nil {
+		zap.L().Error("mysql.CountUnreadNotifications failed", zap.Error(err))
+	}
+	return unread, err
+}
+
+// MarkNotificationRead 将一条通知标记为已读
+func MarkNotificationRead(userID, id int64) error {
+	exist, err := mysql.MarkNotificationRead(userID, id)
+	if err != nil {
+		zap.L().Error("mysql.MarkNotificationRead failed", zap.Error(err))
+		return err
+	}
+	if !exist {
+		return mysql.ErrorNotificationNotExist
+	}
+	return nil
+}
+
+// MarkAllNotificationsRead 将全部通知标记为已读
+func MarkAllNotificationsRead(userID int64) error {
+	n, err := mysql.MarkAllNotificationsRead(userID)
+	if err != nil {
+		zap.L().Error("mysql.MarkAllNotificationsRead failed", zap.Error(err))
+		return err
+	}
+	zap.L().Debug("通知全部标记为已读", zap.Int64("userID", userID), zap.Int64("num", n))
+	return nil
+}
+
+// GetNotificationPreferences 获取用户的通知设置，没有修改过的类型默认开启
+func GetNotificationPreferences(userID int64) ([]*models.NotificationPreference, error) {
+	changed, err := mysql.GetNotificationPreferences(userID)
+	if err != nil {
+		zap.L().Error("mysql.GetNotificationPreferences failed", zap.Error(err))
+		return nil, err
+	}
+	enabled := make(map[int8]bool, len(changed))
+	for _, p := range changed {
+		enabled[p.Type] = p.Enabled
+	}
+	prefs := make([]*models.NotificationPreference, 0, len(models.NotificationTypes))
+	for typ := int8(1); int(typ) <= len(models.NotificationTypes); typ++ {
+		on, ok := enabled[typ]
+		prefs = append(prefs, &models.NotificationPreference{
+			Type:    typ,
+			Name:    models.NotificationTypes[typ],
+			Enabled: !ok || on,
+		})
+	}
+	return prefs, nil
+}
+
+// SetNotificationPreference 开启或关闭一种通知
+func SetNotificationPreference(userID int64, p *models.ParamNotificationPreference) error {
+	if err := mysql.SetNotificationPreference(userID, p.Type, *p.Enabled); err != nil {
+		zap.L().Error("mysql.SetNotificationPreference failed", zap.Error(err))
+		return err
+	}
+	return nil
+}
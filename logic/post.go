@@ -5,23 +5,74 @@ import (
 	"bluebell/dao/redis"
 	"bluebell/models"
 	"bluebell/pkg/snowflake"
+	"context"
 	"errors"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"strconv"
+	"time"
 )
 
 var ErrNotPostAuthor = errors.New("not the post author")
 
 // CreatePost 创建一个帖子
 func CreatePost(c *gin.Context, p *models.Post) error {
-	// 1. 生成post id
+	// 1. 检查用户能否在社区中发帖，以及社区是否允许该类型的帖子
+	settings, err := checkCommunityWrite(p.CommunityID, p.AuthorID)
+	if err != nil {
+		return err
+	}
+	if p.PostType == "" {
+		p.PostType = models.PostTypeText
+	}
+	if !allowsPostType(settings, p.PostType) {
+		return ErrPostTypeNotAllowed
+	}
+	reviewWords, err := filterSensitive(SensitiveFieldPost, &p.Title, &p.Content)
+	if err != nil {
+		return err
+	}
+	// 检查发帖配额、重复内容和重复链接
+	spamReason, err := checkContentSpam(c, p.AuthorID, quotaActionPost, p.Title, p.Content)
+	if err != nil {
+		return err
+	}
+	reason, detail := reviewReason(reviewWords, spamReason)
+	// 2. 生成post id
 	p.ID = snowflake.GenID()
-	// 3. 帖子信息保存mysql到数据库
+	// 3. 帖子信息保存mysql到数据库，命中敏感词或疑似垃圾内容的帖子直接以隐藏状态保存，等待版主审核
+	p.Status = models.PostStatusNormal
+	if reason != "" {
+		p.Status = models.PostStatusHidden
+	}
 	if err := mysql.CreatePost(p); err != nil {
 		zap.L().Error("mysql.CreatePost failed", zap.Error(err))
 		return err
 	}
+	// 等待审核的帖子审核通过后才加入社区的热度排序，也不通知被提及的用户
+	if reason != "" {
+		holdForReview(&models.ReportCase{
+			TargetType:   models.ReportTargetPost,
+			TargetID:     p.ID,
+			CommunityID:  p.CommunityID,
+			TargetUserID: p.AuthorID,
+			Snapshot:     p.Title,
+		}, reason, detail)
+		return nil
+	}
+	// 4. 加入社区的热度排序，订阅该社区的用户首页可以看到新帖子
+	hot := computeRedditHotScore(0, 0, time.Now().Unix())
+	if err := redis.AddCommunityPost(c, p.CommunityID, p.ID, hot); err != nil {
+		zap.L().Error("redis.AddCommunityPost failed", zap.Int64("postID", p.ID), zap.Error(err))
+	}
+	// 5. 保存帖子中的@提及，失败不影响发帖；被影子封禁的用户不通知被提及的用户
+	if isShadowBanned(c, p.AuthorID) {
+		return nil
+	}
+	if err := saveMentions(models.MentionSourcePost, p.ID, p.ID, p.AuthorID, p.Content); err != nil {
+		zap.L().Error("saveMentions failed", zap.Int64("postID", p.ID), zap.Error(err))
+	}
 	return nil
 
 }
@@ -36,6 +87,33 @@ func GetPostDetail(uid, pid int64) (p *models.ApiPostDetail, err error) {
 		zap.L().Error("mysql.GetPostByID falied", zap.Error(err))
 		return
 	}
+	if err = checkCommunityRead(post.CommunityID, uid); err != nil {
+		return nil, err
+	}
+	// 被影子封禁的用户发布的帖子，只有作者自己、版主和管理员可以看到
+	if post.AuthorID != uid && isShadowBanned(context.Background(), post.AuthorID) {
+		reviewer, err := canReviewCommunity(post.CommunityID, uid)
+		if err != nil {
+			return nil, err
+		}
+		if !reviewer {
+			return nil, mysql.ErrorPostNotExist
+		}
+	}
+	switch post.Status {
+	case models.PostStatusRemoved:
+		post.Title, post.Content = postRemovedContent, postRemovedContent
+	case models.PostStatusHidden:
+		// 被举报隐藏的帖子只有版主和管理员可以看到内容
+		reviewer, err := canReviewCommunity(post.CommunityID, uid)
+		if err != nil {
+			return nil, err
+		}
+		if !reviewer {
+			post.Title, post.Content = postHiddenContent, postHiddenContent
+		}
+	}
+	fillPostMentions([]*models.Post{post})
 	// 查询作者信息
 	user := new(models.UserSafe)
 	if user, err = mysql.GetUserByID(post.AuthorID); err != nil {
@@ -50,9 +128,18 @@ func GetPostDetail(uid, pid int64) (p *models.ApiPostDetail, err error) {
 		return nil, err
 	}
 
+	// 查询当前用户的投票
+	uidStr := strconv.FormatInt(uid, 10)
+	myVotes, err := redis.GetUserVotes(context.Background(), uidStr, []string{strconv.FormatInt(pid, 10)})
+	if err != nil {
+		zap.L().Error("redis.GetUserVotes falied", zap.Error(err))
+		return nil, err
+	}
+
 	// 填充信息
 	p = &models.ApiPostDetail{
 		AuthorName:      user.Username,
+		MyVote:          myVotes[0],
 		Post:            post,
 		CommunityDetail: commDetail,
 	}
@@ -69,7 +156,7 @@ func GetPostDetail(uid, pid int64) (p *models.ApiPostDetail, err error) {
 			zap.L().Error("mysql.CreateBehavior failed", zap.Error(err))
 			return nil, err
 		}
-		return nil, nil // 返回 nil 表示没有记录
+		return p, nil
 
 	} else if err != nil {
 		zap.L().Error("mysql.CheckBehavior failed", zap.Error(err))
@@ -84,15 +171,22 @@ func GetPostDetail(uid, pid int64) (p *models.ApiPostDetail, err error) {
 		}
 	}
 
-	return
+	return p, nil
 }
 
 // 查询帖子列表（按照score/time/commid查询）
-func GetPostListByScore(c *gin.Context, p *models.ParamPostList) (apips []*models.ApiPostDetail, err error) {
+func GetPostListByScore(c *gin.Context, uid int64, p *models.ParamPostList) (apips []*models.ApiPostDetail, err error) {
 	var ps []*models.Post
 
-	// 1. 如果是根据score排序，则去redis中获取帖子id列表
-	if p.Order == "score" {
+	// 私密社区只有成员可以浏览
+	if p.Community_id != 0 {
+		if err = checkCommunityRead(p.Community_id, uid); err != nil {
+			return
+		}
+	}
+
+	// 1. 如果不是根据时间排序，则去redis中对应排序方式的zset获取帖子id列表
+	if p.Order != models.OrderTime {
 		var ids []string
 		ids, err = redis.GetPostIdsInOrder(c, p)
 		//fmt.Println(len(ids))
@@ -111,8 +205,12 @@ func GetPostListByScore(c *gin.Context, p *models.ParamPostList) (apips []*model
 			}
 		} else {
 			ps, err = mysql.GetPostsListByIdsAndComm(p.Community_id, ids)
+			if err != nil {
+				zap.L().Error("mysql.GetPostsListByIdsAndComm failed", zap.Error(err))
+				return
+			}
 		}
-	} else if p.Order == "time" {
+	} else {
 		if p.Community_id == 0 {
 			ps, err = mysql.GetPostIdsInTime(p)
 			if err != nil {
@@ -129,9 +227,58 @@ func GetPostListByScore(c *gin.Context, p *models.ParamPostList) (apips []*model
 		}
 	}
 
+	if p.Community_id != 0 {
+		ps, err = withPinnedPosts(p.Community_id, p.Offset, ps)
+	} else {
+		ps, err = filterReadablePosts(uid, ps)
+	}
+	if err != nil {
+		return
+	}
+	return buildPostDetails(c, uid, ps)
+}
+
+// withPinnedPosts 社区帖子列表的第一页最前面显示置顶的帖子，其他位置不再重复显示
+func withPinnedPosts(communityID, offset int64, ps []*models.Post) ([]*models.Post, error) {
+	list := make([]*models.Post, 0, len(ps))
+	if offset <= 1 {
+		pinned, err := mysql.GetPinnedPosts(communityID)
+		if err != nil {
+			zap.L().Error("mysql.GetPinnedPosts failed", zap.Int64("communityID", communityID), zap.Error(err))
+			return nil, err
+		}
+		list = append(list, pinned...)
+	}
+	for _, post := range ps {
+		if !post.Pinned {
+			list = append(list, post)
+		}
+	}
+	return list, nil
+}
+
+// buildPostDetails 去掉被影子封禁的用户发布的帖子，为帖子列表填充@提及、票数、当前用户的投票、作者和社区信息
+func buildPostDetails(c *gin.Context, uid int64, ps []*models.Post) (apips []*models.ApiPostDetail, err error) {
+	ps = filterShadowBannedPosts(c, uid, ps)
+	fillPostMentions(ps)
+
 	// 查询帖子赞成票的数量
 	var vs []int64
 	vs, err = redis.GetPostVoteData(c, ps)
+	if err != nil {
+		zap.L().Error("redis.GetPostVoteData failed", zap.Error(err))
+		return
+	}
+	// 查询当前用户对这些帖子的投票
+	pids := make([]string, len(ps))
+	for i, post := range ps {
+		pids[i] = strconv.FormatInt(post.ID, 10)
+	}
+	var myVotes []int8
+	if myVotes, err = redis.GetUserVotes(c, strconv.FormatInt(uid, 10), pids); err != nil {
+		zap.L().Error("redis.GetUserVotes failed", zap.Error(err))
+		return
+	}
 
 	// 3. 填充帖子的作者和分区信息
 	for idx, post := range ps {
@@ -151,6 +298,7 @@ func GetPostListByScore(c *gin.Context, p *models.ParamPostList) (apips []*model
 		p := &models.ApiPostDetail{
 			AuthorName:      user.Username,
 			VoteNum:         vs[idx],
+			MyVote:          myVotes[idx],
 			Post:            post,
 			CommunityDetail: commDetail,
 		}
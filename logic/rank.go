@@ -0,0 +1,155 @@
+package logic
+
+import (
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"context"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"math"
+	"time"
+)
+
+// 帖子排序算法
+/*
+	除按时间排序外，每种排序方式在 redis 中各自维护一个 zset：
+	1. score:         Reddit 热度，票数取对数再加上发帖时间
+	2. top:           按天/周/月划分时间窗口，窗口内的帖子按净赞成票排序
+	3. controversial: 赞成反对票越接近、总票数越多越靠前
+	4. best:          Wilson 置信区间下界，票数少的帖子不会因为偶然的好评排到前面
+	5. gravity:       Hacker News 重力衰减，分数随发帖时间增长而下降
+	算法参数在 config.yaml 的 rank 下配置
+*/
+
+// TopWindows top 排序的时间窗口
+var TopWindows = map[string]time.Duration{
+	models.WindowDay:   24 * time.Hour,
+	models.WindowWeek:  7 * 24 * time.Hour,
+	models.WindowMonth: 30 * 24 * time.Hour,
+}
+
+// rankParam 读取排序算法参数，未配置时使用默认值
+func rankParam(key string, def float64) float64 {
+	if !viper.IsSet("rank." + key) {
+		return def
+	}
+	return viper.GetFloat64("rank." + key)
+}
+
+// 计算 Reddit 热度
+func computeRedditHotScore(ups, downs, postTime int64) float64 {
+	score := math.Max(float64(ups-downs), 1)
+	order := math.Log10(score)
+	//seconds := float64(postTime - 1134028003) // Reddit 基准时间
+	seconds := float64(postTime) - rankParam("hot_epoch", 1735689600) // bluebell发布的基准时间（2025-01-01）
+	return order + seconds/rankParam("hot_decay", 45000)              // 约12.5小时热度加一
+}
+
+// 计算争议度：总票数为底，赞成反对票之比为指数
+func computeControversialScore(ups, downs int64) float64 {
+	if ups <= 0 || downs <= 0 {
+		return 0
+	}
+	magnitude := float64(ups + downs)
+	balance := float64(downs) / float64(ups)
+	if ups < downs {
+		balance = float64(ups) / float64(downs)
+	}
+	return math.Pow(magnitude, balance)
+}
+
+// 计算 Wilson 置信区间下界
+func computeWilsonScore(ups, downs int64) float64 {
+	n := float64(ups + downs)
+	if n <= 0 {
+		return 0
+	}
+	z := rankParam("wilson_z", 1.96)
+	p := float64(ups) / n
+	left := p + z*z/(2*n)
+	right := z * math.Sqrt((p*(1-p)+z*z/(4*n))/n)
+	return (left - right) / (1 + z*z/n)
+}
+
+// 计算 Hacker News 重力衰减分数
+func computeGravityScore(ups, downs, postTime, now int64) float64 {
+	points := float64(ups - downs)
+	ageHours := math.Max(float64(now-postTime), 0) / 3600
+	offset := rankParam("gravity_offset_hours", 2)
+	return points / math.Pow(ageHours+offset, rankParam("gravity", 1.8))
+}
+
+// postTopWindows 帖子仍处于的 top 时间窗口
+// 只有仍处于时间窗口内的帖子才参与该窗口的 top 排序
+func postTopWindows(postTime, now int64) []string {
+	windows := make([]string, 0, len(TopWindows))
+	for window, d := range TopWindows {
+		if now-postTime <= int64(d.Seconds()) {
+			windows = append(windows, window)
+		}
+	}
+	return windows
+}
+
+// computeRankScores 计算帖子在各排序方式下的分数
+func computeRankScores(ups, downs, postTime, now int64) *models.PostRankScores {
+	return &models.PostRankScores{
+		Hot:           computeRedditHotScore(ups, downs, postTime),
+		Controversial: computeControversialScore(ups, downs),
+		Best:          computeWilsonScore(ups, downs),
+		Gravity:       computeGravityScore(ups, downs, postTime, now),
+		Net:           float64(ups - downs),
+		TopWindows:    postTopWindows(postTime, now),
+	}
+}
+
+// rankParams 投票脚本在 redis 中计算排序分数所需的参数
+func rankParams(postTime, now int64) *models.RankParams {
+	return &models.RankParams{
+		HotEpoch:      rankParam("hot_epoch", 1735689600),
+		HotDecay:      rankParam("hot_decay", 45000),
+		WilsonZ:       rankParam("wilson_z", 1.96),
+		Gravity:       rankParam("gravity", 1.8),
+		GravityOffset: rankParam("gravity_offset_hours", 2),
+		Now:           now,
+		TopWindows:    postTopWindows(postTime, now),
+	}
+}
+
+// RefreshRankScores 刷新随时间变化的排序分数
+// 1. 将超出时间窗口的帖子移出 top 排序
+// 2. 重新计算重力衰减分数，没有新投票的帖子也会随时间下沉
+func RefreshRankScores() {
+	c := context.Background()
+	now := time.Now().Unix()
+
+	for window, d := range TopWindows {
+		removed, err := redis.PruneTopWindow(c, window, now-int64(d.Seconds()))
+		if err != nil {
+			zap.L().Error("redis.PruneTopWindow failed", zap.String("window", window), zap.Error(err))
+			continue
+		}
+		zap.L().Debug("top 时间窗口清理完成", zap.String("window", window), zap.Int64("removed", removed))
+	}
+
+	maxAge := int64(rankParam("gravity_max_age_hours", 720) * 3600)
+	posts, err := redis.GetGravityPosts(c)
+	if err != nil {
+		zap.L().Error("redis.GetGravityPosts failed", zap.Error(err))
+		return
+	}
+	for _, post := range posts {
+		// 太久的帖子分数已趋近于 0，直接移出重力排序
+		if now-post.CreateTime > maxAge {
+			if err := redis.RemoveGravityPost(c, post.PostID); err != nil {
+				zap.L().Error("redis.RemoveGravityPost failed", zap.String("post_id", post.PostID), zap.Error(err))
+			}
+			continue
+		}
+		score := computeGravityScore(post.Ups, post.Downs, post.CreateTime, now)
+		if err := redis.UpdateGravityScore(c, post.PostID, score); err != nil {
+			zap.L().Error("redis.UpdateGravityScore failed", zap.String("post_id", post.PostID), zap.Error(err))
+		}
+	}
+	zap.L().Info("排序分数刷新完成", zap.Int("gravityNum", len(posts)))
+}
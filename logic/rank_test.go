@@ -0,0 +1,158 @@
+package logic
+
+import (
+	"bluebell/models"
+	"math"
+	"sort"
+	"testing"
+)
+
+const floatTolerance = 1e-9
+
+func floatEqual(a, b float64) bool {
+	return math.Abs(a-b) < floatTolerance
+}
+
+func TestComputeRedditHotScore(t *testing.T) {
+	const epoch = 1735689600
+	tests := []struct {
+		name     string
+		ups      int64
+		downs    int64
+		postTime int64
+		want     float64
+	}{
+		{"no votes at epoch", 0, 0, epoch, 0},
+		{"net votes below one", 1, 5, epoch, 0},
+		{"hundred net votes", 100, 0, epoch, 2},
+		{"one decay period later", 10, 0, epoch + 45000, 2},
+		{"before epoch", 0, 0, epoch - 90000, -2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := computeRedditHotScore(tt.ups, tt.downs, tt.postTime); !floatEqual(got, tt.want) {
+				t.Errorf("computeRedditHotScore(%d, %d, %d) = %v, want %v", tt.ups, tt.downs, tt.postTime, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeControversialScore(t *testing.T) {
+	tests := []struct {
+		ups   int64
+		downs int64
+		want  float64
+	}{
+		{0, 0, 0},
+		{10, 0, 0},
+		{0, 10, 0},
+		{5, 5, 10},
+		{10, 5, math.Sqrt(15)},
+		{5, 10, math.Sqrt(15)},
+	}
+	for _, tt := range tests {
+		if got := computeControversialScore(tt.ups, tt.downs); !floatEqual(got, tt.want) {
+			t.Errorf("computeControversialScore(%d, %d) = %v, want %v", tt.ups, tt.downs, got, tt.want)
+		}
+	}
+}
+
+func TestComputeWilsonScore(t *testing.T) {
+	tests := []struct {
+		ups   int64
+		downs int64
+		want  float64
+	}{
+		{0, 0, 0},
+		{1, 0, 1 / (1 + 1.96*1.96)},
+		{0, 1, 0},
+	}
+	for _, tt := range tests {
+		if got := computeWilsonScore(tt.ups, tt.downs); !floatEqual(got, tt.want) {
+			t.Errorf("computeWilsonScore(%d, %d) = %v, want %v", tt.ups, tt.downs, got, tt.want)
+		}
+	}
+	// 票数多的帖子置信度更高，同样的好评率排在前面
+	if few, many := computeWilsonScore(9, 1), computeWilsonScore(90, 10); few >= many {
+		t.Errorf("computeWilsonScore(9, 1) = %v, want less than computeWilsonScore(90, 10) = %v", few, many)
+	}
+}
+
+func TestComputeGravityScore(t *testing.T) {
+	const now = 1735689600
+	tests := []struct {
+		name     string
+		ups      int64
+		downs    int64
+		postTime int64
+		want     float64
+	}{
+		{"new post", 10, 0, now, 10 / math.Pow(2, 1.8)},
+		{"two hours old", 10, 0, now - 7200, 10 / math.Pow(4, 1.8)},
+		{"negative points", 0, 4, now - 7200, -4 / math.Pow(4, 1.8)},
+		{"future post time", 10, 0, now + 3600, 10 / math.Pow(2, 1.8)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := computeGravityScore(tt.ups, tt.downs, tt.postTime, now); !floatEqual(got, tt.want) {
+				t.Errorf("computeGravityScore(%d, %d, %d, %d) = %v, want %v", tt.ups, tt.downs, tt.postTime, now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostTopWindows(t *testing.T) {
+	const (
+		now = 1735689600
+		day = 24 * 3600
+	)
+	tests := []struct {
+		name string
+		age  int64
+		want []string
+	}{
+		{"just posted", 0, []string{models.WindowDay, models.WindowMonth, models.WindowWeek}},
+		{"exactly one day", day, []string{models.WindowDay, models.WindowMonth, models.WindowWeek}},
+		{"two days", 2 * day, []string{models.WindowMonth, models.WindowWeek}},
+		{"ten days", 10 * day, []string{models.WindowMonth}},
+		{"forty days", 40 * day, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := postTopWindows(now-tt.age, now)
+			sort.Strings(got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("postTopWindows = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("postTopWindows = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestComputeRankScores(t *testing.T) {
+	const now = 1735689600 + 3600
+	postTime := int64(now - 7200)
+	got := computeRankScores(30, 10, postTime, now)
+	if !floatEqual(got.Hot, computeRedditHotScore(30, 10, postTime)) {
+		t.Errorf("Hot = %v", got.Hot)
+	}
+	if !floatEqual(got.Controversial, computeControversialScore(30, 10)) {
+		t.Errorf("Controversial = %v", got.Controversial)
+	}
+	if !floatEqual(got.Best, computeWilsonScore(30, 10)) {
+		t.Errorf("Best = %v", got.Best)
+	}
+	if !floatEqual(got.Gravity, computeGravityScore(30, 10, postTime, now)) {
+		t.Errorf("Gravity = %v", got.Gravity)
+	}
+	if got.Net != 20 {
+		t.Errorf("Net = %v, want 20", got.Net)
+	}
+	if len(got.TopWindows) != len(TopWindows) {
+		t.Errorf("TopWindows = %v, want all windows", got.TopWindows)
+	}
+}
@@ -0,0 +1,205 @@
+package logic
+
+import (
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// 实时推送
+/*
+	1. 产生事件的实例把事件发布到 redis 频道：用户频道推送通知，帖子频道推送新评论和票数变化
+	2. 每个实例用一个订阅连接接收事件，本实例上有客户端关注某个频道时才订阅该频道
+	3. 收到的事件分发给本实例上关注该频道的客户端，客户端处理不过来时丢弃事件，不阻塞其他客户端
+	4. 浏览器的 EventSource 不能设置请求头，客户端先用 Token 换取一次性凭证，再把凭证放在 URI 中建立事件流，
+	   Token 不会出现在 URI 和访问日志中
+*/
+
+var (
+	ErrRealtimeNotStarted  = errors.New("实时推送未启动")
+	ErrInvalidStreamTicket = errors.New("凭证无效或已过期")
+)
+
+const (
+	realtimeBufferSize = 64               // 每个客户端缓存的事件数量
+	streamTicketTTL    = 60 * time.Second // 一次性凭证的有效期
+)
+
+// CreateStreamTicket 为用户生成建立事件流的一次性凭证
+func CreateStreamTicket(c context.Context, userID int64) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	ticket := hex.EncodeToString(b)
+	if err := redis.SaveStreamTicket(c, ticket, userID, streamTicketTTL); err != nil {
+		zap.L().Error("redis.SaveStreamTicket failed", zap.Int64("userID", userID), zap.Error(err))
+		return "", err
+	}
+	return ticket, nil
+}
+
+// RedeemStreamTicket 使用一次性凭证，返回对应的用户id；凭证使用后立即失效
+func RedeemStreamTicket(c context.Context, ticket string) (int64, error) {
+	userID, ok, err := redis.TakeStreamTicket(c, ticket)
+	if err != nil {
+		zap.L().Error("redis.TakeStreamTicket failed", zap.Error(err))
+		return 0, err
+	}
+	if !ok {
+		return 0, ErrInvalidStreamTicket
+	}
+	return userID, nil
+}
+
+type realtimeHub struct {
+	mu sync.Mutex
+	ps interface {
+		Subscribe(ctx context.Context, channels ...string) error
+		Unsubscribe(ctx context.Context, channels ...string) error
+		Close() error
+	}
+	subs   map[string]map[chan *models.RealtimeEvent]struct{} // 频道 -> 关注该频道的客户端
+	closed bool
+}
+
+var hub = &realtimeHub{subs: make(map[string]map[chan *models.RealtimeEvent]struct{})}
+
+// StartRealtimeHub 创建订阅连接并开始分发事件
+func StartRealtimeHub() {
+	c := context.Background()
+	ps := redis.NewRealtimeSubscriber(c)
+	hub.mu.Lock()
+	hub.ps = ps
+	hub.mu.Unlock()
+
+	go func() {
+		for msg := range ps.Channel() {
+			ev := new(models.RealtimeEvent)
+			if err := json.Unmarshal([]byte(msg.Payload), ev); err != nil {
+				zap.L().Error("unmarshal realtime event failed", zap.String("channel", msg.Channel), zap.Error(err))
+				continue
+			}
+			hub.dispatch(msg.Channel, ev)
+		}
+	}()
+}
+
+// StopRealtimeHub 关闭订阅连接并结束全部客户端的事件流
+func StopRealtimeHub() {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if hub.ps == nil || hub.closed {
+		return
+	}
+	hub.closed = true
+	_ = hub.ps.Close()
+	closed := make(map[chan *models.RealtimeEvent]struct{})
+	for _, clients := range hub.subs {
+		for ch := range clients {
+			if _, ok := closed[ch]; !ok {
+				close(ch)
+				closed[ch] = struct{}{}
+			}
+		}
+	}
+	hub.subs = make(map[string]map[chan *models.RealtimeEvent]struct{})
+}
+
+func (h *realtimeHub) dispatch(channel string, ev *models.RealtimeEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[channel] {
+		select {
+		case ch <- ev:
+		default:
+			zap.L().Warn("客户端处理不过来，丢弃实时事件", zap.String("channel", channel), zap.String("type", ev.Type))
+		}
+	}
+}
+
+// SubscribeRealtime 关注一组频道，返回接收事件的通道和取消关注的函数
+// 实时推送停止时通道会被关闭
+func SubscribeRealtime(c context.Context, channels ...string) (<-chan *models.RealtimeEvent, func(), error) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if hub.ps == nil || hub.closed {
+		return nil, nil, ErrRealtimeNotStarted
+	}
+
+	// 本实例上第一个关注某个频道的客户端出现时才订阅
+	newChannels := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		if len(hub.subs[channel]) == 0 {
+			newChannels = append(newChannels, channel)
+		}
+	}
+	if len(newChannels) > 0 {
+		if err := hub.ps.Subscribe(c, newChannels...); err != nil {
+			return nil, nil, err
+		}
+	}
+	ch := make(chan *models.RealtimeEvent, realtimeBufferSize)
+	for _, channel := range channels {
+		if hub.subs[channel] == nil {
+			hub.subs[channel] = make(map[chan *models.RealtimeEvent]struct{})
+		}
+		hub.subs[channel][ch] = struct{}{}
+	}
+
+	cancel := func() {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		if hub.closed {
+			return
+		}
+		// 最后一个关注某个频道的客户端离开时退订
+		unused := make([]string, 0, len(channels))
+		for _, channel := range channels {
+			delete(hub.subs[channel], ch)
+			if len(hub.subs[channel]) == 0 {
+				delete(hub.subs, channel)
+				unused = append(unused, channel)
+			}
+		}
+		if len(unused) > 0 {
+			if err := hub.ps.Unsubscribe(context.Background(), unused...); err != nil {
+				zap.L().Error("unsubscribe realtime channels failed", zap.Error(err))
+			}
+		}
+	}
+	return ch, cancel, nil
+}
+
+// SubscribeUserEvents 关注发给用户的事件，postID 不为 0 时同时关注该帖子上的事件
+func SubscribeUserEvents(c context.Context, userID, postID int64) (<-chan *models.RealtimeEvent, func(), error) {
+	channels := []string{redis.RealtimeUserChannel(userID)}
+	if postID != 0 {
+		if err := checkPostRead(postID, userID); err != nil {
+			return nil, nil, err
+		}
+		channels = append(channels, redis.RealtimePostChannel(postID))
+	}
+	return SubscribeRealtime(c, channels...)
+}
+
+// publishUserEvent 向用户推送事件，失败只记录日志
+func publishUserEvent(userID int64, typ string, data interface{}) {
+	if err := redis.PublishRealtime(context.Background(), redis.RealtimeUserChannel(userID), typ, data); err != nil {
+		zap.L().Error("redis.PublishRealtime failed", zap.Int64("userID", userID), zap.String("type", typ), zap.Error(err))
+	}
+}
+
+// publishPostEvent 向正在浏览帖子的用户推送事件，失败只记录日志
+func publishPostEvent(postID int64, typ string, data interface{}) {
+	if err := redis.PublishRealtime(context.Background(), redis.RealtimePostChannel(postID), typ, data); err != nil {
+		zap.L().Error("redis.PublishRealtime failed", zap.Int64("postID", postID), zap.String("type", typ), zap.Error(err))
+	}
+}
@@ -0,0 +1,166 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"context"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"strconv"
+	"time"
+)
+
+// 冷启动重建 redis 排序数据
+/*
+	redis 数据丢失后，按 post_id 顺序分批从 MySQL 读取帖子：
+	1. 发帖时间写回 post:time
+	2. 投票从 post_vote 表合并到 post:voted:<post_id>，不删除 redis 中已有的投票，票数以合并后的投票记录为准，
+	   没有投票记录的旧帖子使用同步到 post 表的 likes/dislikes；被移除和隐藏的帖子不写回排序
+	3. 根据票数重新计算各排序分数，热度优先使用 post_hot_scores 中同步过的值，同时写回帖子所在社区的热度zset
+	每批完成后记录处理到的 post_id，中断后可以从这里继续
+*/
+
+// RebuildRedisRanking 从 MySQL 重建 redis 中的帖子排序数据，返回本次处理的帖子数量
+// fromScratch 为 false 时从上次中断的位置继续
+func RebuildRedisRanking(fromScratch bool) (int, error) {
+	c := context.Background()
+	var afterID int64
+	if !fromScratch {
+		var err error
+		if afterID, err = redis.GetRebuildCheckpoint(c); err != nil {
+			zap.L().Error("redis.GetRebuildCheckpoint failed", zap.Error(err))
+			return 0, err
+		}
+		if afterID > 0 {
+			zap.L().Info("从上次中断的位置继续重建", zap.Int64("afterID", afterID))
+		}
+	}
+
+	batchSize := viper.GetInt("redis.rebuild_batch_size")
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	total := 0
+	for {
+		rows, err := mysql.GetPostsForRebuild(afterID, batchSize)
+		if err != nil {
+			zap.L().Error("mysql.GetPostsForRebuild failed", zap.Int64("afterID", afterID), zap.Error(err))
+			return total, err
+		}
+		if len(rows) == 0 {
+			break
+		}
+		states, err := buildRankStates(c, rows)
+		if err != nil {
+			return total, err
+		}
+		if err := redis.RestorePostRanking(c, states); err != nil {
+			zap.L().Error("redis.RestorePostRanking failed", zap.Int64("afterID", afterID), zap.Error(err))
+			return total, err
+		}
+		afterID = rows[len(rows)-1].PostID
+		total += len(rows)
+		if err := redis.SetRebuildCheckpoint(c, afterID); err != nil {
+			zap.L().Warn("redis.SetRebuildCheckpoint failed", zap.Error(err))
+		}
+		zap.L().Debug("重建排序数据", zap.Int64("afterID", afterID), zap.Int("total", total))
+		if len(rows) < batchSize {
+			break
+		}
+	}
+
+	if err := redis.ClearRebuildCheckpoint(c); err != nil {
+		zap.L().Warn("redis.ClearRebuildCheckpoint failed", zap.Error(err))
+	}
+	if err := redis.SetCommunityScoreDone(c); err != nil {
+		zap.L().Warn("redis.SetCommunityScoreDone failed", zap.Error(err))
+	}
+	zap.L().Info("重建排序数据完成", zap.Int("total", total))
+	return total, nil
+}
+
+// buildRankStates 根据一批帖子及其投票记录计算 redis 中的排序数据
+func buildRankStates(c context.Context, rows []*models.PostRankRow) ([]*models.PostRankState, error) {
+	ids := make([]int64, len(rows))
+	for i, row := range rows {
+		ids[i] = row.PostID
+	}
+	votes, err := mysql.GetPostVotesByPostIDs(ids)
+	if err != nil {
+		zap.L().Error("mysql.GetPostVotesByPostIDs failed", zap.Error(err))
+		return nil, err
+	}
+	voters := make(map[int64]map[string]int8)
+	for _, v := range votes {
+		if voters[v.PostID] == nil {
+			voters[v.PostID] = make(map[string]int8)
+		}
+		voters[v.PostID][strconv.FormatInt(v.UserID, 10)] = v.Direction
+	}
+
+	now := time.Now().Unix()
+	states := make([]*models.PostRankState, len(rows))
+	for i, row := range rows {
+		state := &models.PostRankState{
+			PostID:      strconv.FormatInt(row.PostID, 10),
+			CommunityID: strconv.FormatInt(row.CommunityID, 10),
+			CreateTime:  row.CreateTime.Unix(),
+			Ups:         row.Likes,
+			Downs:       row.Dislikes,
+			Voters:      voters[row.PostID],
+		}
+		states[i] = state
+	}
+	// 合并到 redis 中已有的投票，票数以合并后的投票记录为准
+	if err := redis.MergePostVoters(c, states); err != nil {
+		zap.L().Error("redis.MergePostVoters failed", zap.Error(err))
+		return nil, err
+	}
+	for i, state := range states {
+		state.Scores = computeRankScores(state.Ups, state.Downs, state.CreateTime, now)
+		if rows[i].HotScore.Valid && !state.Voted {
+			state.Scores.Hot = rows[i].HotScore.Float64
+		}
+	}
+	return states, nil
+}
+
+// CheckRedisRanking 启动时检查 redis 中的排序数据，丢失时从 MySQL 重建
+// 社区热度zset没有包含已有帖子时（由没有社区热度的版本升级）也重建一次，否则首页看不到这些帖子；
+// 重建只合并投票，不影响 redis 中已有的数据
+func CheckRedisRanking() {
+	c := context.Background()
+	count, err := redis.CountPostTime(c)
+	if err != nil {
+		zap.L().Error("redis.CountPostTime failed", zap.Error(err))
+		return
+	}
+	checkpoint, err := redis.GetRebuildCheckpoint(c)
+	if err != nil {
+		zap.L().Error("redis.GetRebuildCheckpoint failed", zap.Error(err))
+		return
+	}
+	done, err := redis.IsCommunityScoreDone(c)
+	if err != nil {
+		zap.L().Error("redis.IsCommunityScoreDone failed", zap.Error(err))
+		return
+	}
+	switch {
+	case count > 0 && checkpoint == 0 && done:
+		// 排序数据完整且没有未完成的重建
+		return
+	case count > 0 && checkpoint == 0:
+		zap.L().Warn("社区热度数据不完整，开始从 MySQL 补全", zap.Int64("postTimeCount", count))
+	default:
+		if !viper.GetBool("redis.rebuild_on_start") {
+			return
+		}
+		zap.L().Warn("redis 排序数据缺失，开始从 MySQL 重建", zap.Int64("postTimeCount", count), zap.Int64("checkpoint", checkpoint))
+	}
+	go func() {
+		if _, err := RebuildRedisRanking(false); err != nil {
+			zap.L().Error("RebuildRedisRanking failed", zap.Error(err))
+		}
+	}()
+}
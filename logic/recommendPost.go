@@ -88,7 +88,11 @@ func RecommendArticles(userID int64) ([]*models.Post, error) {
 	if len(behaviors) == 0 {
 		zap.L().Warn("behaviors are empty", zap.Error(err))
 		// 获取全部帖子
-		return mysql.GetPostList(1, 10)
+		posts, err := mysql.GetPostList(1, 10)
+		if err != nil {
+			return nil, err
+		}
+		return filterReadablePosts(userID, posts)
 	}
 
 	// 构建用户-文章评分矩阵：userItemMatrix[userID][articleID] = rate
@@ -129,6 +133,7 @@ func RecommendArticles(userID int64) ([]*models.Post, error) {
 		zap.L().Error("mysql.GetPostsListByInt64Ids", zap.Error(err))
 		return nil, err
 	}
-	return postss, nil
+	// 不推荐用户不能浏览的私密社区中的帖子
+	return filterReadablePosts(userID, postss)
 
 }
@@ -0,0 +1,302 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"unicode/utf8"
+)
+
+// 举报和审核队列
+/*
+	1. 用户可以举报帖子、评论和用户；同一对象的待处理举报合并为一个举报单，同一用户的重复举报只更新理由
+	2. 帖子和评论的举报单进入所在社区版主的审核队列，用户的举报单只有站点管理员可以处理，管理员可以查看全站队列
+	3. 举报人数达到 report.hide_threshold 时自动隐藏帖子或评论，直到版主处理
+	4. 版主处理举报单：举报成立时移除内容，不成立时恢复被隐藏的内容；处理结果和备注记录在举报单上
+	5. 举报单处理后对象可以再次被举报，产生新的举报单
+*/
+
+var (
+	ErrReportSelf     = errors.New("不能举报自己")
+	ErrReportNotExist = errors.New("举报单不存在")
+	ErrReportClosed   = errors.New("举报单已处理")
+)
+
+// reportSnapshotLen 举报单保存的内容快照的最大长度
+const reportSnapshotLen = 200
+
+// truncateSnapshot 截取内容快照
+func truncateSnapshot(s string) string {
+	if utf8.RuneCountInString(s) <= reportSnapshotLen {
+		return s
+	}
+	return string([]rune(s)[:reportSnapshotLen])
+}
+
+// reportTarget 查询被举报的对象，返回对应的举报单
+func reportTarget(userID int64, p *models.ParamReport) (*models.ReportCase, error) {
+	rc := &models.ReportCase{TargetType: p.TargetType, TargetID: p.TargetID}
+	switch p.TargetType {
+	case models.ReportTargetPost:
+		post, err := getPost(p.TargetID)
+		if err != nil {
+			return nil, err
+		}
+		if post.Status == models.PostStatusRemoved {
+			return nil, ErrPostRemoved
+		}
+		if err := checkCommunityRead(post.CommunityID, userID); err != nil {
+			return nil, err
+		}
+		rc.CommunityID, rc.TargetUserID, rc.Snapshot = post.CommunityID, post.AuthorID, post.Title
+	case models.ReportTargetComment:
+		comment, err := mysql.GetCommentByID(p.TargetID)
+		if err != nil {
+			if !errors.Is(err, mysql.ErrorCommentNotExist) {
+				zap.L().Error("mysql.GetCommentByID failed", zap.Int64("commentID", p.TargetID), zap.Error(err))
+			}
+			return nil, err
+		}
+		if comment.Status != models.CommentStatusNormal && comment.Status != models.CommentStatusHidden {
+			return nil, ErrCommentDeleted
+		}
+		post, err := getPost(comment.PostID)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkCommunityRead(post.CommunityID, userID); err != nil {
+			return nil, err
+		}
+		rc.CommunityID, rc.TargetUserID, rc.Snapshot = post.CommunityID, comment.UserID, comment.Content
+	case models.ReportTargetUser:
+		user, err := mysql.GetUserByID(p.TargetID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, mysql.ErrorUserNotExist
+			}
+			zap.L().Error("mysql.GetUserByID failed", zap.Error(err))
+			return nil, err
+		}
+		rc.TargetUserID, rc.Snapshot = user.UserID, user.Username
+	}
+	if rc.TargetUserID == userID {
+		return nil, ErrReportSelf
+	}
+	rc.Snapshot = truncateSnapshot(rc.Snapshot)
+	return rc, nil
+}
+
+// SubmitReport 举报帖子、评论或用户，举报人数达到上限时自动隐藏帖子或评论
+func SubmitReport(userID int64, p *models.ParamReport) error {
+	rc, err := reportTarget(userID, p)
+	if err != nil {
+		return err
+	}
+	caseID, count, added, err := mysql.AddReport(rc, &models.Report{
+		ReporterID: userID,
+		Reason:     p.Reason,
+		Detail:     p.Detail,
+	})
+	if err != nil {
+		zap.L().Error("mysql.AddReport failed", zap.Error(err))
+		return err
+	}
+	threshold := int64(5)
+	if viper.IsSet("report.hide_threshold") {
+		threshold = viper.GetInt64("report.hide_threshold")
+	}
+	if !added || count < threshold || p.TargetType == models.ReportTargetUser {
+		return nil
+	}
+
+	var hidden bool
+	if p.TargetType == models.ReportTargetPost {
+		hidden, err = mysql.ChangePostStatus(p.TargetID, models.PostStatusNormal, models.PostStatusHidden)
+	} else {
+		hidden, err = mysql.ChangeCommentStatus(p.TargetID, models.CommentStatusNormal, models.CommentStatusHidden)
+	}
+	if err != nil {
+		zap.L().Error("hide reported content failed", zap.String("type", p.TargetType), zap.Int64("id", p.TargetID), zap.Error(err))
+		return err
+	}
+	if hidden {
+		zap.L().Info("举报人数达到上限，自动隐藏内容",
+			zap.String("type", p.TargetType), zap.Int64("id", p.TargetID), zap.Int64("reports", count))
+		if err := mysql.SetReportCaseHidden(caseID); err != nil {
+			zap.L().Error("mysql.SetReportCaseHidden failed", zap.Int64("caseID", caseID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// GetReportQueue 分页获取审核队列，指定社区时版主可以查看，不指定时只有管理员可以查看全站
+func GetReportQueue(userID int64, p *models.ParamReportList) ([]*models.ReportCase, error) {
+	ok, err := canReviewCommunity(p.CommunityID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotModerator
+	}
+	if p.Offset <= 0 {
+		p.Offset = 1
+	}
+	if p.Limit <= 0 {
+		p.Limit = 20
+	}
+	cases, err := mysql.GetReportCases(p.CommunityID, p.Status, p.Offset, p.Limit)
+	if err != nil {
+		zap.L().Error("mysql.GetReportCases failed", zap.Error(err))
+	}
+	return cases, err
+}
+
+// getReviewableCase 查询举报单，并检查用户能否处理
+func getReviewableCase(caseID, userID int64) (*models.ReportCase, error) {
+	rc, err := mysql.GetReportCase(caseID)
+	if err != nil {
+		zap.L().Error("mysql.GetReportCase failed", zap.Int64("caseID", caseID), zap.Error(err))
+		return nil, err
+	}
+	if rc == nil {
+		return nil, ErrReportNotExist
+	}
+	ok, err := canReviewCommunity(rc.CommunityID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotModerator
+	}
+	return rc, nil
+}
+
+// GetReportCase 获取举报单及其中的全部举报
+func GetReportCase(caseID, userID int64) (*models.ReportCase, error) {
+	rc, err := getReviewableCase(caseID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if rc.Reports, err = mysql.GetReports(caseID); err != nil {
+		zap.L().Error("mysql.GetReports failed", zap.Int64("caseID", caseID), zap.Error(err))
+		return nil, err
+	}
+	return rc, nil
+}
+
+// ResolveReport 举报成立：移除被举报的帖子或评论，用户的举报只记录处理结果
+func ResolveReport(c context.Context, caseID, userID int64, note string) error {
+	rc, err := getReviewableCase(caseID, userID)
+	if err != nil {
+		return err
+	}
+	if rc.Status != models.ReportCaseOpen {
+		return ErrReportClosed
+	}
+	switch rc.TargetType {
+	case models.ReportTargetPost:
+		post, err := getPost(rc.TargetID)
+		if err != nil {
+			return err
+		}
+		if err := removePost(c, post, userID, note); err != nil {
+			return err
+		}
+	case models.ReportTargetComment:
+		if err := removeReportedComment(rc.TargetID, userID, note); err != nil {
+			return err
+		}
+	}
+	return closeReportCase(rc, models.ReportCaseResolved, userID, note)
+}
+
+// removeReportedComment 移除被举报的评论及其全部回复，记录管理日志
+func removeReportedComment(commentID, userID int64, reason string) error {
+	comment, err := mysql.GetCommentByID(commentID)
+	if err != nil {
+		zap.L().Error("mysql.GetCommentByID failed", zap.Int64("commentID", commentID), zap.Error(err))
+		return err
+	}
+	post, err := getPost(comment.PostID)
+	if err != nil {
+		return err
+	}
+	// 被隐藏的评论不在 RemoveCommentSubtree 的范围内，先单独移除
+	if _, err := mysql.ChangeCommentStatus(commentID, models.CommentStatusHidden, models.CommentStatusRemoved); err != nil {
+		zap.L().Error("mysql.ChangeCommentStatus failed", zap.Error(err))
+		return err
+	}
+	if _, err := mysql.RemoveCommentSubtree(comment.PostID, comment.Path); err != nil {
+		zap.L().Error("mysql.RemoveCommentSubtree failed", zap.Error(err))
+		return err
+	}
+	logModeration(&models.ModerationLog{
+		CommunityID:  post.CommunityID,
+		ModeratorID:  userID,
+		Action:       models.ModActionRemoveComment,
+		TargetUserID: comment.UserID,
+		PostID:       comment.PostID,
+		CommentID:    commentID,
+		Reason:       reason,
+	})
+	return nil
+}
+
+// DismissReport 举报不成立：恢复被自动隐藏的帖子或评论
+func DismissReport(caseID, userID int64, note string) error {
+	rc, err := getReviewableCase(caseID, userID)
+	if err != nil {
+		return err
+	}
+	if rc.Status != models.ReportCaseOpen {
+		return ErrReportClosed
+	}
+	if rc.Hidden {
+		switch rc.TargetType {
+		case models.ReportTargetPost:
+			_, err = mysql.ChangePostStatus(rc.TargetID, models.PostStatusHidden, models.PostStatusNormal)
+		case models.ReportTargetComment:
+			_, err = mysql.ChangeCommentStatus(rc.TargetID, models.CommentStatusHidden, models.CommentStatusNormal)
+		}
+		if err != nil {
+			zap.L().Error("restore hidden content failed", zap.Int64("caseID", caseID), zap.Error(err))
+			return err
+		}
+		// 发布时等待审核的帖子不在社区的热度排序中，审核通过后加入
+		if rc.TargetType == models.ReportTargetPost {
+			restoreCommunityPost(rc.TargetID)
+		}
+	}
+	return closeReportCase(rc, models.ReportCaseDismissed, userID, note)
+}
+
+// restoreCommunityPost 将恢复公开的帖子加入社区的热度排序，失败只记录日志
+func restoreCommunityPost(postID int64) {
+	post, err := mysql.GetPostByID(postID)
+	if err != nil {
+		zap.L().Error("mysql.GetPostByID failed", zap.Int64("postID", postID), zap.Error(err))
+		return
+	}
+	hot := computeRedditHotScore(0, 0, post.CreateTime.Unix())
+	if err := redis.RestoreCommunityPost(context.Background(), post.CommunityID, post.ID, hot); err != nil {
+		zap.L().Error("redis.RestoreCommunityPost failed", zap.Int64("postID", postID), zap.Error(err))
+	}
+}
+
+// closeReportCase 记录举报单的处理结果
+func closeReportCase(rc *models.ReportCase, status int8, userID int64, note string) error {
+	closed, err := mysql.CloseReportCase(rc.ID, status, userID, note)
+	if err != nil {
+		zap.L().Error("mysql.CloseReportCase failed", zap.Int64("caseID", rc.ID), zap.Error(err))
+		return err
+	}
+	if !closed {
+		return ErrReportClosed
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"context"
+	"database/sql"
+	"errors"
+	"go.uber.org/zap"
+	"time"
+)
+
+// 全站封禁和影子封禁
+/*
+	1. 处罚记录保存在 mysql，同时写入 redis 有序集合（分数为到期时间），请求时只查 redis；
+	   到期时间过了即视为解除，定时任务按 mysql 重建有序集合，清理到期的记录
+	2. 封禁：不能登录，封禁前签发的Token全部失效，登录和查询账号状态时说明封禁原因和解封时间
+	3. 影子封禁：用户照常发帖评论，自己能看到，其他人（版主和管理员除外）看不到，也不会收到@提及和回复通知
+*/
+
+var (
+	ErrUserSuspended     = errors.New("账号已被封禁")
+	ErrTokenRevoked      = errors.New("登录已失效，请重新登录")
+	ErrNotSiteAdmin      = errors.New("只有管理员可以操作")
+	ErrSanctionProtected = errors.New("不能处罚管理员")
+)
+
+// sanctionExpire 处罚在 redis 有序集合中的到期时间
+func sanctionExpire(s *models.UserSanction) int64 {
+	if s.ExpireTime == nil {
+		return redis.SanctionPermanent
+	}
+	return s.ExpireTime.Unix()
+}
+
+// SanctionUser 管理员封禁或影子封禁用户，已有同类处罚时以新的为准
+func SanctionUser(operatorID, userID int64, typ string, p *models.ParamUserSanction) error {
+	if !IsSiteAdmin(operatorID) {
+		return ErrNotSiteAdmin
+	}
+	if IsSiteAdmin(userID) {
+		return ErrSanctionProtected
+	}
+	if _, err := mysql.GetUserByID(userID); err != nil {
+		if err == sql.ErrNoRows {
+			return mysql.ErrorUserNotExist
+		}
+		zap.L().Error("mysql.GetUserByID failed", zap.Error(err))
+		return err
+	}
+	now := time.Now()
+	s := &models.UserSanction{
+		UserID:     userID,
+		Type:       typ,
+		Reason:     p.Reason,
+		Note:       p.Note,
+		OperatorID: operatorID,
+	}
+	if p.Hours > 0 {
+		expire := now.Add(time.Duration(p.Hours) * time.Hour)
+		s.ExpireTime = &expire
+	}
+	if err := mysql.SaveUserSanction(s); err != nil {
+		zap.L().Error("mysql.SaveUserSanction failed", zap.Int64("userID", userID), zap.String("type", typ), zap.Error(err))
+		return err
+	}
+	c := context.Background()
+	if err := redis.AddUserSanction(c, typ, userID, sanctionExpire(s)); err != nil {
+		zap.L().Error("redis.AddUserSanction failed", zap.Int64("userID", userID), zap.String("type", typ), zap.Error(err))
+		return err
+	}
+	if typ == models.SanctionSuspend {
+		// 封禁前签发的Token全部失效，解封后需要重新登录
+		if err := redis.SetTokenNotBefore(c, userID, now); err != nil {
+			zap.L().Error("redis.SetTokenNotBefore failed", zap.Int64("userID", userID), zap.Error(err))
+			return err
+		}
+		// 通知各实例关闭用户已打开的实时事件流
+		publishUserEvent(userID, models.EventSessionClose, &models.SessionCloseEvent{Reason: ErrUserSuspended.Error()})
+	}
+	return nil
+}
+
+// LiftSanction 管理员提前解除用户的处罚
+func LiftSanction(operatorID, userID int64, typ string) error {
+	if !IsSiteAdmin(operatorID) {
+		return ErrNotSiteAdmin
+	}
+	if _, err := mysql.DeleteUserSanction(userID, typ); err != nil {
+		zap.L().Error("mysql.DeleteUserSanction failed", zap.Int64("userID", userID), zap.String("type", typ), zap.Error(err))
+		return err
+	}
+	if err := redis.RemoveUserSanction(context.Background(), typ, userID); err != nil {
+		zap.L().Error("redis.RemoveUserSanction failed", zap.Int64("userID", userID), zap.String("type", typ), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetUserSanctions 管理员查看用户尚未到期的处罚
+func GetUserSanctions(operatorID, userID int64) ([]*models.UserSanction, error) {
+	if !IsSiteAdmin(operatorID) {
+		return nil, ErrNotSiteAdmin
+	}
+	list, err := mysql.GetUserSanctions(userID, time.Now())
+	if err != nil {
+		zap.L().Error("mysql.GetUserSanctions failed", zap.Int64("userID", userID), zap.Error(err))
+	}
+	return list, err
+}
+
+// accountStatus 根据尚未到期的封禁生成账号状态
+func accountStatus(userID int64) (*models.AccountStatus, error) {
+	s, err := mysql.GetUserSanction(userID, models.SanctionSuspend, time.Now())
+	if err != nil {
+		zap.L().Error("mysql.GetUserSanction failed", zap.Int64("userID", userID), zap.Error(err))
+		return nil, err
+	}
+	if s == nil {
+		return &models.AccountStatus{}, nil
+	}
+	return &models.AccountStatus{
+		Suspended:  true,
+		Reason:     s.Reason,
+		ReasonText: models.SanctionReasonText[s.Reason],
+		Note:       s.Note,
+		Permanent:  s.ExpireTime == nil,
+		ExpireTime: s.ExpireTime,
+	}, nil
+}
+
+// GetAccountStatus 用用户名和密码查询账号状态，被封禁时说明原因和解封时间；影子封禁不告知用户
+func GetAccountStatus(p *models.ParamAccountStatus) (*models.AccountStatus, error) {
+	user := &models.User{
+		Username: p.Username,
+		Password: p.Password,
+	}
+	if err := mysql.CheckLogin(user); err != nil {
+		return nil, err
+	}
+	return accountStatus(user.UserID)
+}
+
+// checkLoginAllowed 被封禁的用户不能登录
+func checkLoginAllowed(userID int64) error {
+	status, err := accountStatus(userID)
+	if err != nil {
+		return err
+	}
+	if status.Suspended {
+		return ErrUserSuspended
+	}
+	return nil
+}
+
+// CheckUserNotSuspended 校验用户没有被封禁；redis 出错时放行
+func CheckUserNotSuspended(c context.Context, userID int64) error {
+	suspended, err := redis.IsUserSanctioned(c, models.SanctionSuspend, userID, time.Now())
+	if err != nil {
+		zap.L().Error("redis.IsUserSanctioned failed", zap.Int64("userID", userID), zap.Error(err))
+		return nil
+	}
+	if suspended {
+		return ErrUserSuspended
+	}
+	return nil
+}
+
+// CheckUserToken 校验Token的用户没有被封禁，且Token不是在封禁前签发的；redis 出错时放行
+func CheckUserToken(c context.Context, userID int64, issuedAt time.Time) error {
+	if err := CheckUserNotSuspended(c, userID); err != nil {
+		return err
+	}
+	notBefore, err := redis.GetTokenNotBefore(c, userID)
+	if err != nil {
+		zap.L().Error("redis.GetTokenNotBefore failed", zap.Int64("userID", userID), zap.Error(err))
+		return nil
+	}
+	if notBefore > 0 && issuedAt.Unix() < notBefore {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// isShadowBanned 用户是否被影子封禁
+func isShadowBanned(c context.Context, userID int64) bool {
+	banned, err := redis.IsUserSanctioned(c, models.SanctionShadowBan, userID, time.Now())
+	if err != nil {
+		zap.L().Error("redis.IsUserSanctioned failed", zap.Int64("userID", userID), zap.Error(err))
+		return false
+	}
+	return banned
+}
+
+// shadowBannedUsers 被影子封禁的全部用户，不包括当前用户自己
+func shadowBannedUsers(c context.Context, viewerID int64) map[int64]struct{} {
+	ids, err := redis.GetSanctionedUsers(c, models.SanctionShadowBan, time.Now())
+	if err != nil {
+		zap.L().Error("redis.GetSanctionedUsers failed", zap.Error(err))
+		return nil
+	}
+	banned := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		if id != viewerID {
+			banned[id] = struct{}{}
+		}
+	}
+	return banned
+}
+
+// filterShadowBannedPosts 去掉被影子封禁的用户发布的帖子，作者自己仍然能看到
+func filterShadowBannedPosts(c context.Context, viewerID int64, ps []*models.Post) []*models.Post {
+	if len(ps) == 0 {
+		return ps
+	}
+	banned := shadowBannedUsers(c, viewerID)
+	if len(banned) == 0 {
+		return ps
+	}
+	list := make([]*models.Post, 0, len(ps))
+	for _, post := range ps {
+		if _, ok := banned[post.AuthorID]; !ok {
+			list = append(list, post)
+		}
+	}
+	return list
+}
+
+// filterShadowBannedComments 去掉被影子封禁的用户发布的评论，作者自己仍然能看到
+func filterShadowBannedComments(c context.Context, viewerID int64, comments []*models.Comment) []*models.Comment {
+	if len(comments) == 0 {
+		return comments
+	}
+	banned := shadowBannedUsers(c, viewerID)
+	if len(banned) == 0 {
+		return comments
+	}
+	list := make([]*models.Comment, 0, len(comments))
+	for _, comment := range comments {
+		if _, ok := banned[comment.UserID]; !ok {
+			list = append(list, comment)
+		}
+	}
+	return list
+}
+
+// SyncUserSanctions 按 mysql 中尚未到期的处罚重建 redis 有序集合，清理已到期的记录
+func SyncUserSanctions() {
+	c := context.Background()
+	now := time.Now()
+	for _, typ := range []string{models.SanctionSuspend, models.SanctionShadowBan} {
+		list, err := mysql.GetActiveSanctions(typ, now)
+		if err != nil {
+			zap.L().Error("mysql.GetActiveSanctions failed", zap.String("type", typ), zap.Error(err))
+			continue
+		}
+		expires := make(map[int64]int64, len(list))
+		for _, s := range list {
+			expires[s.UserID] = sanctionExpire(s)
+		}
+		if err := redis.ReplaceSanctionedUsers(c, typ, expires); err != nil {
+			zap.L().Error("redis.ReplaceSanctionedUsers failed", zap.String("type", typ), zap.Error(err))
+		}
+	}
+}
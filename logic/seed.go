@@ -0,0 +1,115 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/models"
+	"bluebell/pkg/snowflake"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"math/rand"
+	"time"
+)
+
+// SeedPassword 测试用户的密码
+const SeedPassword = "12345678"
+
+// seedUser 创建测试用户，用户名已存在时返回已有的用户
+func seedUser(username string) (userID int64, created bool, err error) {
+	userID, err = mysql.GetExistUser(username)
+	if err == nil || !errors.Is(err, sql.ErrNoRows) {
+		return userID, false, err
+	}
+	user := &models.User{
+		UserID:    snowflake.GenID(),
+		Username:  username,
+		Password:  SeedPassword,
+		CreatedAt: time.Now(),
+		AvatarURL: DefaultAvatarURL,
+	}
+	if err = mysql.InsertUser(user); err != nil {
+		return 0, false, err
+	}
+	return user.UserID, true, nil
+}
+
+// seedCommunity 创建测试社区，社区名已存在时返回已有的社区
+func seedCommunity(name string, ownerID int64) (communityID int64, created bool, err error) {
+	existing, err := mysql.GetCommunityByName(name)
+	if err == nil {
+		return existing.ID, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, false, err
+	}
+	comm := &models.CommunityDetail{
+		ID:           snowflake.GenID(),
+		Name:         name,
+		Introduction: "用于开发和测试的社区",
+		OwnerID:      ownerID,
+	}
+	if err = mysql.CreateCommunity(comm); err != nil {
+		return 0, false, err
+	}
+	return comm.ID, true, nil
+}
+
+// SeedTestData 生成测试用户、社区和帖子，帖子作者随机选取并加入所在社区，最后重建 redis 排序数据
+// 直接写入数据库，不经过敏感词、信任等级和配额检查
+func SeedTestData(users, communities, posts int) (*models.SeedResult, error) {
+	if users <= 0 || communities <= 0 {
+		return nil, errors.New("至少需要一个测试用户和一个测试社区")
+	}
+	result := new(models.SeedResult)
+	userIDs := make([]int64, 0, users)
+	for i := 1; i <= users; i++ {
+		userID, created, err := seedUser(fmt.Sprintf("test_user_%d", i))
+		if err != nil {
+			zap.L().Error("seedUser failed", zap.Int("index", i), zap.Error(err))
+			return result, err
+		}
+		if created {
+			result.Users++
+		}
+		userIDs = append(userIDs, userID)
+	}
+	communityIDs := make([]int64, 0, communities)
+	for i := 1; i <= communities; i++ {
+		communityID, created, err := seedCommunity(fmt.Sprintf("测试社区%d", i), userIDs[(i-1)%len(userIDs)])
+		if err != nil {
+			zap.L().Error("seedCommunity failed", zap.Int("index", i), zap.Error(err))
+			return result, err
+		}
+		if created {
+			result.Communities++
+		}
+		communityIDs = append(communityIDs, communityID)
+	}
+	for i := 1; i <= posts; i++ {
+		p := &models.Post{
+			ID:          snowflake.GenID(),
+			AuthorID:    userIDs[rand.Intn(len(userIDs))],
+			CommunityID: communityIDs[rand.Intn(len(communityIDs))],
+			PostType:    models.PostTypeText,
+			Status:      models.PostStatusNormal,
+			Title:       fmt.Sprintf("测试帖子 %d", i),
+			Content:     fmt.Sprintf("这是第 %d 个测试帖子的内容，生成于 %s。", i, time.Now().Format(time.DateTime)),
+		}
+		if _, err := mysql.JoinCommunity(p.CommunityID, p.AuthorID); err != nil {
+			zap.L().Error("mysql.JoinCommunity failed", zap.Error(err))
+			return result, err
+		}
+		if err := mysql.CreatePost(p); err != nil {
+			zap.L().Error("mysql.CreatePost failed", zap.Error(err))
+			return result, err
+		}
+		result.Posts++
+	}
+	if result.Posts > 0 {
+		if _, err := RebuildRedisRanking(true); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,112 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/models"
+	"bluebell/pkg/sensitive"
+	"errors"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"strings"
+)
+
+// 敏感词过滤
+/*
+	1. 帖子、评论、用户名和社区信息在保存前检查敏感词，各字段命中后的处理方式由 sensitive.actions 配置：
+	   reject 拒绝保存；mask 把敏感词替换为 * 后保存；review 照常保存但先隐藏，进入审核队列由版主处理
+	2. 用户名和社区信息不能隐藏，配置为 review 时按 reject 处理
+*/
+
+var ErrSensitiveContent = errors.New("内容包含敏感词")
+
+// 需要过滤敏感词的字段
+const (
+	SensitiveFieldPost      = "post"
+	SensitiveFieldComment   = "comment"
+	SensitiveFieldUsername  = "username"
+	SensitiveFieldCommunity = "community"
+)
+
+// 命中敏感词后的处理方式
+const (
+	SensitiveActionReject = "reject"
+	SensitiveActionMask   = "mask"
+	SensitiveActionReview = "review"
+)
+
+// defaultSensitiveActions 没有配置时各字段的处理方式
+var defaultSensitiveActions = map[string]string{
+	SensitiveFieldPost:      SensitiveActionReview,
+	SensitiveFieldComment:   SensitiveActionMask,
+	SensitiveFieldUsername:  SensitiveActionReject,
+	SensitiveFieldCommunity: SensitiveActionReject,
+}
+
+// sensitiveAction 字段命中敏感词后的处理方式
+func sensitiveAction(field string) string {
+	action := defaultSensitiveActions[field]
+	if key := "sensitive.actions." + field; viper.IsSet(key) {
+		action = viper.GetString(key)
+	}
+	if action == SensitiveActionReview && (field == SensitiveFieldUsername || field == SensitiveFieldCommunity) {
+		return SensitiveActionReject
+	}
+	return action
+}
+
+// filterSensitive 按字段的处理方式检查文本：reject 时返回 ErrSensitiveContent，mask 时替换传入的文本，
+// review 时返回命中的敏感词，由调用方保存后隐藏内容并提交审核
+func filterSensitive(field string, texts ...*string) ([]string, error) {
+	filter := sensitive.Default()
+	action := sensitiveAction(field)
+	var words []string
+	for _, text := range texts {
+		masked, matches := filter.Mask(*text)
+		if len(matches) == 0 {
+			continue
+		}
+		for _, m := range matches {
+			words = append(words, m.Word)
+		}
+		switch action {
+		case SensitiveActionReject:
+			zap.L().Info("内容包含敏感词，拒绝保存", zap.String("field", field), zap.Strings("words", words))
+			return nil, ErrSensitiveContent
+		case SensitiveActionMask:
+			*text = masked
+		}
+	}
+	if action != SensitiveActionReview {
+		return nil, nil
+	}
+	return words, nil
+}
+
+// reviewReason 内容需要送审时的举报理由和说明：命中敏感词优先，其次是反垃圾检查给出的原因
+func reviewReason(words []string, spamReason string) (reason, detail string) {
+	switch {
+	case len(words) > 0:
+		return models.ReportReasonSensitive, "命中敏感词: " + strings.Join(words, ", ")
+	case spamReason != "":
+		return models.ReportReasonSpam, spamReason
+	}
+	return "", ""
+}
+
+// holdForReview 为以隐藏状态保存的帖子或评论以系统身份提交举报单，由版主在审核队列中处理
+// 提交失败时内容保持隐藏，不会公开
+func holdForReview(rc *models.ReportCase, reason, detail string) {
+	rc.Snapshot = truncateSnapshot(rc.Snapshot)
+	caseID, _, _, err := mysql.AddReport(rc, &models.Report{
+		ReporterID: 0,
+		Reason:     reason,
+		Detail:     truncateSnapshot(detail),
+	})
+	if err != nil {
+		zap.L().Error("mysql.AddReport failed", zap.String("type", rc.TargetType), zap.Int64("id", rc.TargetID), zap.Error(err))
+		return
+	}
+	if err := mysql.SetReportCaseHidden(caseID); err != nil {
+		zap.L().Error("mysql.SetReportCaseHidden failed", zap.Int64("caseID", caseID), zap.Error(err))
+	}
+}
@@ -0,0 +1,320 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	mail "bluebell/pkg/email"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 新账号的反垃圾策略
+/*
+	1. 信任等级由注册时长、声望（帖子和评论获得的赞减踩）和邮箱是否验证决定，用户取满足条件的最高等级，
+	   等级计算结果在 redis 中缓存 trust.cache_minutes 分钟
+	2. 每个等级限制每小时发帖、评论、投票和发链接的数量，超过时拒绝操作；最低等级可以配置为内容先送审
+	3. 最近 spam.window_hours 小时内重复出现的内容、多次出现的相同链接，照常保存但先隐藏，进入审核队列
+*/
+
+var (
+	ErrActionRateLimited = errors.New("操作过于频繁，请稍后再试")
+	ErrTrustLevelTooLow  = errors.New("账号信任等级不足")
+	ErrEmailNotSet       = errors.New("没有设置邮箱")
+	ErrEmailUnavailable  = errors.New("邮件服务暂不可用")
+)
+
+// 受配额限制的操作
+const (
+	quotaActionPost    = "post"
+	quotaActionComment = "comment"
+	quotaActionVote    = "vote"
+	quotaActionLink    = "link"
+)
+
+const (
+	emailCodeTTL = 10 * time.Minute
+	quotaWindow  = time.Hour
+)
+
+// defaultTrustLevels 没有配置 trust.levels 时使用的信任等级
+var defaultTrustLevels = []models.TrustLevel{
+	{PostsPerHour: 2, CommentsPerHour: 10, VotesPerHour: 30, LinksPerHour: 1, HoldForReview: true},
+	{MinAgeHours: 24, PostsPerHour: 5, CommentsPerHour: 30, VotesPerHour: 100, LinksPerHour: 3},
+	{MinAgeHours: 24 * 7, MinKarma: 50, EmailVerified: true, PostsPerHour: 20, CommentsPerHour: 120, VotesPerHour: 500, LinksPerHour: 20, CreateCommunity: true},
+}
+
+// linkPattern 文本中的链接
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"'()]+`)
+
+// trustLevels 从低到高的信任等级
+func trustLevels() []models.TrustLevel {
+	var levels []models.TrustLevel
+	if err := viper.UnmarshalKey("trust.levels", &levels); err != nil || len(levels) == 0 {
+		return defaultTrustLevels
+	}
+	return levels
+}
+
+// computeUserTrust 根据注册时长、声望和邮箱验证情况计算用户的信任等级
+func computeUserTrust(userID int64) (*models.UserTrust, error) {
+	createTime, _, verified, err := mysql.GetUserTrustInfo(userID)
+	if err != nil {
+		return nil, err
+	}
+	karma, err := mysql.GetUserKarma(userID)
+	if err != nil {
+		return nil, err
+	}
+	trust := &models.UserTrust{
+		Karma:           karma,
+		AccountAgeHours: int64(time.Since(createTime).Hours()),
+		EmailVerified:   verified,
+	}
+	levels := trustLevels()
+	for i := range levels {
+		l := levels[i]
+		if trust.AccountAgeHours >= l.MinAgeHours && karma >= l.MinKarma && (verified || !l.EmailVerified) {
+			trust.Level = i
+		}
+	}
+	trust.Limits = &levels[trust.Level]
+	return trust, nil
+}
+
+// userTrustLevel 用户当前的信任等级，优先使用缓存
+func userTrustLevel(c context.Context, userID int64) (*models.TrustLevel, error) {
+	levels := trustLevels()
+	level, ok, err := redis.GetTrustLevel(c, userID)
+	if err != nil {
+		zap.L().Error("redis.GetTrustLevel failed", zap.Int64("userID", userID), zap.Error(err))
+	}
+	if ok && level < len(levels) {
+		return &levels[level], nil
+	}
+	trust, err := computeUserTrust(userID)
+	if err != nil {
+		return nil, err
+	}
+	ttl := time.Duration(viper.GetInt("trust.cache_minutes")) * time.Minute
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	if err := redis.SetTrustLevel(c, userID, trust.Level, ttl); err != nil {
+		zap.L().Error("redis.SetTrustLevel failed", zap.Int64("userID", userID), zap.Error(err))
+	}
+	return trust.Limits, nil
+}
+
+// GetUserTrust 查询用户的信任等级、计算依据和当前等级的配额
+func GetUserTrust(userID int64) (*models.UserTrust, error) {
+	return computeUserTrust(userID)
+}
+
+// consumeQuota 记录 n 次操作，当前小时内的总数超过 limit 时返回 ErrActionRateLimited；limit 为负数表示不限制
+// 计数失败时返回错误，操作被拒绝
+func consumeQuota(c context.Context, userID int64, action string, n, limit int64) error {
+	if limit < 0 || n <= 0 {
+		return nil
+	}
+	count, err := redis.IncrQuota(c, action, userID, n, quotaWindow)
+	if err != nil {
+		// 计数失败时拒绝操作，避免配额失效
+		zap.L().Error("redis.IncrQuota failed, action denied", zap.String("action", action), zap.Int64("userID", userID), zap.Error(err))
+		return err
+	}
+	if count > limit {
+		zap.L().Info("操作超过每小时配额", zap.String("action", action), zap.Int64("userID", userID), zap.Int64("count", count), zap.Int64("limit", limit))
+		return ErrActionRateLimited
+	}
+	return nil
+}
+
+// checkVoteQuota 检查用户本小时的投票配额
+func checkVoteQuota(c context.Context, userID int64) error {
+	level, err := userTrustLevel(c, userID)
+	if err != nil {
+		return err
+	}
+	return consumeQuota(c, userID, quotaActionVote, 1, level.VotesPerHour)
+}
+
+// checkCreateCommunity 检查用户的信任等级能否创建社区
+func checkCreateCommunity(c context.Context, userID int64) error {
+	level, err := userTrustLevel(c, userID)
+	if err != nil {
+		return err
+	}
+	if !level.CreateCommunity {
+		return ErrTrustLevelTooLow
+	}
+	return nil
+}
+
+// extractLinks 提取文本中的链接，统一为小写并去掉结尾的标点
+func extractLinks(texts ...string) []string {
+	var links []string
+	for _, text := range texts {
+		for _, link := range linkPattern.FindAllString(text, -1) {
+			link = strings.TrimRightFunc(strings.ToLower(link), unicode.IsPunct)
+			link = strings.TrimPrefix(strings.TrimPrefix(link, "https://"), "http://")
+			links = append(links, strings.TrimPrefix(link, "www."))
+		}
+	}
+	return links
+}
+
+// contentHash 去掉空白和标点、统一大小写后的内容哈希，内容太短时返回空字符串
+func contentHash(texts ...string) string {
+	var b strings.Builder
+	for _, text := range texts {
+		for _, r := range text {
+			if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+				continue
+			}
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	minLength := viper.GetInt("spam.min_duplicate_length")
+	if minLength <= 0 {
+		minLength = 20
+	}
+	if len([]rune(b.String())) < minLength {
+		return ""
+	}
+	sum := sha1.Sum([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkContentSpam 检查发帖或评论的配额，以及内容是否重复、链接是否被反复发布；
+// 超过配额时返回 ErrActionRateLimited，需要送审时返回送审原因
+func checkContentSpam(c context.Context, userID int64, action string, texts ...string) (string, error) {
+	level, err := userTrustLevel(c, userID)
+	if err != nil {
+		return "", err
+	}
+	limit := level.PostsPerHour
+	if action == quotaActionComment {
+		limit = level.CommentsPerHour
+	}
+	if err := consumeQuota(c, userID, action, 1, limit); err != nil {
+		return "", err
+	}
+	links := extractLinks(texts...)
+	if err := consumeQuota(c, userID, quotaActionLink, int64(len(links)), level.LinksPerHour); err != nil {
+		return "", err
+	}
+
+	window := time.Duration(viper.GetInt("spam.window_hours")) * time.Hour
+	if window <= 0 {
+		window = 24 * time.Hour
+	}
+	var reasons []string
+	if hash := contentHash(texts...); hash != "" {
+		maxDuplicates := viper.GetInt64("spam.max_duplicates")
+		if maxDuplicates <= 0 {
+			maxDuplicates = 1
+		}
+		count, err := redis.MarkContentSeen(c, hash, window)
+		if err != nil {
+			// 无法检查时内容先送审
+			zap.L().Error("redis.MarkContentSeen failed, content held for review", zap.Error(err))
+			reasons = append(reasons, "重复内容检查失败")
+		} else if count > maxDuplicates {
+			reasons = append(reasons, fmt.Sprintf("最近出现过%d次相同内容", count-1))
+		}
+	}
+	maxRepeats := viper.GetInt64("spam.max_link_repeats")
+	if maxRepeats <= 0 {
+		maxRepeats = 3
+	}
+	seen := make(map[string]struct{}, len(links))
+	for _, link := range links {
+		if _, ok := seen[link]; ok {
+			continue
+		}
+		seen[link] = struct{}{}
+		sum := sha1.Sum([]byte(link))
+		count, err := redis.MarkLinkSeen(c, hex.EncodeToString(sum[:]), window)
+		if err != nil {
+			zap.L().Error("redis.MarkLinkSeen failed, content held for review", zap.Error(err))
+			reasons = append(reasons, fmt.Sprintf("链接 %s 重复检查失败", link))
+			continue
+		}
+		if count > maxRepeats {
+			reasons = append(reasons, fmt.Sprintf("链接 %s 最近出现了%d次", link, count))
+		}
+	}
+	if level.HoldForReview {
+		reasons = append(reasons, "新账号发布的内容需要审核")
+	}
+	return strings.Join(reasons, "; "), nil
+}
+
+// SendEmailCode 为用户当前的邮箱生成6位验证码并通过邮件发送，有效期10分钟
+// 验证码只能通过邮件送达，不能返回给调用方，也不能写入日志
+func SendEmailCode(c context.Context, userID int64) error {
+	_, email, _, err := mysql.GetUserTrustInfo(userID)
+	if err != nil {
+		return err
+	}
+	if email == "" {
+		return ErrEmailNotSet
+	}
+	if !mail.Enabled() {
+		return ErrEmailUnavailable
+	}
+	code := ""
+	for i := 0; i < 6; i++ {
+		num, _ := rand.Int(rand.Reader, big.NewInt(10))
+		code += fmt.Sprintf("%d", num)
+	}
+	if err := redis.SetEmailCode(c, userID, email, code, emailCodeTTL); err != nil {
+		zap.L().Error("redis.SetEmailCode failed", zap.Int64("userID", userID), zap.Error(err))
+		return err
+	}
+	body := fmt.Sprintf("你的邮箱验证码是 %s，%d 分钟内有效。如果不是你本人的操作，请忽略这封邮件。", code, int(emailCodeTTL.Minutes()))
+	if err := mail.Send(email, "bluebell 邮箱验证码", body); err != nil {
+		zap.L().Error("mail.Send failed", zap.Int64("userID", userID), zap.Error(err))
+		return ErrEmailUnavailable
+	}
+	return nil
+}
+
+// VerifyEmail 校验邮箱验证码，验证码只能使用一次；发送验证码后修改过邮箱时验证失败
+func VerifyEmail(c context.Context, userID int64, code string) error {
+	expected, email, err := redis.TakeEmailCode(c, userID)
+	if err != nil {
+		zap.L().Error("redis.TakeEmailCode failed", zap.Int64("userID", userID), zap.Error(err))
+		return err
+	}
+	if expected == "" {
+		return ErrorOPTExpired
+	}
+	if expected != code {
+		return ErrorOPTInvalid
+	}
+	ok, err := mysql.SetEmailVerified(userID, email)
+	if err != nil {
+		zap.L().Error("mysql.SetEmailVerified failed", zap.Int64("userID", userID), zap.Error(err))
+		return err
+	}
+	if !ok {
+		return ErrorOPTInvalid
+	}
+	if err := redis.ClearTrustLevel(c, userID); err != nil {
+		zap.L().Error("redis.ClearTrustLevel failed", zap.Int64("userID", userID), zap.Error(err))
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package logic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractLinks(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+		want  []string
+	}{
+		{"none", []string{"no links here"}, nil},
+		{"https", []string{"see https://Example.com/Path"}, []string{"example.com/path"}},
+		{"http and www", []string{"http://www.example.com"}, []string{"example.com"}},
+		{"www only", []string{"go to www.example.com/a."}, []string{"example.com/a"}},
+		{"trailing punctuation", []string{"(see https://example.com/x!)"}, []string{"example.com/x"}},
+		{"chinese punctuation", []string{"链接https://example.com/x。"}, []string{"example.com/x"}},
+		{"multiple texts", []string{"https://a.com", "title", "www.b.com and http://c.com"}, []string{"a.com", "b.com", "c.com"}},
+		{"no scheme not link", []string{"example.com"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractLinks(tt.texts...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractLinks(%q) = %q, want %q", tt.texts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	const text = "This is a long enough duplicate post"
+	if got := contentHash("too short"); got != "" {
+		t.Errorf("contentHash(short) = %q, want empty", got)
+	}
+	base := contentHash(text)
+	if base == "" {
+		t.Fatal("contentHash(long) is empty")
+	}
+	same := []string{
+		"this is a long enough duplicate post",
+		"This  is a long, enough duplicate post!!",
+		"Thisisalongenoughduplicatepost",
+	}
+	for _, s := range same {
+		if got := contentHash(s); got != base {
+			t.Errorf("contentHash(%q) = %q, want %q", s, got, base)
+		}
+	}
+	if got := contentHash("This is a long enough", " duplicate post"); got != base {
+		t.Errorf("contentHash over several texts = %q, want %q", got, base)
+	}
+	if got := contentHash("This is a long enough different post"); got == base {
+		t.Error("contentHash of different content equals base")
+	}
+}
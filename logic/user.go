@@ -6,11 +6,13 @@ import (
 	"bluebell/models"
 	"bluebell/pkg/jwt"
 	"bluebell/pkg/snowflake"
+	"context"
 	"crypto/rand"
 	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"math/big"
 	"time"
@@ -73,6 +75,9 @@ func GetExistUser(req *models.ParamUsernameRequest) (int64, bool) {
 
 // Signup 用户注册的逻辑处理
 func Signup(p *models.ParamSignUp) (err error) {
+	if _, err = filterSensitive(SensitiveFieldUsername, &p.Username); err != nil {
+		return err
+	}
 	// 判断用户是否存在
 	if err = mysql.CheckUserExist(p.Username); err != nil {
 		return err
@@ -102,6 +107,10 @@ func LogIn(c *gin.Context, p *models.ParamLogIn) (User *models.User, err error)
 	if err = mysql.CheckLogin(user); err != nil {
 		return nil, err
 	}
+	// 被封禁的用户不能登录
+	if err = checkLoginAllowed(user.UserID); err != nil {
+		return nil, err
+	}
 	// 判断验证码是否正确
 	var otp string
 	if otp, err = redis.GetOTP(c, user.UserID); err != nil {
@@ -126,6 +135,17 @@ func LogOut(token string) error {
 	return mysql.AddToBlacklist(token)
 }
 
+// PurgeTokenBlacklist 清理黑名单中已经过期的Token，返回清理的数量
+// 加入黑名单超过 auth.jwt_expire 小时的Token本身已过期，不需要再保留
+func PurgeTokenBlacklist() (int64, error) {
+	before := time.Now().Add(-time.Duration(viper.GetInt("auth.jwt_expire")) * time.Hour)
+	n, err := mysql.PurgeBlacklist(before)
+	if err != nil {
+		zap.L().Error("mysql.PurgeBlacklist failed", zap.Error(err))
+	}
+	return n, err
+}
+
 // GetUserInfo 获取当前用户的信息
 func GetUserInfo(userID int64) (*models.User, error) {
 	return mysql.GetUserInfo(userID)
@@ -147,6 +167,12 @@ func ModifyUserInfo(userID int64, updates map[string]interface{}) error {
 		zap.L().Error("存在不允许修改字段")
 		return ErrorModifyNil
 	}
+	if username, ok := updates["username"].(string); ok {
+		if _, err := filterSensitive(SensitiveFieldUsername, &username); err != nil {
+			return err
+		}
+		updates["username"] = username
+	}
 
 	// 转换 JSON 字段为 DB 字段
 	validUpdates := make(map[string]interface{})
@@ -156,6 +182,10 @@ func ModifyUserInfo(userID int64, updates map[string]interface{}) error {
 		}
 	}
 
+	// 修改邮箱后需要重新验证
+	if _, ok := validUpdates["email"]; ok {
+		validUpdates["email_verified"] = false
+	}
 	// 修改更新信息时间
 	validUpdates[UserUpdateTime] = time.Now()
 	// 更新mysql数据库表
@@ -163,6 +193,11 @@ func ModifyUserInfo(userID int64, updates map[string]interface{}) error {
 		zap.L().Error("mysql.ModifyUserInfo error", zap.Error(err))
 		return err
 	}
+	if _, ok := validUpdates["email_verified"]; ok {
+		if err := redis.ClearTrustLevel(context.Background(), userID); err != nil {
+			zap.L().Error("redis.ClearTrustLevel failed", zap.Int64("userID", userID), zap.Error(err))
+		}
+	}
 	return nil
 }
 
@@ -229,8 +264,8 @@ func GetUsers() ([]*models.UserSafe, error) {
 	return users, nil
 }
 
-// GetUserPosts 获取用户以及其对应的全部帖子
-func GetUserPosts(userID int64) (*models.UserPost, error) {
+// GetUserPosts 获取用户以及其对应的全部帖子，不包括当前用户不能浏览的私密社区中的帖子
+func GetUserPosts(viewerID, userID int64) (*models.UserPost, error) {
 	// 获取用户
 	user, err := GetUserByID(userID)
 	if err != nil {
@@ -247,6 +282,9 @@ func GetUserPosts(userID int64) (*models.UserPost, error) {
 		zap.L().Error("mysql.GetPostListByUserID error", zap.Error(err))
 		return nil, err
 	}
+	if posts, err = filterReadablePosts(viewerID, posts); err != nil {
+		return nil, err
+	}
 	// 拼装得到的信息
 	userpost := &models.UserPost{
 		User:  user,
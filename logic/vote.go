@@ -5,46 +5,79 @@ import (
 	"bluebell/dao/redis"
 	"bluebell/models"
 	"context"
-	"fmt"
 	"github.com/gin-gonic/gin"
-	rd "github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
-	"math"
 	"strconv"
 	"time"
 )
 
 // 投票功能
 /*
-	1. 用户点赞时，直接更新 Redis 热度（不实时写 MySQL）
+	1. 用户点赞时，通过 lua 脚本原子地更新 Redis 投票、票数和热度（不实时写 MySQL）
 	2. 用户查询时，直接从 Redis 读取，无需计算，速度快
-	3. 定期任务同步 Redis → MySQL，确保最终一致性
+	3. 定期任务同步 Redis → MySQL，确保最终一致性（见 vote_sync.go）
 */
 
-// 计算 Reddit 热度
-func computeRedditHotScore(ups, downs, postTime int64) float64 {
-	score := math.Max(float64(ups-downs), 1)
-	order := math.Log10(score)
-	//seconds := float64(postTime - 1134028003) // Reddit 基准时间
-	seconds := float64(postTime - 1735689600) // bluebell发布的基准时间（2025-01-01）
-	return order + seconds/45000.0            // 约12.5小时热度加一
-}
-
 // VoteForPost 为帖子投票的函数
 func VoteForPost(c *gin.Context, userID int64, p *models.ParamVoteData) error {
 	zap.L().Debug("VoteForPost", zap.Int64("userID", userID), zap.Int64("postid", p.PostID), zap.Int8("direction", p.Direction))
 	uidStr := strconv.FormatInt(userID, 10)
 	pidStr := strconv.FormatInt(p.PostID, 10)
 
-	// 更新用户为该帖子投票结果
-	if err := redis.VoteForPost(c, uidStr, pidStr, float64(p.Direction)); err != nil {
+	// 发帖时间不会改变，可以在投票脚本之外先查出来
+	createTimeStamp, err := GetPostCreateTimeCached(c, p.PostID)
+	if err != nil {
+		zap.L().Error("GetPostCreateTimeCached", zap.Error(err))
+		return err
+	}
+
+	post, err := getPost(p.PostID)
+	if err != nil {
+		return err
+	}
+	// 被移除或隐藏的帖子不能投票，否则会重新进入排序
+	if postUnavailable(post) {
+		return ErrPostRemoved
+	}
+	if err := checkCommunityRead(post.CommunityID, userID); err != nil {
+		return err
+	}
+	if err := checkCommunityArchived(post.CommunityID); err != nil {
+		return err
+	}
+	if err := checkVoteQuota(c, userID); err != nil {
+		return err
+	}
+
+	// 一次往返完成：更新投票、票数、各排序分数，并标记帖子待同步
+	changed, ups, downs, err := redis.VoteForPost(c, uidStr, pidStr, strconv.FormatInt(post.CommunityID, 10), float64(p.Direction), createTimeStamp, rankParams(createTimeStamp, time.Now().Unix()))
+	if err != nil {
 		zap.L().Error("VoteForPost", zap.Error(err))
 		return err
 	}
+	if !changed {
+		zap.L().Debug("重复投票", zap.Int64("userID", userID), zap.Int64("postid", p.PostID))
+	} else {
+		// 投票持久化到 MySQL，redis 数据丢失时据此重建
+		// redis 中的投票已经生效，写入失败时记录下来由同步任务重放，MySQL 最终与 redis 一致
+		if err := mysql.SavePostVote(userID, p.PostID, p.Direction); err != nil {
+			zap.L().Error("mysql.SavePostVote failed", zap.Int64("postID", p.PostID), zap.Int64("userID", userID), zap.Error(err))
+			if err := redis.AddPendingPostVote(c, pidStr, uidStr); err != nil {
+				zap.L().Error("redis.AddPendingPostVote failed", zap.Int64("postID", p.PostID), zap.Int64("userID", userID), zap.Error(err))
+				return err
+			}
+		}
+		// 向正在浏览帖子的用户推送新的票数
+		publishPostEvent(p.PostID, models.EventPostVote, &models.VoteCountEvent{PostID: p.PostID, Ups: ups, Downs: downs})
+		// 只通知赞成票
+		if p.Direction == 1 {
+			notifyPostVote(userID, p.PostID)
+		}
+	}
+
 	// 更新用户和帖子行为表
 	// 更新帖子用户行为信息
-	//var behavior *models.UserPostBehavior
 	behavior, err := mysql.CheckBehavior(userID, p.PostID)
 	if err == gorm.ErrRecordNotFound {
 		// 没有找到记录, 创建记录
@@ -53,153 +86,58 @@ func VoteForPost(c *gin.Context, userID int64, p *models.ParamVoteData) error {
 			zap.L().Error("mysql.CreateBehavior failed", zap.Error(err))
 			return err
 		}
-		return nil // 返回 nil 表示没有记录
-
 	} else if err != nil {
 		zap.L().Error("mysql.CheckBehavior failed", zap.Error(err))
 		return err
-	}
-
-	// 行为存在，查看是否需要更新行为
-	if behavior.Like == 0 {
+	} else if behavior.Like == 0 {
+		// 行为存在，查看是否需要更新行为
 		if err := mysql.UpdateBehavior(userID, p.PostID, mysql.BehaviorLike); err != nil {
 			zap.L().Error("mysql.UpdateBehavior failed", zap.Error(err))
 			return err
 		}
 	}
-
-	// 更新热度 --> 得到赞成票、反对票以及发帖时间
-	approve, err := redis.GetPostVoteByID(c, strconv.FormatInt(p.PostID, 10))
-	if err != nil {
-		zap.L().Error("GetPostVoteByID", zap.Error(err))
-		return err
-	}
-	against, err := redis.GetPostVoteAgainstByID(c, strconv.FormatInt(p.PostID, 10))
-	if err != nil {
-		zap.L().Error("GetPostVoteAgainstData", zap.Error(err))
-		return err
-	}
-	createTimeStamp, err := GetPostCreateTimeCached(c, p.PostID)
-	//fmt.Println("createTimeStamp:", createTimeStamp)
-	if err != nil {
-		zap.L().Error("GetPostCreateTimeCached", zap.Error(err))
-		return err
-	}
-	// 计算热度
-	score := computeRedditHotScore(approve, against, createTimeStamp)
-	//fmt.Println("score:", score)
-	err = redis.UpdateScore(c, strconv.FormatInt(p.PostID, 10), score)
-	if err != nil {
-		zap.L().Error("UpdateScore", zap.Error(err))
-	}
-	// 在redis中更新投票的的最新时间
-	return redis.UpdateVoteTime(c, strconv.FormatInt(p.PostID, 10), float64(time.Now().Unix()))
-}
-
-// 获取上次同步的时间戳
-func getLastSyncTime(c context.Context, syncKey string) (int64, error) {
-	lastSyncTimeStr, err := redis.GetLastSyncTime(c, syncKey)
-	if err != nil {
-		zap.L().Error("GetLastSyncTime failed", zap.Error(err))
-		return time.Now().Unix() - 600, err // 默认同步 10 分钟内的数据
-	}
-	lastSyncTime, err := strconv.ParseInt(lastSyncTimeStr, 10, 64)
-	if err != nil {
-		zap.L().Error("strconv.ParseInt(lastSyncTimeStr) failed", zap.Error(err))
-		return time.Now().Unix() - 600, err
-	}
-	return lastSyncTime, err
-
-}
-
-// 设置新的 last_sync_time
-func setLastSyncTime(c context.Context, syncKey string, timestamp int64) error {
-	if err := redis.SetLastSyncTime(c, syncKey, timestamp); err != nil {
-		zap.L().Error("redis.SetLastSyncTime failed", zap.Error(err))
-		return err
-	}
 	return nil
 }
 
-// 增量同步 Redis 热度数据到 MySQL
-func SyncHotsDLikesToMySQL() {
-	fmt.Println("热度增量同步开始:", time.Now())
-	c := context.Background()
-	// 获取上次同步的时间
-	lastSyncTime, err := getLastSyncTime(c, redis.LastSyncTimeHotDLikesKey)
-	currentTime := time.Now().Unix()
-	postUpdateScores, err := redis.GetTimeAndScore(c, lastSyncTime, currentTime)
+// notifyPostVote 通知帖子作者收到赞成票
+func notifyPostVote(userID, postID int64) {
+	author, err := mysql.GetPostAuthor(postID)
 	if err != nil {
-		zap.L().Error("redis.GetScoreLimitTime failed", zap.Error(err))
-		return
-	}
-	// 没有新增点赞点踩数据
-	if len(postUpdateScores) == 0 {
-		zap.L().Info("没有需要同步的热度数据", zap.Int64("lastSyncTime", lastSyncTime), zap.Int64("currentTime", currentTime))
-		setLastSyncTime(c, redis.LastSyncTimeHotDLikesKey, currentTime)
+		zap.L().Error("mysql.GetPostAuthor failed", zap.Int64("postID", postID), zap.Error(err))
 		return
 	}
-	// 增量更新热度到mysql
-	if err := syncHots(c, postUpdateScores); err != nil {
-		zap.L().Error("syncHots failed", zap.Error(err))
-		return
-	}
-	// 增量更新热度到redis
-	if err := syncLikes(c, postUpdateScores); err != nil {
-		zap.L().Error("syncLikes failed", zap.Error(err))
-		return
-	}
-	// 增量更新点踩到redis
-	if err := syncDislikes(c, postUpdateScores); err != nil {
-		zap.L().Error("syncDislikes failed", zap.Error(err))
-		return
-	}
-
-	// 更新 last_hot_sync_time
-	err = setLastSyncTime(c, redis.LastSyncTimeHotDLikesKey, currentTime)
-	if err != nil {
-		zap.L().Error("setLastSyncTime failed", zap.Error(err))
-		return
-	}
-	zap.L().Info("同步数据完成", zap.Int("updateNum", len(postUpdateScores)))
-	return
+	notifyAggregated(&models.Notification{
+		UserID:  author,
+		ActorID: userID,
+		Type:    models.NotificationPostVote,
+		PostID:  postID,
+	})
 }
 
-// 增量更新热度
-func syncHots(c context.Context, postUpdateScores []rd.Z) error {
-	// 根据postUpdateScores中的post_id从redis中获取热度
-	idhot := redis.GetHotsByIds(c, postUpdateScores)
-
-	// 开始MySQL事务，并将数据存入mysql数据库
-	if err := mysql.BatchInsertPostHotScores(idhot); err != nil {
-		zap.L().Error("BatchInsertPostHotScores failed", zap.Error(err))
-		return err
+// RepairVoteCounters 根据投票zset重建帖子票数，并报告计数存在偏差的帖子
+func RepairVoteCounters() ([]*models.VoteCountDrift, error) {
+	c := context.Background()
+	drifts, checked, err := redis.RepairVoteCounters(c)
+	for _, d := range drifts {
+		zap.L().Warn("帖子票数存在偏差",
+			zap.String("postID", d.PostID),
+			zap.Int64("oldUps", d.OldUps), zap.Int64("ups", d.Ups),
+			zap.Int64("oldDowns", d.OldDowns), zap.Int64("downs", d.Downs))
 	}
-	return nil
-}
-
-// 增量更新点赞数
-func syncLikes(c context.Context, postUpdateScores []rd.Z) error {
-	// 根据postUpdateScores中的post_id从redis中获取点赞数
-	idlikes := redis.GetLikesByIds(c, postUpdateScores)
-
-	// 开始MySQL事务，并将数据存入mysql数据库
-	if err := mysql.BatchInsertLikes(idlikes); err != nil {
-		zap.L().Error("BatchInsertPostHotScores failed", zap.Error(err))
-		return err
+	if err != nil {
+		zap.L().Error("redis.RepairVoteCounters failed", zap.Error(err))
+		return drifts, err
 	}
-	return nil
+	zap.L().Info("帖子票数修复完成", zap.Int("checked", checked), zap.Int("drift", len(drifts)))
+	return drifts, nil
 }
 
-// 增量更新点踩数
-func syncDislikes(c context.Context, postUpdateScores []rd.Z) error {
-	// 根据postUpdateScores中的post_id从redis中获取点赞数
-	iddislikes := redis.GetDisLikesByIds(c, postUpdateScores)
-
-	// 开始MySQL事务，并将数据存入mysql数据库
-	if err := mysql.BatchInsertDisLikes(iddislikes); err != nil {
-		zap.L().Error("BatchInsertPostHotScores failed", zap.Error(err))
-		return err
+// GetUserVotedPosts 分页获取用户投过票的帖子
+func GetUserVotedPosts(userID int64, p *models.ParamVoteList) ([]*models.ApiVotedPost, error) {
+	posts, err := mysql.GetUserVotedPosts(userID, p)
+	if err != nil {
+		zap.L().Error("mysql.GetUserVotedPosts failed", zap.Error(err))
+		return nil, err
 	}
-	return nil
+	return posts, nil
 }
@@ -0,0 +1,254 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"context"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"strconv"
+	"time"
+)
+
+// 投票数据同步 Redis → MySQL
+/*
+	1. 投票时帖子被标记到待同步集合 post:dirty
+	2. 同步时分批取出最早的帖子，一批数据在一个 MySQL 事务中写入，失败时按指数退避重试
+	3. 提交成功后才确认移出待同步集合，中途失败或崩溃后下次同步从未确认的帖子继续
+	4. 每批提交后记录进度，并报告仍待同步的数量和延迟
+	5. 评论的点赞数和点踩数以同样的方式从 comment:dirty 同步到 comments 表
+	6. 投票时写入 post_vote 失败的用户投票记录在 post:vote_retry，同步时按 redis 中的当前投票重放
+*/
+
+const syncLockTTL = 10 * time.Minute
+
+// syncParam 读取同步参数，未配置时使用默认值
+func syncParam(key string, def int) int {
+	if !viper.IsSet("sync." + key) {
+		return def
+	}
+	return viper.GetInt("sync." + key)
+}
+
+// retryWithBackoff 执行fn，失败时按指数退避重试
+func retryWithBackoff(name string, fn func() error) (err error) {
+	retries := syncParam("max_retries", 3)
+	backoff := time.Duration(syncParam("retry_backoff_ms", 500)) * time.Millisecond
+	for i := 0; ; i++ {
+		if err = fn(); err == nil || i >= retries {
+			return
+		}
+		zap.L().Warn("同步失败，准备重试", zap.String("step", name), zap.Int("retry", i+1), zap.Duration("backoff", backoff), zap.Error(err))
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// SyncHotsDLikesToMySQL 将待同步帖子的热度、点赞数和点踩数同步到 MySQL
+func SyncHotsDLikesToMySQL() {
+	c := context.Background()
+	// 多个实例同时运行定时任务时只有一个实例执行同步
+	token, ok, err := redis.AcquireSyncLock(c, syncLockTTL)
+	if err != nil {
+		zap.L().Error("redis.AcquireSyncLock failed", zap.Error(err))
+		return
+	}
+	if !ok {
+		zap.L().Info("其他实例正在同步投票数据")
+		return
+	}
+	defer redis.ReleaseSyncLock(c, token)
+
+	cp, err := redis.GetSyncCheckpoint(c)
+	if err != nil {
+		zap.L().Error("redis.GetSyncCheckpoint failed", zap.Error(err))
+		cp = new(models.VoteSyncCheckpoint)
+	}
+	cp.LastRun = time.Now().Unix()
+	cp.LastSynced = 0
+
+	batchSize := int64(syncParam("batch_size", 500))
+	maxBatches := syncParam("max_batches", 100)
+	var failed bool
+	for i := 0; i < maxBatches; i++ {
+		batch, err := redis.GetDirtyPosts(c, batchSize)
+		if err != nil {
+			zap.L().Error("redis.GetDirtyPosts failed", zap.Error(err))
+			failed = true
+			break
+		}
+		if len(batch) == 0 {
+			break
+		}
+		ids := make([]string, len(batch))
+		for j, item := range batch {
+			ids[j] = item.ID
+		}
+		data, err := redis.GetPostSyncData(c, ids)
+		if err != nil {
+			zap.L().Error("redis.GetPostSyncData failed", zap.Error(err))
+			failed = true
+			break
+		}
+		if err := retryWithBackoff("mysql.SyncPostVotes", func() error { return mysql.SyncPostVotes(data) }); err != nil {
+			zap.L().Error("mysql.SyncPostVotes failed", zap.Int("batch", len(data)), zap.Error(err))
+			failed = true
+			break
+		}
+		// MySQL 已提交，确认这一批帖子
+		if err := retryWithBackoff("redis.AckDirtyPosts", func() error {
+			_, err := redis.AckDirtyPosts(c, batch)
+			return err
+		}); err != nil {
+			// 未确认的帖子下次会重复同步，写入是幂等的
+			zap.L().Error("redis.AckDirtyPosts failed", zap.Error(err))
+			failed = true
+			break
+		}
+		cp.LastSuccess = time.Now().Unix()
+		cp.LastSynced += int64(len(data))
+		cp.TotalSynced += int64(len(data))
+		if err := redis.SaveSyncCheckpoint(c, cp); err != nil {
+			zap.L().Warn("redis.SaveSyncCheckpoint failed", zap.Error(err))
+		}
+		if int64(len(batch)) < batchSize {
+			break
+		}
+	}
+
+	if err := replayPendingPostVotes(c, batchSize, maxBatches); err != nil {
+		failed = true
+	}
+
+	commentSynced, err := syncCommentVotes(c, batchSize, maxBatches)
+	if err != nil {
+		failed = true
+	}
+
+	if failed {
+		cp.Failures++
+	} else {
+		cp.Failures = 0
+	}
+	// 报告同步落后的程度
+	pending, oldest, err := redis.GetDirtyStats(c)
+	if err != nil {
+		zap.L().Error("redis.GetDirtyStats failed", zap.Error(err))
+	}
+	cp.Pending, cp.Lag = pending, 0
+	if pending > 0 {
+		cp.Lag = time.Now().Unix() - oldest
+	}
+	if err := redis.SaveSyncCheckpoint(c, cp); err != nil {
+		zap.L().Warn("redis.SaveSyncCheckpoint failed", zap.Error(err))
+	}
+	zap.L().Info("同步数据完成",
+		zap.Int64("updateNum", cp.LastSynced),
+		zap.Int64("commentNum", commentSynced),
+		zap.Int64("pending", cp.Pending),
+		zap.Int64("lagSeconds", cp.Lag),
+		zap.Int64("failures", cp.Failures))
+}
+
+// replayPendingPostVotes 把投票时写入 MySQL 失败的用户投票按 redis 中的当前投票重新写入 post_vote
+func replayPendingPostVotes(c context.Context, batchSize int64, maxBatches int) error {
+	for i := 0; i < maxBatches; i++ {
+		postIDs, userIDs, err := redis.PopPendingPostVotes(c, batchSize)
+		if err != nil {
+			zap.L().Error("redis.PopPendingPostVotes failed", zap.Error(err))
+			return err
+		}
+		for j := range postIDs {
+			if err := replayPostVote(c, postIDs[j], userIDs[j]); err != nil {
+				zap.L().Error("replayPostVote failed", zap.String("postID", postIDs[j]), zap.String("userID", userIDs[j]), zap.Error(err))
+				// 放回集合，下次同步继续重放
+				if err := redis.AddPendingPostVote(c, postIDs[j], userIDs[j]); err != nil {
+					zap.L().Error("redis.AddPendingPostVote failed", zap.String("postID", postIDs[j]), zap.String("userID", userIDs[j]), zap.Error(err))
+				}
+				return err
+			}
+		}
+		if int64(len(postIDs)) < batchSize {
+			break
+		}
+	}
+	return nil
+}
+
+// replayPostVote 把用户在 redis 中对帖子的当前投票写入 post_vote
+func replayPostVote(c context.Context, postID, userID string) error {
+	pid, err := strconv.ParseInt(postID, 10, 64)
+	if err != nil {
+		return err
+	}
+	uid, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		return err
+	}
+	votes, err := redis.GetUserVotes(c, userID, []string{postID})
+	if err != nil {
+		return err
+	}
+	return retryWithBackoff("mysql.SavePostVote", func() error { return mysql.SavePostVote(uid, pid, votes[0]) })
+}
+
+// syncCommentVotes 将待同步评论的点赞数和点踩数同步到 MySQL，返回同步的评论数量
+func syncCommentVotes(c context.Context, batchSize int64, maxBatches int) (int64, error) {
+	var synced int64
+	for i := 0; i < maxBatches; i++ {
+		batch, err := redis.GetDirtyComments(c, batchSize)
+		if err != nil {
+			zap.L().Error("redis.GetDirtyComments failed", zap.Error(err))
+			return synced, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		ids := make([]string, len(batch))
+		for j, item := range batch {
+			ids[j] = item.ID
+		}
+		data, err := redis.GetCommentSyncData(c, ids)
+		if err != nil {
+			zap.L().Error("redis.GetCommentSyncData failed", zap.Error(err))
+			return synced, err
+		}
+		if err := retryWithBackoff("mysql.SyncCommentVotes", func() error { return mysql.SyncCommentVotes(data) }); err != nil {
+			zap.L().Error("mysql.SyncCommentVotes failed", zap.Int("batch", len(data)), zap.Error(err))
+			return synced, err
+		}
+		if err := retryWithBackoff("redis.AckDirtyComments", func() error {
+			_, err := redis.AckDirtyComments(c, batch)
+			return err
+		}); err != nil {
+			zap.L().Error("redis.AckDirtyComments failed", zap.Error(err))
+			return synced, err
+		}
+		synced += int64(len(data))
+		if int64(len(batch)) < batchSize {
+			break
+		}
+	}
+	return synced, nil
+}
+
+// GetVoteSyncStatus 获取投票同步的进度及当前延迟
+func GetVoteSyncStatus() (*models.VoteSyncCheckpoint, error) {
+	c := context.Background()
+	cp, err := redis.GetSyncCheckpoint(c)
+	if err != nil {
+		zap.L().Error("redis.GetSyncCheckpoint failed", zap.Error(err))
+		return nil, err
+	}
+	pending, oldest, err := redis.GetDirtyStats(c)
+	if err != nil {
+		zap.L().Error("redis.GetDirtyStats failed", zap.Error(err))
+		return nil, err
+	}
+	cp.Pending, cp.Lag = pending, 0
+	if pending > 0 {
+		cp.Lag = time.Now().Unix() - oldest
+	}
+	return cp, nil
+}
@@ -0,0 +1,138 @@
+package logic
+
+import (
+	"bluebell/dao/redis"
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/spf13/viper"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// redisKeyPrefix 与 dao/redis 中的 key 前缀保持一致
+const redisKeyPrefix = "bluebell:"
+
+// setupMiniRedis 启动 miniredis 并让 dao/redis 连接到它
+func setupMiniRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	port, err := strconv.Atoi(mr.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("redis.host", mr.Host())
+	viper.Set("redis.port", port)
+	if err := redis.Init(); err != nil {
+		t.Fatalf("redis.Init error: %v", err)
+	}
+	t.Cleanup(redis.Close)
+	return mr
+}
+
+// scoreClose 比较 lua 脚本和 Go 计算出的分数，脚本的分数经过字符串转换，允许极小的误差
+func scoreClose(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+func zscore(t *testing.T, mr *miniredis.Miniredis, key, member string) float64 {
+	t.Helper()
+	score, err := mr.ZScore(redisKeyPrefix+key, member)
+	if err != nil {
+		t.Fatalf("ZScore(%s, %s) error: %v", key, member, err)
+	}
+	return score
+}
+
+// TestVoteScriptMatchesRankScores 投票脚本在 redis 中算出的分数与 computeRankScores 一致
+func TestVoteScriptMatchesRankScores(t *testing.T) {
+	mr := setupMiniRedis(t)
+	c := context.Background()
+
+	const (
+		postID      = "1001"
+		communityID = "7"
+		now         = int64(1750000000)
+	)
+	tests := []struct {
+		name     string
+		age      int64 // 发帖时长，秒
+		userID   string
+		dir      float64
+		wantUps  int64
+		wantDown int64
+		changed  bool
+	}{
+		{"first up vote", 3 * 3600, "1", 1, 1, 0, true},
+		{"second up vote", 3 * 3600, "2", 1, 2, 0, true},
+		{"down vote", 3 * 3600, "3", -1, 2, 1, true},
+		{"repeat vote", 3 * 3600, "3", -1, 2, 1, false},
+		{"change up to down", 3 * 3600, "1", -1, 1, 2, true},
+		{"cancel vote", 3 * 3600, "2", 0, 0, 2, true},
+		{"up vote", 3 * 3600, "4", 1, 1, 2, true},
+	}
+	for _, tt := range tests {
+		createTime := now - tt.age
+		rp := rankParams(createTime, now)
+		changed, ups, downs, err := redis.VoteForPost(c, tt.userID, postID, communityID, tt.dir, createTime, rp)
+		if err != nil {
+			t.Fatalf("%s: VoteForPost error: %v", tt.name, err)
+		}
+		if changed != tt.changed || ups != tt.wantUps || downs != tt.wantDown {
+			t.Fatalf("%s: VoteForPost = (%v, %d, %d), want (%v, %d, %d)",
+				tt.name, changed, ups, downs, tt.changed, tt.wantUps, tt.wantDown)
+		}
+
+		want := computeRankScores(ups, downs, createTime, now)
+		checks := []struct {
+			key  string
+			want float64
+		}{
+			{redis.KeyPostScoreZSet, want.Hot},
+			{redis.KeyPostControversialZSet, want.Controversial},
+			{redis.KeyPostBestZSet, want.Best},
+			{redis.KeyPostGravityZSet, want.Gravity},
+			{redis.KeyCommunityScoreZSetPrefix + communityID, want.Hot},
+		}
+		for _, window := range want.TopWindows {
+			checks = append(checks, struct {
+				key  string
+				want float64
+			}{redis.KeyPostTopZSetPrefix + window, want.Net})
+		}
+		for _, ck := range checks {
+			if got := zscore(t, mr, ck.key, postID); !scoreClose(got, ck.want) {
+				t.Errorf("%s: %s score = %v, want %v", tt.name, ck.key, got, ck.want)
+			}
+		}
+	}
+}
+
+// TestVoteScriptTopWindows 只有帖子仍处于的时间窗口才会写入 top 排序
+func TestVoteScriptTopWindows(t *testing.T) {
+	mr := setupMiniRedis(t)
+	c := context.Background()
+
+	const now = int64(1750000000)
+	createTime := now - 10*24*3600
+	rp := rankParams(createTime, now)
+	if _, _, _, err := redis.VoteForPost(c, "1", "2002", "7", 1, createTime, rp); err != nil {
+		t.Fatalf("VoteForPost error: %v", err)
+	}
+	for window := range TopWindows {
+		key := redisKeyPrefix + redis.KeyPostTopZSetPrefix + window
+		_, err := mr.ZScore(key, "2002")
+		inWindow := false
+		for _, w := range rp.TopWindows {
+			if w == window {
+				inWindow = true
+			}
+		}
+		if inWindow && err != nil {
+			t.Errorf("post missing from top window %s: %v", window, err)
+		}
+		if !inWindow && err == nil {
+			t.Errorf("post should not be in top window %s", window)
+		}
+	}
+}
@@ -6,10 +6,13 @@ import (
 	"bluebell/dao/redis"
 	"bluebell/logger"
 	"bluebell/logic"
+	"bluebell/pkg/email"
+	"bluebell/pkg/sensitive"
 	"bluebell/pkg/snowflake"
 	"bluebell/routes"
 	"bluebell/settings"
 	"context"
+	"flag"
 	"fmt"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -35,6 +38,11 @@ import (
 // @host 这里写接口服务的host
 // @BasePath 这里写base path
 func main() {
+	// -rebuild: 从 MySQL 重建 redis 中的帖子排序数据后退出，-from-scratch 忽略上次中断的进度
+	rebuild := flag.Bool("rebuild", false, "rebuild redis ranking data from mysql and exit")
+	fromScratch := flag.Bool("from-scratch", false, "ignore the rebuild checkpoint and start over")
+	flag.Parse()
+
 	// 1.加载配置
 	if err := settings.Init(); err != nil {
 		fmt.Printf("init settings failed, err:%v\n", err)
@@ -49,6 +57,16 @@ func main() {
 	zap.L().Debug("Logger init success...")
 	defer zap.L().Sync()
 
+	// 加载敏感词库，词库文件变化时自动重新加载
+	if err := sensitive.Init(viper.GetStringSlice("sensitive.word_files")); err != nil {
+		zap.L().Error("init sensitive words failed", zap.Error(err))
+		return
+	}
+
+	// 发送邮箱验证码的 SMTP 服务器
+	email.Init(viper.GetString("email.host"), viper.GetInt("email.port"),
+		viper.GetString("email.username"), viper.GetString("email.password"), viper.GetString("email.from"))
+
 	// 3.初始化Mysql
 	defer mysql.Close()
 	if err := mysql.Init(); err != nil {
@@ -63,6 +81,21 @@ func main() {
 		return
 	}
 
+	// 重建 redis 排序数据
+	if *rebuild {
+		total, err := logic.RebuildRedisRanking(*fromScratch)
+		if err != nil {
+			zap.L().Error("rebuild redis ranking failed", zap.Int("total", total), zap.Error(err))
+			return
+		}
+		fmt.Printf("rebuild redis ranking success, total posts: %d\n", total)
+		return
+	}
+	// redis 数据丢失时从 MySQL 重建
+	logic.CheckRedisRanking()
+	// 订阅 redis 频道，向本实例上的客户端推送实时事件
+	logic.StartRealtimeHub()
+
 	//// 5. 初始化postgresql
 	//defer postgresql.Close()
 	//if err := postgresql.Init(); err != nil {
@@ -93,6 +126,8 @@ func main() {
 		Addr:    fmt.Sprintf(":%d", viper.GetInt("app.port")),
 		Handler: r,
 	}
+	// 关机时结束全部实时事件流，否则长连接会让优雅关机一直等到超时
+	srv.RegisterOnShutdown(logic.StopRealtimeHub)
 
 	go func() {
 		// 开启一个goroutine启动服务
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"bluebell/controllers"
+	"bluebell/logic"
+	"bluebell/models"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"strings"
+)
+
+// truncate 按字符截断，避免超出数据库字段长度
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
+// Audit 记录审计日志的中间件，处理函数返回后把操作人、操作对象、结果、IP 和 User-Agent 追加到审计日志
+// 操作对象取路径参数 targetParam，不在路径中时由处理函数指定，其他路径参数记入说明；
+// targetType 为 user 且没有操作对象时，对象是操作人自己
+func Audit(action, targetType, targetParam string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.Next()
+
+		l := &models.AuditLog{
+			Action:     action,
+			TargetType: targetType,
+			Code:       int64(controllers.CodeServerBusy),
+			IP:         c.ClientIP(),
+			UserAgent:  truncate(c.Request.UserAgent(), 255),
+		}
+		if code, ok := c.Get(controllers.CtxResponseCodeKey); ok {
+			if rc, ok := code.(controllers.ResCode); ok {
+				l.Code = int64(rc)
+				l.Success = rc == controllers.CodeSuccess
+			}
+		}
+		if uid, ok := c.Get(controllers.CtxUserIDKey); ok {
+			l.ActorID, _ = uid.(int64)
+		} else if uid, ok := c.Get(controllers.CtxAuditActorKey); ok {
+			l.ActorID, _ = uid.(int64)
+		}
+
+		details := make([]string, 0, len(c.Params)+1)
+		if targetParam != "" {
+			l.TargetID, _ = strconv.ParseInt(c.Param(targetParam), 10, 64)
+		} else if target, ok := c.Get(controllers.CtxAuditTargetKey); ok {
+			l.TargetID, _ = target.(int64)
+		}
+		for _, param := range c.Params {
+			if param.Key != targetParam {
+				details = append(details, param.Key+"="+param.Value)
+			}
+		}
+		if l.TargetID == 0 && targetType == models.AuditTargetUser {
+			l.TargetID = l.ActorID
+		}
+		if detail, ok := c.Get(controllers.CtxAuditDetailKey); ok {
+			if s, _ := detail.(string); s != "" {
+				details = append(details, s)
+			}
+		}
+		l.Detail = truncate(strings.Join(details, " "), 500)
+
+		logic.RecordAudit(l)
+	}
+}
@@ -3,9 +3,12 @@ package middleware
 import (
 	"bluebell/controllers"
 	"bluebell/dao/mysql"
+	"bluebell/logic"
 	"bluebell/pkg/jwt"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"strings"
+	"time"
 )
 
 // JWTAuthMiddleware 基于JWT的认证中间件
@@ -19,6 +22,11 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 
 		// 检查获取到的 Authorization 头是否为空，以及格式是否正确。
 		authHeader := c.Request.Header.Get("Authorization")
+		// 浏览器的 EventSource 不能设置请求头，SSE 请求使用URI中ticket参数的一次性凭证，Token不放在URI中
+		if authHeader == "" && c.GetHeader("Accept") == "text/event-stream" && c.Query("ticket") != "" {
+			streamTicketAuth(c)
+			return
+		}
 		if authHeader == "" {
 			controllers.ResponseError(c, controllers.CodeNeedLogin)
 			c.Abort()
@@ -47,8 +55,54 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 			c.Abort()
 			return
 		}
+		// 被封禁的用户和封禁前签发的Token不能继续使用
+		var issuedAt time.Time
+		if mc.IssuedAt != nil {
+			issuedAt = mc.IssuedAt.Time
+		}
+		if err := logic.CheckUserToken(c, mc.Userid, issuedAt); err != nil {
+			if errors.Is(err, logic.ErrUserSuspended) {
+				controllers.ResponseError(c, controllers.CodeUserSuspended)
+			} else {
+				controllers.ResponseError(c, controllers.CodeInvalidToken)
+			}
+			c.Abort()
+			return
+		}
 		// 将当前请求的userid信息保存到请求的上下文c上
 		c.Set(controllers.CtxUserIDKey, mc.Userid)
 		c.Next() // 后续的处理函数可以用过c.Get(controllers.CtxUserIDKey)来获取当前请求的用户信息
 	}
 }
+
+// streamTicketAuth 用一次性凭证认证建立事件流的请求
+func streamTicketAuth(c *gin.Context) {
+	userID, err := logic.RedeemStreamTicket(c, c.Query("ticket"))
+	if err != nil {
+		controllers.ResponseError(c, controllers.CodeInvalidToken)
+		c.Abort()
+		return
+	}
+	// 凭证有效期内被封禁的用户不能建立事件流
+	if err := logic.CheckUserNotSuspended(c, userID); err != nil {
+		controllers.ResponseError(c, controllers.CodeUserSuspended)
+		c.Abort()
+		return
+	}
+	c.Set(controllers.CtxUserIDKey, userID)
+	c.Next()
+}
+
+// AdminAuthMiddleware 管理后台的中间件，只有站点管理员可以访问，需要在 JWTAuthMiddleware 之后使用
+func AdminAuthMiddleware() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userID, _ := c.Get(controllers.CtxUserIDKey)
+		uid, _ := userID.(int64)
+		if !logic.IsSiteAdmin(uid) {
+			controllers.ResponseError(c, controllers.CodeNoPermission)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
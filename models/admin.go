@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// 用户的全站角色
+const (
+	UserRoleUser  int8 = 0
+	UserRoleAdmin int8 = 1
+)
+
+// AdminUser 管理后台中的用户信息
+type AdminUser struct {
+	UserID        int64     `json:"user_id,string" db:"user_id"`
+	Username      string    `json:"username" db:"username"`
+	Email         string    `json:"email" db:"email"`
+	EmailVerified bool      `json:"email_verified" db:"email_verified"`
+	Role          int8      `json:"role" db:"role"`
+	CreateTime    time.Time `json:"create_time" db:"create_time"`
+	UpdateTime    time.Time `json:"update_time" db:"update_time"`
+}
+
+// AdminUserDetail 管理后台中用户的完整记录
+type AdminUserDetail struct {
+	*AdminUser
+	Trust        *UserTrust      `json:"trust"`
+	Sanctions    []*UserSanction `json:"sanctions"`
+	PostCount    int64           `json:"post_count"`
+	CommentCount int64           `json:"comment_count"`
+	OpenReports  int64           `json:"open_reports"` // 针对该用户的内容尚未处理的举报单数量
+	RecentAudit  []*AuditLog     `json:"recent_audit"` // 该用户最近的操作
+}
+
+// MaintenanceJob 维护任务的运行状态
+type MaintenanceJob struct {
+	Name       string     `json:"name"`
+	Running    bool       `json:"running"`
+	LastStart  *time.Time `json:"last_start,omitempty"`
+	LastFinish *time.Time `json:"last_finish,omitempty"`
+	LastResult string     `json:"last_result,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+// MaintenanceStatus 全部维护任务的状态及投票同步进度
+type MaintenanceStatus struct {
+	Jobs     []*MaintenanceJob   `json:"jobs"`
+	VoteSync *VoteSyncCheckpoint `json:"vote_sync"`
+}
+
+// SeedResult 生成测试数据的结果，已存在的测试用户和社区会被复用，不计入数量
+type SeedResult struct {
+	Users       int `json:"users"`
+	Communities int `json:"communities"`
+	Posts       int `json:"posts"`
+}
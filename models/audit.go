@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// 审计日志记录的操作
+const (
+	AuditLogin          = "login"
+	AuditLogout         = "logout"
+	AuditPasswordChange = "password_change"
+	AuditUserUpdate     = "user_update"
+
+	AuditCommunityCreate    = "community_create"
+	AuditCommunityUpdate    = "community_update"
+	AuditCommunitySettings  = "community_settings"
+	AuditCommunityArchive   = "community_archive"
+	AuditCommunityUnarchive = "community_unarchive"
+	AuditJoinApprove        = "join_approve"
+	AuditJoinReject         = "join_reject"
+
+	AuditModeratorAppoint = "moderator_appoint"
+	AuditModeratorDismiss = "moderator_dismiss"
+	AuditCommunityBan     = "community_ban"
+	AuditCommunityUnban   = "community_unban"
+
+	AuditPostRemove    = "post_remove"
+	AuditPostPin       = "post_pin"
+	AuditPostUnpin     = "post_unpin"
+	AuditPostLock      = "post_lock"
+	AuditPostUnlock    = "post_unlock"
+	AuditCommentDelete = "comment_delete"
+	AuditCommentPin    = "comment_pin"
+	AuditCommentUnpin  = "comment_unpin"
+	AuditReportResolve = "report_resolve"
+	AuditReportDismiss = "report_dismiss"
+
+	AuditUserSuspend     = "user_suspend"
+	AuditUserUnsuspend   = "user_unsuspend"
+	AuditUserShadowBan   = "user_shadow_ban"
+	AuditUserUnshadowBan = "user_unshadow_ban"
+	AuditUserRoleChange  = "user_role_change"
+	AuditPasswordReset   = "password_reset"
+
+	AuditPostForceDelete    = "post_force_delete"
+	AuditCommentForceDelete = "comment_force_delete"
+	AuditCommunityForceEdit = "community_force_edit"
+	AuditJobRun             = "job_run"
+)
+
+// 审计日志的操作对象
+const (
+	AuditTargetUser      = "user"
+	AuditTargetCommunity = "community"
+	AuditTargetPost      = "post"
+	AuditTargetComment   = "comment"
+	AuditTargetReport    = "report"
+)
+
+// AuditLog 一条审计日志，只能追加，不能修改和删除
+type AuditLog struct {
+	ID         int64     `json:"id,string" db:"id"`
+	ActorID    int64     `json:"actor_id,string" db:"actor_id"` // 操作人，登录失败时为0
+	Action     string    `json:"action" db:"action"`
+	TargetType string    `json:"target_type" db:"target_type"`
+	TargetID   int64     `json:"target_id,string" db:"target_id"`
+	Success    bool      `json:"success" db:"success"`
+	Code       int64     `json:"code" db:"code"` // 接口返回的业务状态码
+	Detail     string    `json:"detail" db:"detail"`
+	IP         string    `json:"ip" db:"ip"`
+	UserAgent  string    `json:"user_agent" db:"user_agent"`
+	CreateTime time.Time `json:"create_time" db:"create_time"`
+}
@@ -1,20 +1,67 @@
 package models
 
+import "time"
+
 // Comment 用于绑定评论相关的请求数据
 type Comment struct {
-	ID         int64  `json:"-" db:"id" form:"id" binding:"omitempty"`                         // 评论 ID，数据库自增主键
-	CommentID  int64  `json:"comment_id" db:"comment_id" form:"comment_id" binding:"required"` // 评论的唯一标识
-	PostID     int64  `json:"post_id" db:"post_id" form:"post_id" binding:"required"`          // 帖子 ID，必填
-	ParentID   *int64 `json:"parent_id" db:"parent_id" form:"parent_id"`                       // 父评论 ID，可空
-	UserID     int64  `json:"user_id" db:"user_id" form:"user_id" binding:"required"`          // 用户 ID，必填
-	Content    string `json:"content" db:"content" form:"content" binding:"required"`          // 评论内容，必填
-	Likes      int    `json:"likes" db:"likes" form:"likes" binding:"required"`                // 点赞数，默认为 0
-	Dislikes   int    `json:"dislikes" db:"dislikes" form:"dislikes" binding:"required"`       // 点踩数，默认为 0
-	Status     int8   `json:"status" db:"status" form:"status" binding:"required"`             // 评论状态，默认为 1
-	CreateTime string `json:"create_time" db:"create_time" form:"create_time"`                 // 创建时间
-	UpdateTime string `json:"update_time" db:"update_time" form:"update_time"`                 // 更新时间
+	ID         int64      `json:"-" db:"id" form:"id" binding:"omitempty"`                         // 评论 ID，数据库自增主键
+	CommentID  int64      `json:"comment_id" db:"comment_id" form:"comment_id" binding:"required"` // 评论的唯一标识
+	PostID     int64      `json:"post_id" db:"post_id" form:"post_id" binding:"required"`          // 帖子 ID，必填
+	ParentID   *int64     `json:"parent_id" db:"parent_id" form:"parent_id"`                       // 父评论 ID，可空
+	UserID     int64      `json:"user_id" db:"user_id" form:"user_id" binding:"required"`          // 用户 ID，必填
+	Content    string     `json:"content" db:"content" form:"content" binding:"required"`          // 评论内容，必填
+	Likes      int        `json:"likes" db:"likes" form:"likes" binding:"required"`                // 点赞数，默认为 0
+	Dislikes   int        `json:"dislikes" db:"dislikes" form:"dislikes" binding:"required"`       // 点踩数，默认为 0
+	Status     int8       `json:"status" db:"status" form:"status" binding:"required"`             // 评论状态，默认为 1
+	CreateTime string     `json:"create_time" db:"create_time" form:"create_time"`                 // 创建时间
+	UpdateTime string     `json:"update_time" db:"update_time" form:"update_time"`                 // 更新时间
+	IsTop      int8       `json:"is_top" db:"is_top"`                                              // 是否置顶
+	TopTime    *time.Time `json:"-" db:"top_time"`                                                 // 置顶时间
+	Path       string     `json:"-" db:"path"`                                                     // 物化路径，由根评论到当前评论的id组成，如 /1/2/3/
+	Depth      int        `json:"depth" db:"depth"`                                                // 评论层级，顶级评论为 0
+	EditTime   *string    `json:"edit_time" db:"edit_time"`                                        // 最后一次编辑时间，未编辑过为 null
+
+	Mentions        []*ApiMention `json:"mentions,omitempty" db:"-"`         // 内容中提及的用户
+	RenderedContent string        `json:"rendered_content,omitempty" db:"-"` // 提及渲染为链接后的内容
+}
+
+// 评论状态
+const (
+	CommentStatusDeleted int8 = 0 // 作者删除
+	CommentStatusNormal  int8 = 1 // 正常
+	CommentStatusRemoved int8 = 2 // 帖子作者或版主移除
+	CommentStatusHidden  int8 = 3 // 被举报次数达到上限后自动隐藏，等待版主处理
+)
+
+// ParamEditComment 编辑评论的请求参数
+type ParamEditComment struct {
+	CommentID int64  `json:"comment_id,string" binding:"required"`
+	Content   string `json:"content" binding:"required"`
 }
 
 type ParamDeleteComment struct {
-	CommentID int64 `json:"comment_id,string" db:"comment_id" form:"comment_id" binding:"required"`
+	CommentID int64  `json:"comment_id,string" db:"comment_id" form:"comment_id" binding:"required"`
+	Reason    string `json:"reason" form:"reason" binding:"max=200"` // 版主移除评论时记录在管理日志中
+}
+
+// CommentSyncData 需要同步到 MySQL 的评论票数
+type CommentSyncData struct {
+	CommentID string
+	Likes     int64 `redis:"up"`
+	Dislikes  int64 `redis:"down"`
+}
+
+// ApiCommentNode 评论树的节点
+type ApiCommentNode struct {
+	*Comment
+	Replies   []*ApiCommentNode `json:"replies"`        // 已加载的子评论
+	MoreCount int               `json:"more_count"`     // 未加载的子评论数量
+	More      string            `json:"more,omitempty"` // 继续加载子评论的令牌
+}
+
+// ApiCommentTree 帖子的评论树
+type ApiCommentTree struct {
+	Comments  []*ApiCommentNode `json:"comments"`
+	MoreCount int               `json:"more_count"`     // 未加载的顶级评论数量
+	More      string            `json:"more,omitempty"` // 继续加载顶级评论的令牌
 }
@@ -2,17 +2,64 @@ package models
 
 import "time"
 
+// 社区成员的角色
+const (
+	CommunityRoleNone      int8 = -1 // 不是社区成员
+	CommunityRoleMember    int8 = 0
+	CommunityRoleModerator int8 = 1
+	CommunityRoleOwner     int8 = 2
+)
+
+// 社区的可见性
+const (
+	CommunityPublic     int8 = 0 // 任何人可以浏览和发帖
+	CommunityRestricted int8 = 1 // 任何人可以浏览，只有成员可以发帖和评论
+	CommunityPrivate    int8 = 2 // 只有成员可以浏览，需要邀请或版主批准才能加入
+)
+
+// 加入社区申请的状态
+const (
+	JoinRequestPending  int8 = 0
+	JoinRequestApproved int8 = 1 // 版主批准或邀请
+	JoinRequestRejected int8 = 2
+)
+
+// 版主操作，记录在社区的管理日志中
+const (
+	ModActionAppointModerator = "appoint_moderator"
+	ModActionDismissModerator = "dismiss_moderator"
+	ModActionRemovePost       = "remove_post"
+	ModActionRemoveComment    = "remove_comment"
+	ModActionPinPost          = "pin_post"
+	ModActionUnpinPost        = "unpin_post"
+	ModActionLockPost         = "lock_post"
+	ModActionUnlockPost       = "unlock_post"
+	ModActionBanUser          = "ban_user"
+	ModActionUnbanUser        = "unban_user"
+	ModActionUpdateSettings   = "update_settings"
+	ModActionInviteMember     = "invite_member"
+	ModActionApproveMember    = "approve_member"
+	ModActionRejectMember     = "reject_member"
+	ModActionArchive          = "archive"
+	ModActionUnarchive        = "unarchive"
+)
+
 type Community struct {
-	ID   int64  `json:"id" db:"community_id"`
-	Name string `json:"name" db:"community_name"`
+	ID          int64  `json:"id" db:"community_id"`
+	Name        string `json:"name" db:"community_name"`
+	MemberCount int64  `json:"member_count" db:"member_count"`
 }
 
 type CommunityDetail struct {
-	ID           int64     `json:"commid,string" db:"community_id" binding:"required"`
-	Name         string    `json:"commname" db:"community_name" binding:"required"`
-	Introduction string    `json:"introduction,omitempty" db:"introduction"`
-	CreateTime   time.Time `json:"create_time" db:"create_time"`
-	UpdateTime   time.Time `json:"update_time" db:"update_time"`
+	ID           int64  `json:"commid,string" db:"community_id" binding:"required"`
+	Name         string `json:"commname" db:"community_name" binding:"required"`
+	Introduction string `json:"introduction,omitempty" db:"introduction"`
+	MemberCount  int64  `json:"member_count" db:"member_count"`
+	OwnerID      int64  `json:"owner_id,string" db:"owner_id"`
+
+	Settings   *CommunitySettings `json:"settings,omitempty" db:"-"`
+	CreateTime time.Time          `json:"create_time" db:"create_time"`
+	UpdateTime time.Time          `json:"update_time" db:"update_time"`
 }
 
 type CommPosts struct {
@@ -25,3 +72,83 @@ type CommPosts struct {
 	//Content     string    `json:"content" db:"content" binding:"required"`
 	CreateTime time.Time `json:"create_time" db:"create_time"`
 }
+
+// CommunityModerator 社区的版主或创建者
+type CommunityModerator struct {
+	UserID     int64     `json:"user_id,string" db:"user_id"`
+	Username   string    `json:"username" db:"username"`
+	Role       int8      `json:"role" db:"role"`
+	CreateTime time.Time `json:"create_time" db:"create_time"`
+}
+
+// CommunityBan 用户在社区中被禁言的记录
+type CommunityBan struct {
+	CommunityID int64     `json:"community_id,string" db:"community_id"`
+	UserID      int64     `json:"user_id,string" db:"user_id"`
+	Username    string    `json:"username,omitempty" db:"username"`
+	OperatorID  int64     `json:"operator_id,string" db:"operator_id"`
+	Reason      string    `json:"reason" db:"reason"`
+	ExpireTime  time.Time `json:"expire_time" db:"expire_time"`
+	CreateTime  time.Time `json:"create_time" db:"create_time"`
+}
+
+// ModerationLog 社区的管理日志
+type ModerationLog struct {
+	ID           int64     `json:"id,string" db:"id"`
+	CommunityID  int64     `json:"community_id,string" db:"community_id"`
+	ModeratorID  int64     `json:"moderator_id,string" db:"moderator_id"`
+	Action       string    `json:"action" db:"action"`
+	TargetUserID int64     `json:"target_user_id,string" db:"target_user_id"`
+	PostID       int64     `json:"post_id,string" db:"post_id"`
+	CommentID    int64     `json:"comment_id,string" db:"comment_id"`
+	Reason       string    `json:"reason" db:"reason"`
+	CreateTime   time.Time `json:"create_time" db:"create_time"`
+}
+
+// CommunitySettings 社区设置，没有修改过设置的社区使用默认设置
+type CommunitySettings struct {
+	CommunityID      int64    `json:"-" db:"community_id"`
+	Rules            string   `json:"rules" db:"rules"`
+	Icon             string   `json:"icon" db:"icon"`
+	Banner           string   `json:"banner" db:"banner"`
+	PostTypes        string   `json:"-" db:"allowed_post_types"` // 逗号分隔的帖子类型
+	AllowedPostTypes []string `json:"allowed_post_types" db:"-"`
+	Visibility       int8     `json:"visibility" db:"visibility"`
+	Archived         bool     `json:"archived" db:"archived"` // 归档的社区只读，不能发帖、评论和投票
+}
+
+// CommunityJoinRequest 加入社区的申请或邀请
+type CommunityJoinRequest struct {
+	CommunityID int64     `json:"community_id,string" db:"community_id"`
+	UserID      int64     `json:"user_id,string" db:"user_id"`
+	Username    string    `json:"username,omitempty" db:"username"`
+	InviterID   int64     `json:"inviter_id,string" db:"inviter_id"` // 版主邀请时为版主id
+	Status      int8      `json:"status" db:"status"`
+	CreateTime  time.Time `json:"create_time" db:"create_time"`
+}
+
+// CommunityDailyStats 社区每天的统计数据，由定时任务预先汇总
+type CommunityDailyStats struct {
+	CommunityID int64  `json:"-" db:"community_id"`
+	Day         string `json:"day" db:"day"`
+	Posts       int64  `json:"posts" db:"posts"`
+	Comments    int64  `json:"comments" db:"comments"`
+	Votes       int64  `json:"votes" db:"votes"`
+	ActiveUsers int64  `json:"active_users" db:"active_users"` // 当天发帖、评论或投票的用户数
+	NewMembers  int64  `json:"new_members" db:"new_members"`
+	MemberCount int64  `json:"member_count" db:"member_count"` // 当天结束时的成员数
+}
+
+// CommunityActivity 社区最近一段时间和此前一段时间的活跃度（帖子、评论、投票和新成员数之和）
+type CommunityActivity struct {
+	CommunityID int64 `db:"community_id"`
+	Recent      int64 `db:"recent"`
+	Baseline    int64 `db:"baseline"`
+}
+
+// UserCommunityRate 用户在某个社区的帖子上的行为分数之和
+type UserCommunityRate struct {
+	UserID      int64   `db:"user_id"`
+	CommunityID int64   `db:"community_id"`
+	Rate        float64 `db:"rate"`
+}
@@ -6,10 +6,449 @@ CREATE TABLE `user` (
                         `password` varchar(64) COLLATE utf8mb4_general_ci NOT NULL,
                         `email` varchar(64) COLLATE utf8mb4_general_ci,
                         `gender` tinyint(4) NOT NULL DEFAULT '0',
+                        `avatar_url` varchar(255) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+                        `bio` varchar(500) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
                         `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
                         `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE
 CURRENT_TIMESTAMP,
                         PRIMARY KEY (`id`),
                         UNIQUE KEY `idx_username` (`username`) USING BTREE,
                         UNIQUE KEY `idx_user_id` (`user_id`) USING BTREE
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
\ No newline at end of file
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `community`;
+CREATE TABLE `community` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `community_id` bigint(20) NOT NULL,
+                        `community_name` varchar(128) COLLATE utf8mb4_general_ci NOT NULL,
+                        `introduction` varchar(256) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE
+CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `idx_community_id` (`community_id`) USING BTREE,
+                        UNIQUE KEY `idx_community_name` (`community_name`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `post`;
+CREATE TABLE `post` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `post_id` bigint(20) NOT NULL,
+                        `title` varchar(128) COLLATE utf8mb4_general_ci NOT NULL,
+                        `content` text COLLATE utf8mb4_general_ci NOT NULL,
+                        `author_id` bigint(20) NOT NULL,
+                        `community_id` bigint(20) NOT NULL,
+                        `status` tinyint(4) NOT NULL DEFAULT '1' COMMENT '1正常 2移除 3隐藏',
+                        `likes` bigint(20) NOT NULL DEFAULT '0',
+                        `dislikes` bigint(20) NOT NULL DEFAULT '0',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE
+CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `idx_post_id` (`post_id`) USING BTREE,
+                        KEY `idx_author_id` (`author_id`) USING BTREE,
+                        KEY `idx_community_id` (`community_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `post_hot_scores`;
+CREATE TABLE `post_hot_scores` (
+                        `post_id` bigint(20) NOT NULL,
+                        `hot_score` double NOT NULL DEFAULT '0',
+                        `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`post_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `images`;
+CREATE TABLE `images` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `post_id` bigint(20) NOT NULL,
+                        `image_url` varchar(512) COLLATE utf8mb4_general_ci NOT NULL,
+                        PRIMARY KEY (`id`),
+                        KEY `idx_post_id` (`post_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `comments`;
+CREATE TABLE `comments` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `comment_id` bigint(20) NOT NULL,
+                        `post_id` bigint(20) NOT NULL,
+                        `parent_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '顶级评论为0',
+                        `user_id` bigint(20) NOT NULL,
+                        `content` text COLLATE utf8mb4_general_ci NOT NULL,
+                        `likes` int(11) NOT NULL DEFAULT '0',
+                        `dislikes` int(11) NOT NULL DEFAULT '0',
+                        `status` tinyint(4) NOT NULL DEFAULT '1' COMMENT '0删除 1正常 2移除 3隐藏',
+                        `is_top` tinyint(1) NOT NULL DEFAULT '0',
+                        `top_time` timestamp NULL DEFAULT NULL,
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE
+CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `idx_comment_id` (`comment_id`) USING BTREE,
+                        KEY `idx_post_id` (`post_id`) USING BTREE,
+                        KEY `idx_parent_id` (`parent_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `user_post_behavior`;
+CREATE TABLE `user_post_behavior` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `user_id` bigint(20) NOT NULL,
+                        `post_id` bigint(20) NOT NULL,
+                        `rate` tinyint(4) NOT NULL DEFAULT '0',
+                        `browse` tinyint(4) NOT NULL DEFAULT '0',
+                        `like` tinyint(4) NOT NULL DEFAULT '0',
+                        `comment` tinyint(4) NOT NULL DEFAULT '0',
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `idx_user_post` (`user_id`, `post_id`) USING BTREE,
+                        KEY `idx_post_id` (`post_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- 服务启动时 GORM 也会按 models.JWTBlacklist 自动建表
+DROP TABLE IF EXISTS `jwt_blacklists`;
+CREATE TABLE `jwt_blacklists` (
+                        `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+                        `token` varchar(512) COLLATE utf8mb4_general_ci DEFAULT NULL,
+                        `created_at` datetime(3) DEFAULT NULL,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `idx_jwt_blacklists_token` (`token`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `post_vote`;
+CREATE TABLE `post_vote` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `post_id` bigint(20) NOT NULL,
+                        `user_id` bigint(20) NOT NULL,
+                        `direction` tinyint(4) NOT NULL COMMENT '1赞成 -1反对',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE
+CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `idx_post_user` (`post_id`, `user_id`) USING BTREE,
+                        KEY `idx_user_id` (`user_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `post_vote_event`;
+CREATE TABLE `post_vote_event` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `post_id` bigint(20) NOT NULL,
+                        `user_id` bigint(20) NOT NULL,
+                        `old_direction` tinyint(4) NOT NULL DEFAULT '0',
+                        `direction` tinyint(4) NOT NULL COMMENT '1赞成 0取消 -1反对',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`id`),
+                        KEY `idx_user_time` (`user_id`, `create_time`) USING BTREE,
+                        KEY `idx_post_id` (`post_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- 评论的物化路径，一次查询即可取出整棵子树
+ALTER TABLE `comments`
+    ADD COLUMN `path` varchar(2000) CHARACTER SET ascii NOT NULL DEFAULT '' COMMENT '由根评论到当前评论的id组成，如 /1/2/3/',
+    ADD COLUMN `depth` int(11) NOT NULL DEFAULT '0' COMMENT '评论层级，顶级评论为0',
+    ADD KEY `idx_post_path` (`post_id`, `path`(255));
+
+-- 为已有评论生成物化路径
+UPDATE `comments` c JOIN (
+    WITH RECURSIVE t AS (
+        SELECT `comment_id`, CAST(CONCAT('/', `comment_id`, '/') AS CHAR(2000)) AS `path`, 0 AS `depth`
+        FROM `comments` WHERE `parent_id` = 0
+        UNION ALL
+        SELECT c2.`comment_id`, CONCAT(t.`path`, c2.`comment_id`, '/'), t.`depth` + 1
+        FROM `comments` c2 JOIN t ON c2.`parent_id` = t.`comment_id`
+    )
+    SELECT * FROM t
+) t ON c.`comment_id` = t.`comment_id`
+SET c.`path` = t.`path`, c.`depth` = t.`depth`;
+
+-- 评论编辑时间
+ALTER TABLE `comments`
+    ADD COLUMN `edit_time` timestamp NULL DEFAULT NULL COMMENT '最后一次编辑时间';
+
+DROP TABLE IF EXISTS `mention`;
+CREATE TABLE `mention` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `source_type` tinyint(4) NOT NULL COMMENT '1帖子 2评论',
+                        `source_id` bigint(20) NOT NULL COMMENT '帖子id或评论id',
+                        `post_id` bigint(20) NOT NULL,
+                        `user_id` bigint(20) NOT NULL COMMENT '被提及的用户',
+                        `username` varchar(64) COLLATE utf8mb4_general_ci NOT NULL,
+                        `author_id` bigint(20) NOT NULL COMMENT '提及者',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `idx_source_user` (`source_type`, `source_id`, `user_id`) USING BTREE,
+                        KEY `idx_user_id` (`user_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `notification`;
+CREATE TABLE `notification` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `user_id` bigint(20) NOT NULL COMMENT '接收通知的用户',
+                        `actor_id` bigint(20) NOT NULL COMMENT '触发通知的用户',
+                        `type` tinyint(4) NOT NULL,
+                        `post_id` bigint(20) NOT NULL DEFAULT '0',
+                        `comment_id` bigint(20) NOT NULL DEFAULT '0',
+                        `count` int(11) NOT NULL DEFAULT '1' COMMENT '合并的事件数量',
+                        `is_read` tinyint(4) NOT NULL DEFAULT '0',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`id`),
+                        KEY `idx_user_read` (`user_id`, `is_read`) USING BTREE,
+                        KEY `idx_user_time` (`user_id`, `update_time`) USING BTREE,
+                        KEY `idx_aggregate` (`user_id`, `type`, `post_id`, `comment_id`, `is_read`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `notification_preference`;
+CREATE TABLE `notification_preference` (
+                        `user_id` bigint(20) NOT NULL,
+                        `type` tinyint(4) NOT NULL,
+                        `enabled` tinyint(1) NOT NULL DEFAULT '1',
+                        `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE
+CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`user_id`, `type`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `conversation`;
+CREATE TABLE `conversation` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `conversation_id` bigint(20) NOT NULL,
+                        `user_a` bigint(20) NOT NULL COMMENT '较小的用户id',
+                        `user_b` bigint(20) NOT NULL COMMENT '较大的用户id',
+                        `a_last_read_id` bigint(20) NOT NULL DEFAULT '0' COMMENT 'user_a已读到的消息id',
+                        `b_last_read_id` bigint(20) NOT NULL DEFAULT '0' COMMENT 'user_b已读到的消息id',
+                        `last_message_id` bigint(20) NOT NULL DEFAULT '0',
+                        `last_message_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `idx_conversation_id` (`conversation_id`) USING BTREE,
+                        UNIQUE KEY `idx_users` (`user_a`, `user_b`) USING BTREE,
+                        KEY `idx_user_b` (`user_b`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `message`;
+CREATE TABLE `message` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `message_id` bigint(20) NOT NULL,
+                        `conversation_id` bigint(20) NOT NULL,
+                        `sender_id` bigint(20) NOT NULL,
+                        `content` varchar(2000) COLLATE utf8mb4_general_ci NOT NULL,
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `idx_message_id` (`message_id`) USING BTREE,
+                        KEY `idx_conversation_message` (`conversation_id`, `message_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `user_block`;
+CREATE TABLE `user_block` (
+                        `user_id` bigint(20) NOT NULL,
+                        `blocked_id` bigint(20) NOT NULL COMMENT '被屏蔽的用户',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`user_id`, `blocked_id`),
+                        KEY `idx_blocked_id` (`blocked_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- 关注
+DROP TABLE IF EXISTS `user_follow`;
+CREATE TABLE `user_follow` (
+                        `user_id` bigint(20) NOT NULL COMMENT '被关注的用户',
+                        `follower_id` bigint(20) NOT NULL COMMENT '关注者',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`user_id`, `follower_id`),
+                        KEY `idx_follower_id` (`follower_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- 社区成员数量
+ALTER TABLE `community`
+    ADD COLUMN `member_count` int(11) NOT NULL DEFAULT '0' COMMENT '成员数量';
+
+DROP TABLE IF EXISTS `community_member`;
+CREATE TABLE `community_member` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `community_id` bigint(20) NOT NULL,
+                        `user_id` bigint(20) NOT NULL,
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `idx_community_user` (`community_id`, `user_id`) USING BTREE,
+                        KEY `idx_user_id` (`user_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- 社区创建者及成员角色
+ALTER TABLE `community`
+    ADD COLUMN `owner_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '创建者';
+ALTER TABLE `community_member`
+    ADD COLUMN `role` tinyint(4) NOT NULL DEFAULT '0' COMMENT '0成员 1版主 2创建者';
+
+-- 版主置顶及锁定帖子
+ALTER TABLE `post`
+    ADD COLUMN `pinned` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否置顶',
+    ADD COLUMN `pin_time` timestamp NULL DEFAULT NULL,
+    ADD COLUMN `locked` tinyint(1) NOT NULL DEFAULT '0' COMMENT '锁定后不能评论',
+    ADD KEY `idx_community_pinned` (`community_id`, `pinned`);
+
+DROP TABLE IF EXISTS `community_ban`;
+CREATE TABLE `community_ban` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `community_id` bigint(20) NOT NULL,
+                        `user_id` bigint(20) NOT NULL,
+                        `operator_id` bigint(20) NOT NULL,
+                        `reason` varchar(200) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+                        `expire_time` timestamp NOT NULL,
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `idx_community_user` (`community_id`, `user_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `moderation_log`;
+CREATE TABLE `moderation_log` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `community_id` bigint(20) NOT NULL,
+                        `moderator_id` bigint(20) NOT NULL,
+                        `action` varchar(32) COLLATE utf8mb4_general_ci NOT NULL,
+                        `target_user_id` bigint(20) NOT NULL DEFAULT '0',
+                        `post_id` bigint(20) NOT NULL DEFAULT '0',
+                        `comment_id` bigint(20) NOT NULL DEFAULT '0',
+                        `reason` varchar(200) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`id`),
+                        KEY `idx_community_id` (`community_id`, `id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- 帖子类型
+ALTER TABLE `post`
+    ADD COLUMN `post_type` varchar(16) COLLATE utf8mb4_general_ci NOT NULL DEFAULT 'text' COMMENT 'text/image/link';
+
+DROP TABLE IF EXISTS `community_setting`;
+CREATE TABLE `community_setting` (
+                        `community_id` bigint(20) NOT NULL,
+                        `rules` text COLLATE utf8mb4_general_ci NOT NULL,
+                        `icon` varchar(255) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+                        `banner` varchar(255) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+                        `allowed_post_types` varchar(64) COLLATE utf8mb4_general_ci NOT NULL DEFAULT 'text,image,link',
+                        `visibility` tinyint(4) NOT NULL DEFAULT '0' COMMENT '0公开 1受限 2私密',
+                        `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE
+CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`community_id`),
+                        KEY `idx_visibility` (`visibility`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `community_join_request`;
+CREATE TABLE `community_join_request` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `community_id` bigint(20) NOT NULL,
+                        `user_id` bigint(20) NOT NULL,
+                        `inviter_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '版主邀请时为版主id',
+                        `status` tinyint(4) NOT NULL DEFAULT '0' COMMENT '0待处理 1已批准 2已拒绝',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `idx_community_user` (`community_id`, `user_id`) USING BTREE,
+                        KEY `idx_community_status` (`community_id`, `status`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- 归档的社区只读
+ALTER TABLE `community_setting`
+    ADD COLUMN `archived` tinyint(1) NOT NULL DEFAULT '0' COMMENT '1已归档';
+
+-- 社区统计按天汇总时按时间范围扫描
+ALTER TABLE `post_vote_event`
+    ADD KEY `idx_create_time` (`create_time`) USING BTREE;
+ALTER TABLE `comments`
+    ADD KEY `idx_create_time` (`create_time`) USING BTREE;
+ALTER TABLE `community_member`
+    ADD KEY `idx_create_time` (`create_time`) USING BTREE;
+
+DROP TABLE IF EXISTS `community_daily_stats`;
+CREATE TABLE `community_daily_stats` (
+                        `community_id` bigint(20) NOT NULL,
+                        `day` date NOT NULL,
+                        `posts` int(11) NOT NULL DEFAULT '0',
+                        `comments` int(11) NOT NULL DEFAULT '0',
+                        `votes` int(11) NOT NULL DEFAULT '0',
+                        `active_users` int(11) NOT NULL DEFAULT '0' COMMENT '发帖、评论或投票的用户数',
+                        `new_members` int(11) NOT NULL DEFAULT '0',
+                        `member_count` int(11) NOT NULL DEFAULT '0' COMMENT '当天结束时的成员数',
+                        `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`community_id`, `day`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- 举报单：同一对象的待处理举报合并为一个，open_key 只在待处理时有值，保证同一对象只有一个待处理的举报单
+DROP TABLE IF EXISTS `report_case`;
+CREATE TABLE `report_case` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `target_type` varchar(16) COLLATE utf8mb4_general_ci NOT NULL COMMENT 'post/comment/user',
+                        `target_id` bigint(20) NOT NULL,
+                        `community_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '举报用户时为0',
+                        `target_user_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '被举报内容的作者或被举报的用户',
+                        `snapshot` varchar(512) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+                        `report_count` int(11) NOT NULL DEFAULT '0',
+                        `hidden` tinyint(1) NOT NULL DEFAULT '0' COMMENT '1已自动隐藏',
+                        `status` tinyint(4) NOT NULL DEFAULT '0' COMMENT '0待处理 1举报成立 2举报不成立',
+                        `open_key` varchar(64) COLLATE utf8mb4_general_ci DEFAULT NULL,
+                        `resolver_id` bigint(20) NOT NULL DEFAULT '0',
+                        `note` varchar(512) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+                        `resolve_time` timestamp NULL DEFAULT NULL,
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `idx_open_key` (`open_key`) USING BTREE,
+                        KEY `idx_community_status` (`community_id`, `status`) USING BTREE,
+                        KEY `idx_status` (`status`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `report`;
+CREATE TABLE `report` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `case_id` bigint(20) NOT NULL,
+                        `reporter_id` bigint(20) NOT NULL,
+                        `reason` varchar(32) COLLATE utf8mb4_general_ci NOT NULL,
+                        `detail` varchar(512) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `idx_case_reporter` (`case_id`, `reporter_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+ALTER TABLE `user`
+    ADD COLUMN `email_verified` tinyint(1) NOT NULL DEFAULT '0' AFTER `email`;
+
+DROP TABLE IF EXISTS `user_sanction`;
+CREATE TABLE `user_sanction` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `user_id` bigint(20) NOT NULL,
+                        `type` varchar(16) COLLATE utf8mb4_general_ci NOT NULL COMMENT 'suspend 封禁, shadow_ban 影子封禁',
+                        `reason` varchar(32) COLLATE utf8mb4_general_ci NOT NULL,
+                        `note` varchar(200) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+                        `operator_id` bigint(20) NOT NULL,
+                        `expire_time` timestamp NULL DEFAULT NULL COMMENT 'NULL 表示永久',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `idx_user_type` (`user_id`, `type`) USING BTREE,
+                        KEY `idx_type_expire` (`type`, `expire_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `audit_log`;
+CREATE TABLE `audit_log` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `actor_id` bigint(20) NOT NULL DEFAULT '0',
+                        `action` varchar(32) COLLATE utf8mb4_general_ci NOT NULL,
+                        `target_type` varchar(16) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+                        `target_id` bigint(20) NOT NULL DEFAULT '0',
+                        `success` tinyint(1) NOT NULL DEFAULT '0',
+                        `code` int(11) NOT NULL DEFAULT '0',
+                        `detail` varchar(500) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+                        `ip` varchar(45) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+                        `user_agent` varchar(255) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`id`),
+                        KEY `idx_actor_time` (`actor_id`, `create_time`),
+                        KEY `idx_action_time` (`action`, `create_time`),
+                        KEY `idx_target` (`target_type`, `target_id`),
+                        KEY `idx_create_time` (`create_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- 审计日志只能追加，禁止修改和删除
+DROP TRIGGER IF EXISTS `audit_log_no_update`;
+CREATE TRIGGER `audit_log_no_update` BEFORE UPDATE ON `audit_log` FOR EACH ROW
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
+DROP TRIGGER IF EXISTS `audit_log_no_delete`;
+CREATE TRIGGER `audit_log_no_delete` BEFORE DELETE ON `audit_log` FOR EACH ROW
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
+
+ALTER TABLE `user`
+    ADD COLUMN `role` tinyint(4) NOT NULL DEFAULT '0' COMMENT '0 普通用户, 1 管理员' AFTER `gender`;
\ No newline at end of file
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// 被提及的内容类型
+const (
+	MentionSourcePost    int8 = 1
+	MentionSourceComment int8 = 2
+)
+
+// Mention 帖子或评论中的 @提及记录
+type Mention struct {
+	ID         int64     `json:"-" db:"id"`
+	SourceType int8      `json:"source_type" db:"source_type"`    // 1帖子 2评论
+	SourceID   int64     `json:"source_id,string" db:"source_id"` // 帖子id或评论id
+	PostID     int64     `json:"post_id,string" db:"post_id"`     // 所属帖子
+	UserID     int64     `json:"user_id,string" db:"user_id"`     // 被提及的用户
+	Username   string    `json:"username" db:"username"`          // 被提及的用户名
+	AuthorID   int64     `json:"author_id,string" db:"author_id"` // 提及者
+	CreateTime time.Time `json:"create_time" db:"create_time"`
+}
+
+// ApiMention 响应中被提及的用户
+type ApiMention struct {
+	UserID   int64  `json:"user_id,string"`
+	Username string `json:"username"`
+	Link     string `json:"link"` // 用户主页链接
+}
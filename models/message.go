@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Conversation 两个用户之间的私信会话，UserA 为较小的用户id
+type Conversation struct {
+	ConversationID  int64     `json:"conversation_id,string" db:"conversation_id"`
+	UserA           int64     `json:"-" db:"user_a"`
+	UserB           int64     `json:"-" db:"user_b"`
+	ALastReadID     int64     `json:"-" db:"a_last_read_id"` // UserA 已读到的消息id
+	BLastReadID     int64     `json:"-" db:"b_last_read_id"` // UserB 已读到的消息id
+	LastMessageID   int64     `json:"-" db:"last_message_id"`
+	LastMessageTime time.Time `json:"last_message_time" db:"last_message_time"`
+	CreateTime      time.Time `json:"create_time" db:"create_time"`
+}
+
+// Message 私信消息
+type Message struct {
+	MessageID      int64     `json:"message_id,string" db:"message_id"`
+	ConversationID int64     `json:"conversation_id,string" db:"conversation_id"`
+	SenderID       int64     `json:"sender_id,string" db:"sender_id"`
+	Content        string    `json:"content" db:"content"`
+	CreateTime     time.Time `json:"create_time" db:"create_time"`
+}
+
+// ApiConversation 会话列表中的一项
+type ApiConversation struct {
+	ConversationID  int64     `json:"conversation_id,string"`
+	Peer            *UserSafe `json:"peer"`                     // 对方用户
+	LastMessage     *Message  `json:"last_message"`             // 最后一条消息
+	Unread          int64     `json:"unread"`                   // 对方发来的未读消息数量
+	PeerLastReadID  int64     `json:"peer_last_read_id,string"` // 对方已读到的消息id，用于显示已读回执
+	LastMessageTime time.Time `json:"last_message_time"`
+}
+
+// MessageReadEvent 已读回执的实时事件内容
+type MessageReadEvent struct {
+	ConversationID int64 `json:"conversation_id,string"`
+	ReaderID       int64 `json:"reader_id,string"`
+	LastReadID     int64 `json:"last_read_id,string"`
+}
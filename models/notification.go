@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// 通知类型
+const (
+	NotificationMention       int8 = 1 // 在帖子或评论中被提及
+	NotificationPostReply     int8 = 2 // 帖子收到评论
+	NotificationCommentReply  int8 = 3 // 评论收到回复
+	NotificationCommentPinned int8 = 4 // 评论被帖子作者置顶
+	NotificationPostVote      int8 = 5 // 帖子收到赞成票，未读期间的多次投票合并为一条
+	NotificationCommentVote   int8 = 6 // 评论收到赞成票，未读期间的多次投票合并为一条
+	NotificationFollow        int8 = 7 // 新的关注者
+)
+
+// NotificationTypes 全部通知类型及名称
+var NotificationTypes = map[int8]string{
+	NotificationMention:       "mention",
+	NotificationPostReply:     "post_reply",
+	NotificationCommentReply:  "comment_reply",
+	NotificationCommentPinned: "comment_pinned",
+	NotificationPostVote:      "post_vote",
+	NotificationCommentVote:   "comment_vote",
+	NotificationFollow:        "follow",
+}
+
+// Notification 站内通知
+type Notification struct {
+	ID         int64     `json:"id,string" db:"id"`
+	UserID     int64     `json:"user_id,string" db:"user_id"`       // 接收通知的用户
+	ActorID    int64     `json:"actor_id,string" db:"actor_id"`     // 触发通知的用户，合并的通知为最近一次触发的用户
+	Type       int8      `json:"type" db:"type"`                    // 通知类型
+	PostID     int64     `json:"post_id,string" db:"post_id"`       // 相关的帖子
+	CommentID  int64     `json:"comment_id,string" db:"comment_id"` // 相关的评论，没有时为 0
+	Count      int64     `json:"count" db:"count"`                  // 合并的事件数量
+	IsRead     int8      `json:"is_read" db:"is_read"`
+	CreateTime time.Time `json:"create_time" db:"create_time"`
+	UpdateTime time.Time `json:"update_time" db:"update_time"` // 最近一次合并的时间
+}
+
+// ApiNotificationList 通知列表及未读数量
+type ApiNotificationList struct {
+	Unread        int64           `json:"unread"`
+	Notifications []*Notification `json:"notifications"`
+}
+
+// NotificationPreference 用户对一种通知的设置
+type NotificationPreference struct {
+	Type    int8   `json:"type" db:"type"`
+	Name    string `json:"name" db:"-"`
+	Enabled bool   `json:"enabled" db:"enabled"`
+}
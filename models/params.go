@@ -4,8 +4,27 @@ import "time"
 
 // 帖子排序方式
 const (
-	OrderTime  = "time"
-	OrderScore = "score"
+	OrderTime          = "time"
+	OrderScore         = "score"         // Reddit 热度
+	OrderTop           = "top"           // 时间窗口内净赞成票最多
+	OrderControversial = "controversial" // 争议度，赞成反对票数接近且总票数多
+	OrderBest          = "best"          // Wilson 置信区间下界
+	OrderGravity       = "gravity"       // Hacker News 重力衰减
+)
+
+// 评论排序方式
+const (
+	CommentSortNew           = "new"           // 发表时间
+	CommentSortBest          = "best"          // Wilson 置信区间下界
+	CommentSortTop           = "top"           // 净赞成票
+	CommentSortControversial = "controversial" // 争议度
+)
+
+// top 排序的时间窗口
+const (
+	WindowDay   = "day"
+	WindowWeek  = "week"
+	WindowMonth = "month"
 )
 
 // ParamSignUp 注册请求参数
@@ -27,7 +46,8 @@ type ParamLogIn struct {
 type ParamPostList struct {
 	Offset       int64  `json:"offset" form:"offset"`
 	Limit        int64  `json:"limit" form:"limit"`
-	Order        string `json:"order" form:"order"`
+	Order        string `json:"order" form:"order" binding:"omitempty,oneof=time score top controversial best gravity"`
+	Window       string `json:"window" form:"window" binding:"omitempty,oneof=day week month"` // 仅 top 排序使用
 	Community_id int64  `json:"community_id" form:"community_id"`
 }
 
@@ -55,6 +75,13 @@ type ParamVoteData struct {
 	Direction int8  `json:"direction,string" binding:"oneof=1 0 -1"`
 }
 
+// ParamVoteList 查询用户投票记录的请求参数
+type ParamVoteList struct {
+	Offset    int64 `json:"offset" form:"offset"`
+	Limit     int64 `json:"limit" form:"limit"`
+	Direction int8  `json:"direction" form:"direction" binding:"omitempty,oneof=1 -1"` // 不传时查询全部
+}
+
 // 定义用于创建评论请求的结构体
 type ParamComment struct {
 	Comment_id int64  `json:"comment_id,string" db:"comment_id" binding:"omitempty"`
@@ -64,6 +91,114 @@ type ParamComment struct {
 	Content    string `json:"content" db:"content" form:"content" binding:"required"`               // 评论内容，必填
 }
 
+// ParamCommentVote 评论投票数据
+type ParamCommentVote struct {
+	CommentID int64 `json:"comment_id,string" binding:"required"`
+	Direction int8  `json:"direction,string" binding:"oneof=1 0 -1"`
+}
+
+// ParamCommentList 查询评论的请求参数
+type ParamCommentList struct {
+	Sort string `json:"sort" form:"sort" binding:"omitempty,oneof=new best top controversial"`
+}
+
+// ParamCommentTree 查询评论树的请求参数
+type ParamCommentTree struct {
+	Sort     string `json:"sort" form:"sort" binding:"omitempty,oneof=new best top controversial"`
+	MaxDepth int    `json:"max_depth" form:"max_depth" binding:"omitempty,min=1,max=10"` // 返回的最大层数
+	Limit    int    `json:"limit" form:"limit" binding:"omitempty,min=1,max=100"`        // 每层最多返回的评论数
+	Cursor   string `json:"cursor" form:"cursor"`                                        // 继续加载的令牌，为空时从顶级评论开始
+}
+
+// ParamNotificationList 查询通知的请求参数
+type ParamNotificationList struct {
+	Offset     int64 `json:"offset" form:"offset"`
+	Limit      int64 `json:"limit" form:"limit"`
+	UnreadOnly bool  `json:"unread_only" form:"unread_only"` // 只查询未读通知
+}
+
+// ParamNotificationPreference 修改通知设置的请求参数
+type ParamNotificationPreference struct {
+	Type    int8  `json:"type" binding:"required,min=1,max=7"`
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// ParamSendMessage 发送私信的请求参数
+type ParamSendMessage struct {
+	ToUserID int64  `json:"to_user_id,string" binding:"required"`
+	Content  string `json:"content" binding:"required,max=2000"`
+}
+
+// ParamMessageList 查询私信历史的请求参数，按消息id倒序分页
+type ParamMessageList struct {
+	Before int64 `json:"before,string" form:"before"` // 只返回id小于before的消息，为 0 时从最新的消息开始
+	Limit  int64 `json:"limit" form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// ParamAppointModerator 任命版主的请求参数
+type ParamAppointModerator struct {
+	UserID int64 `json:"user_id,string" binding:"required"`
+}
+
+// ParamModReason 版主操作的原因
+type ParamModReason struct {
+	Reason string `json:"reason" binding:"max=200"`
+}
+
+// ParamCommunityBan 在社区中禁言用户的请求参数
+type ParamCommunityBan struct {
+	UserID int64  `json:"user_id,string" binding:"required"`
+	Hours  int64  `json:"hours" binding:"required,min=1,max=8760"` // 禁言时长（小时）
+	Reason string `json:"reason" binding:"max=200"`
+}
+
+// ParamCommunitySettings 修改社区设置的请求参数
+type ParamCommunitySettings struct {
+	Rules            string   `json:"rules" binding:"max=10000"`
+	Icon             string   `json:"icon" binding:"omitempty,url,max=255"`
+	Banner           string   `json:"banner" binding:"omitempty,url,max=255"`
+	AllowedPostTypes []string `json:"allowed_post_types" binding:"required,min=1,dive,oneof=text image link"`
+	Visibility       *int8    `json:"visibility" binding:"required,oneof=0 1 2"`
+}
+
+// ParamUpdateCommunity 修改社区的请求参数，只修改传入的字段
+type ParamUpdateCommunity struct {
+	Introduction     *string  `json:"introduction" binding:"omitempty,max=1000"`
+	Rules            *string  `json:"rules" binding:"omitempty,max=10000"`
+	Icon             *string  `json:"icon" binding:"omitempty,max=255"`
+	Banner           *string  `json:"banner" binding:"omitempty,max=255"`
+	AllowedPostTypes []string `json:"allowed_post_types" binding:"omitempty,min=1,dive,oneof=text image link"`
+	Visibility       *int8    `json:"visibility" binding:"omitempty,oneof=0 1 2"`
+}
+
+// ParamCommunityStats 查询社区统计数据的请求参数
+type ParamCommunityStats struct {
+	Days int `form:"days" binding:"omitempty,min=1,max=365"`
+}
+
+// ParamCommunitySearch 按名称和简介搜索社区的请求参数
+type ParamCommunitySearch struct {
+	Query  string `form:"q" binding:"required,max=50"`
+	Offset int64  `form:"offset"`
+	Limit  int64  `form:"limit" binding:"omitempty,max=50"`
+}
+
+// ParamCommunitySuggest 社区名称自动补全的请求参数
+type ParamCommunitySuggest struct {
+	Prefix string `form:"prefix" binding:"required,max=50"`
+	Limit  int64  `form:"limit" binding:"omitempty,max=20"`
+}
+
+// ParamCommunityDiscover 热门社区和推荐社区的请求参数
+type ParamCommunityDiscover struct {
+	Limit int64 `form:"limit" binding:"omitempty,max=50"`
+}
+
+// ParamInviteMember 邀请用户加入社区的请求参数
+type ParamInviteMember struct {
+	UserID int64 `json:"user_id,string" binding:"required"`
+}
+
 // 定义用于上传图片请求的结构体
 type ParamImage struct {
 	PostID   int64  `json:"post_id,string"` // 文章 ID，关联图片
@@ -74,3 +209,73 @@ type ParamImage struct {
 func (ParamImage) TableName() string {
 	return "images" // 确保使用 "images" 表
 }
+
+// ParamReport 举报帖子、评论或用户的请求参数
+type ParamReport struct {
+	TargetType string `json:"target_type" binding:"required,oneof=post comment user"`
+	TargetID   int64  `json:"target_id,string" binding:"required"`
+	Reason     string `json:"reason" binding:"required,oneof=spam harassment hate violence sexual misinformation other"`
+	Detail     string `json:"detail" binding:"max=500"`
+}
+
+// ParamReportList 查询审核队列的请求参数，不指定社区时查询全站（只有管理员可以）
+type ParamReportList struct {
+	CommunityID int64 `form:"community_id"`
+	Status      int8  `form:"status" binding:"omitempty,oneof=0 1 2"`
+	Offset      int64 `form:"offset"`
+	Limit       int64 `form:"limit" binding:"omitempty,max=100"`
+}
+
+// ParamReviewReport 处理举报单的请求参数
+type ParamReviewReport struct {
+	Note string `json:"note" binding:"max=500"`
+}
+
+// ParamVerifyEmail 验证邮箱的请求参数
+type ParamVerifyEmail struct {
+	Code string `json:"code" binding:"required,len=6"`
+}
+
+// ParamUserSanction 管理员封禁或影子封禁用户的请求参数
+type ParamUserSanction struct {
+	Reason string `json:"reason" binding:"required,oneof=spam harassment hate violence sexual impersonation ban_evasion other"`
+	Note   string `json:"note" binding:"max=200"`
+	Hours  int64  `json:"hours" binding:"min=0,max=87600"` // 处罚时长（小时），0 表示永久
+}
+
+// ParamAccountStatus 查询账号状态的请求参数，被封禁的用户不能登录，用用户名和密码查询
+type ParamAccountStatus struct {
+	Username string `json:"uname" binding:"required"`
+	Password string `json:"upwd" binding:"required"`
+}
+
+// ParamAuditLogList 管理员查询审计日志的请求参数，时间为 unix 秒
+type ParamAuditLogList struct {
+	ActorID    int64  `form:"actor_id"`
+	Action     string `form:"action" binding:"max=32"`
+	TargetType string `form:"target_type" binding:"max=16"`
+	TargetID   int64  `form:"target_id"`
+	Success    *bool  `form:"success"`
+	StartTime  int64  `form:"start_time" binding:"min=0"`
+	EndTime    int64  `form:"end_time" binding:"min=0"`
+	Offset     int64  `form:"offset" binding:"min=0"`
+	Limit      int64  `form:"limit" binding:"min=0,max=100"`
+}
+
+// ParamAdminUserList 管理员搜索用户的请求参数，按用户名或邮箱前缀匹配，传入数字时同时匹配用户id
+type ParamAdminUserList struct {
+	Query  string `form:"q" binding:"max=64"`
+	Offset int64  `form:"offset" binding:"min=0"`
+	Limit  int64  `form:"limit" binding:"min=0,max=100"`
+}
+
+// ParamAdminRole 管理员修改用户全站角色的请求参数
+type ParamAdminRole struct {
+	Role *int8 `json:"role" binding:"required,oneof=0 1"`
+}
+
+// ParamAdminUpdateCommunity 管理员修改社区的请求参数，只修改传入的字段
+type ParamAdminUpdateCommunity struct {
+	Name *string `json:"name" binding:"omitempty,min=1,max=128"`
+	ParamUpdateCommunity
+}
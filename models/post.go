@@ -9,14 +9,34 @@ type Post struct {
 	ID          int64     `json:"id,string" db:"post_id"`
 	AuthorID    int64     `json:"author_id,string" db:"author_id"`
 	CommunityID int64     `json:"community_id,string" db:"community_id" binding:"required"`
+	PostType    string    `json:"post_type" db:"post_type" binding:"omitempty,oneof=text image link"`
 	Status      int32     `json:"status,string" db:"status"`
 	Title       string    `json:"title" db:"title" binding:"required"`
 	Content     string    `json:"content" db:"content" binding:"required"`
 	CreateTime  time.Time `json:"create_time" db:"create_time"`
 	Likes       int64     `json:"likes,string" db:"likes"`
 	DisLikes    int64     `json:"dislikes,string" db:"d"`
+	Pinned      bool      `json:"pinned" db:"pinned"` // 被版主置顶在社区帖子列表的最前面
+	Locked      bool      `json:"locked" db:"locked"` // 被版主锁定，不能再评论
+
+	Mentions        []*ApiMention `json:"mentions,omitempty" db:"-"`         // 内容中提及的用户
+	RenderedContent string        `json:"rendered_content,omitempty" db:"-"` // 提及渲染为链接后的内容
 }
 
+// 帖子类型，社区可以限制允许发布的类型
+const (
+	PostTypeText  = "text"
+	PostTypeImage = "image"
+	PostTypeLink  = "link"
+)
+
+// 帖子状态
+const (
+	PostStatusNormal  int32 = 1
+	PostStatusRemoved int32 = 2 // 被版主移除
+	PostStatusHidden  int32 = 3 // 被举报次数达到上限后自动隐藏，等待版主处理
+)
+
 // TableName 方法用于指定 GORM 使用的表名
 func (Post) TableName() string {
 	return "post" // 确保使用 "images" 表
@@ -25,6 +45,45 @@ func (Post) TableName() string {
 type ApiPostDetail struct {
 	AuthorName       string `json:"author_name" db:"author_name"`
 	VoteNum          int64  `json:"votes"`
+	MyVote           int8   `json:"my_vote"` // 当前用户的投票方向
 	*Post            `json:"post_detail"`
 	*CommunityDetail `json:"community_detail"`
 }
+
+// PostRankScores 帖子在各排序方式下的分数
+type PostRankScores struct {
+	Hot           float64  // Reddit 热度
+	Controversial float64  // 争议度
+	Best          float64  // Wilson 置信区间下界
+	Gravity       float64  // Hacker News 重力衰减
+	Net           float64  // 净赞成票，用于 top 排序
+	TopWindows    []string // 帖子仍处于的 top 时间窗口
+}
+
+// RankParams 投票脚本计算排序分数所需的参数，与 logic 中的排序算法保持一致
+type RankParams struct {
+	HotEpoch      float64
+	HotDecay      float64
+	WilsonZ       float64
+	Gravity       float64
+	GravityOffset float64
+	Now           int64
+	TopWindows    []string // 帖子仍处于的 top 时间窗口
+}
+
+// PostVoteStat 帖子的投票统计及发帖时间
+type PostVoteStat struct {
+	PostID     string
+	Ups        int64
+	Downs      int64
+	CreateTime int64
+}
+
+// VoteCountDrift 帖子票数计数与投票记录之间的偏差
+type VoteCountDrift struct {
+	PostID   string `json:"post_id"`
+	OldUps   int64  `json:"old_ups"`
+	OldDowns int64  `json:"old_downs"`
+	Ups      int64  `json:"ups"`
+	Downs    int64  `json:"downs"`
+}
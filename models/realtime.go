@@ -0,0 +1,33 @@
+package models
+
+import "encoding/json"
+
+// 实时事件类型
+const (
+	EventNotification = "notification"  // 新通知，发给接收通知的用户
+	EventComment      = "comment"       // 帖子收到新评论，发给正在浏览该帖子的用户
+	EventPostVote     = "post_vote"     // 帖子票数变化
+	EventCommentVote  = "comment_vote"  // 评论票数变化
+	EventMessage      = "message"       // 收到私信
+	EventMessageRead  = "message_read"  // 对方已读私信
+	EventSessionClose = "session_close" // 账号被封禁，服务端随后关闭用户的事件流
+)
+
+// RealtimeEvent 通过 redis 发布订阅转发给客户端的实时事件
+type RealtimeEvent struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// VoteCountEvent 票数变化事件的内容
+type VoteCountEvent struct {
+	PostID    int64 `json:"post_id,string"`
+	CommentID int64 `json:"comment_id,string,omitempty"`
+	Ups       int64 `json:"ups"`
+	Downs     int64 `json:"downs"`
+}
+
+// SessionCloseEvent 关闭事件流事件的内容
+type SessionCloseEvent struct {
+	Reason string `json:"reason"`
+}
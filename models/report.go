@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// 举报的对象类型
+const (
+	ReportTargetPost    = "post"
+	ReportTargetComment = "comment"
+	ReportTargetUser    = "user"
+)
+
+// 举报理由
+const (
+	ReportReasonSpam           = "spam"
+	ReportReasonHarassment     = "harassment"
+	ReportReasonHate           = "hate"
+	ReportReasonViolence       = "violence"
+	ReportReasonSexual         = "sexual"
+	ReportReasonMisinformation = "misinformation"
+	ReportReasonOther          = "other"
+	ReportReasonSensitive      = "sensitive" // 命中敏感词，由系统提交
+)
+
+// 举报单的状态
+const (
+	ReportCaseOpen      int8 = 0 // 待处理
+	ReportCaseResolved  int8 = 1 // 举报成立，内容已移除
+	ReportCaseDismissed int8 = 2 // 举报不成立，内容已恢复
+)
+
+// ReportCase 同一对象的待处理举报合并为一个举报单，进入版主和管理员的审核队列
+type ReportCase struct {
+	ID           int64      `json:"id,string" db:"id"`
+	TargetType   string     `json:"target_type" db:"target_type"`
+	TargetID     int64      `json:"target_id,string" db:"target_id"`
+	CommunityID  int64      `json:"community_id,string" db:"community_id"` // 举报用户时为0，只有管理员可以处理
+	TargetUserID int64      `json:"target_user_id,string" db:"target_user_id"`
+	Snapshot     string     `json:"snapshot" db:"snapshot"` // 举报时的标题、评论内容或用户名
+	ReportCount  int64      `json:"report_count" db:"report_count"`
+	Hidden       bool       `json:"hidden" db:"hidden"` // 是否因举报人数达到上限被自动隐藏
+	Status       int8       `json:"status" db:"status"`
+	ResolverID   int64      `json:"resolver_id,string" db:"resolver_id"`
+	Note         string     `json:"note" db:"note"`
+	ResolveTime  *time.Time `json:"resolve_time,omitempty" db:"resolve_time"`
+	CreateTime   time.Time  `json:"create_time" db:"create_time"`
+	UpdateTime   time.Time  `json:"update_time" db:"update_time"`
+
+	Reports []*Report `json:"reports,omitempty" db:"-"`
+}
+
+// Report 用户对某个对象的一次举报，同一用户对同一举报单只保留一条
+type Report struct {
+	CaseID     int64     `json:"-" db:"case_id"`
+	ReporterID int64     `json:"reporter_id,string" db:"reporter_id"`
+	Reason     string    `json:"reason" db:"reason"`
+	Detail     string    `json:"detail" db:"detail"`
+	CreateTime time.Time `json:"create_time" db:"create_time"`
+}
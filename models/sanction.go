@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// 全站处罚的类型
+const (
+	SanctionSuspend   = "suspend"    // 封禁：不能登录，已签发的Token失效
+	SanctionShadowBan = "shadow_ban" // 影子封禁：用户自己能看到发布的内容，其他人看不到
+)
+
+// 处罚原因
+const (
+	SanctionReasonSpam          = "spam"
+	SanctionReasonHarassment    = "harassment"
+	SanctionReasonHate          = "hate"
+	SanctionReasonViolence      = "violence"
+	SanctionReasonSexual        = "sexual"
+	SanctionReasonImpersonation = "impersonation"
+	SanctionReasonBanEvasion    = "ban_evasion"
+	SanctionReasonOther         = "other"
+)
+
+// SanctionReasonText 处罚原因的说明，向被封禁的用户展示
+var SanctionReasonText = map[string]string{
+	SanctionReasonSpam:          "发布垃圾信息或广告",
+	SanctionReasonHarassment:    "骚扰或攻击其他用户",
+	SanctionReasonHate:          "发布仇恨言论",
+	SanctionReasonViolence:      "发布暴力或威胁内容",
+	SanctionReasonSexual:        "发布色情内容",
+	SanctionReasonImpersonation: "冒充他人",
+	SanctionReasonBanEvasion:    "使用其他账号规避处罚",
+	SanctionReasonOther:         "违反社区规定",
+}
+
+// UserSanction 用户当前的全站处罚，ExpireTime 为nil表示永久
+type UserSanction struct {
+	UserID     int64      `json:"user_id,string" db:"user_id"`
+	Type       string     `json:"type" db:"type"`
+	Reason     string     `json:"reason" db:"reason"`
+	Note       string     `json:"note" db:"note"`
+	OperatorID int64      `json:"operator_id,string" db:"operator_id"`
+	ExpireTime *time.Time `json:"expire_time" db:"expire_time"`
+	CreateTime time.Time  `json:"create_time" db:"create_time"`
+}
+
+// AccountStatus 账号状态，被封禁时说明原因和解封时间
+type AccountStatus struct {
+	Suspended  bool       `json:"suspended"`
+	Reason     string     `json:"reason,omitempty"`
+	ReasonText string     `json:"reason_text,omitempty"`
+	Note       string     `json:"note,omitempty"`
+	Permanent  bool       `json:"permanent"`
+	ExpireTime *time.Time `json:"expire_time,omitempty"`
+}
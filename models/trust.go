@@ -0,0 +1,24 @@
+package models
+
+// TrustLevel 信任等级：达到等级的条件，以及该等级每小时可以发帖、评论、投票和发链接的数量
+// 数量为负数表示不限制
+type TrustLevel struct {
+	MinAgeHours     int64 `json:"min_age_hours" mapstructure:"min_age_hours"`
+	MinKarma        int64 `json:"min_karma" mapstructure:"min_karma"`
+	EmailVerified   bool  `json:"email_verified" mapstructure:"email_verified"`
+	PostsPerHour    int64 `json:"posts_per_hour" mapstructure:"posts_per_hour"`
+	CommentsPerHour int64 `json:"comments_per_hour" mapstructure:"comments_per_hour"`
+	VotesPerHour    int64 `json:"votes_per_hour" mapstructure:"votes_per_hour"`
+	LinksPerHour    int64 `json:"links_per_hour" mapstructure:"links_per_hour"`
+	CreateCommunity bool  `json:"create_community" mapstructure:"create_community"` // 能否创建社区
+	HoldForReview   bool  `json:"hold_for_review" mapstructure:"hold_for_review"`   // 发布的内容是否先进入审核队列
+}
+
+// UserTrust 用户当前的信任等级及计算依据
+type UserTrust struct {
+	Level           int         `json:"level"`
+	Karma           int64       `json:"karma"`
+	AccountAgeHours int64       `json:"account_age_hours"`
+	EmailVerified   bool        `json:"email_verified"`
+	Limits          *TrustLevel `json:"limits"`
+}
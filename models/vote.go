@@ -0,0 +1,68 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PostSyncData 需要从 redis 同步到 MySQL 的帖子投票数据
+type PostSyncData struct {
+	PostID   string
+	Hot      float64
+	Likes    int64
+	Dislikes int64
+}
+
+// DirtyItem 待同步的帖子或评论，Version 为取出时的投票版本号
+type DirtyItem struct {
+	ID      string
+	Version int64
+}
+
+// VoteSyncCheckpoint 投票同步的进度
+type VoteSyncCheckpoint struct {
+	LastRun     int64 `redis:"last_run" json:"last_run"`         // 上次开始同步的时间
+	LastSuccess int64 `redis:"last_success" json:"last_success"` // 上次成功提交一批数据的时间
+	LastSynced  int64 `redis:"last_synced" json:"last_synced"`   // 上次同步的帖子数量
+	TotalSynced int64 `redis:"total_synced" json:"total_synced"` // 累计同步的帖子数量
+	Failures    int64 `redis:"failures" json:"failures"`         // 连续失败的次数
+	Pending     int64 `redis:"pending" json:"pending"`           // 仍待同步的帖子数量
+	Lag         int64 `redis:"lag" json:"lag"`                   // 最早一次未同步投票距今的秒数
+}
+
+// PostVote 用户对帖子的当前投票，持久化在 MySQL 中
+type PostVote struct {
+	PostID     int64     `json:"post_id,string" db:"post_id"`
+	UserID     int64     `json:"user_id,string" db:"user_id"`
+	Direction  int8      `json:"direction" db:"direction"`
+	UpdateTime time.Time `json:"update_time" db:"update_time"`
+}
+
+// PostRankRow 重建 redis 排序数据时从 MySQL 读取的帖子信息
+type PostRankRow struct {
+	PostID      int64           `db:"post_id"`
+	CommunityID int64           `db:"community_id"`
+	CreateTime  time.Time       `db:"create_time"`
+	Likes       int64           `db:"likes"`
+	Dislikes    int64           `db:"dislikes"`
+	HotScore    sql.NullFloat64 `db:"hot_score"`
+}
+
+// PostRankState 一个帖子在 redis 中的全部排序数据
+type PostRankState struct {
+	PostID      string
+	CommunityID string
+	CreateTime  int64
+	Ups         int64
+	Downs       int64
+	Voters      map[string]int8 // 用户id -> 投票方向
+	Voted       bool            // redis 或 MySQL 中有投票记录，票数以投票记录为准
+	Scores      *PostRankScores
+}
+
+// ApiVotedPost 用户投过票的帖子
+type ApiVotedPost struct {
+	Direction int8      `json:"direction" db:"direction"`
+	VoteTime  time.Time `json:"vote_time" db:"vote_time"`
+	*Post     `json:"post_detail"`
+}
@@ -0,0 +1,60 @@
+package email
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+)
+
+// 通过 SMTP 发送邮件，没有配置 SMTP 服务器时不能发送
+
+var ErrNotConfigured = errors.New("邮件服务未配置")
+
+var (
+	addr string
+	from string
+	auth smtp.Auth
+)
+
+// Init 设置 SMTP 服务器，host 为空时不启用邮件服务
+func Init(host string, port int, username, password, sender string) {
+	if host == "" {
+		addr = ""
+		return
+	}
+	addr = net.JoinHostPort(host, strconv.Itoa(port))
+	from = sender
+	if from == "" {
+		from = username
+	}
+	auth = nil
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+}
+
+// Enabled 是否配置了 SMTP 服务器
+func Enabled() bool {
+	return addr != ""
+}
+
+// Send 发送一封纯文本邮件
+func Send(to, subject, body string) error {
+	if !Enabled() {
+		return ErrNotConfigured
+	}
+	// 收件人和标题不能包含换行，防止注入邮件头
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return fmt.Errorf("invalid mail header")
+	}
+	msg := "From: " + from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body
+	return smtp.SendMail(addr, auth, from, []string{to}, []byte(msg))
+}
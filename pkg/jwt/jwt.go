@@ -31,6 +31,7 @@ func GenToken(userid int64, username string) (string, error) {
 		username, // 自定义字段
 		jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(viper.GetInt("auth.jwt_expire")) * time.Hour)), // 设置过期时间
+			IssuedAt:  jwt.NewNumericDate(time.Now()),                                                                 // 签发时间，用户被封禁时据此让旧Token失效
 			Issuer:    "bluebell",                                                                                     // 签发人
 		},
 	}
@@ -0,0 +1,280 @@
+package sensitive
+
+import (
+	"bufio"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
+// 敏感词过滤
+/*
+	1. 用 Aho-Corasick 自动机一次扫描找出文本中的全部敏感词
+	2. 匹配前统一字符：全角转半角、英文转小写；空白、标点和符号不参与匹配，
+	   “敏 感 词”“敏*感*词”这类插入间隔的写法同样能匹配
+	3. 词库从文件加载，每行一个词，# 开头的行是注释；文件变化时自动重新加载
+*/
+
+type node struct {
+	children map[rune]*node
+	fail     *node
+	depth    int // 从根到该节点的字符数
+	word     bool
+}
+
+// Filter 敏感词自动机，创建后只读，可以并发使用
+type Filter struct {
+	root *node
+	size int
+}
+
+// Match 文本中匹配到的一个敏感词，Start、End 是原文中的字符（rune）下标，End 不包含
+type Match struct {
+	Word  string
+	Start int
+	End   int
+}
+
+// normalize 统一字符：全角转半角，英文转小写
+func normalize(r rune) rune {
+	switch {
+	case r == '　':
+		r = ' '
+	case r >= '！' && r <= '～':
+		r -= 0xfee0
+	}
+	return unicode.ToLower(r)
+}
+
+// ignored 不参与匹配的字符：空白、标点、符号和控制字符
+func ignored(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsControl(r)
+}
+
+// New 用一组敏感词创建自动机
+func New(words []string) *Filter {
+	f := &Filter{root: &node{children: make(map[rune]*node)}}
+	for _, w := range words {
+		f.add(w)
+	}
+	f.build()
+	return f
+}
+
+func (f *Filter) add(word string) {
+	n := f.root
+	for _, r := range word {
+		r = normalize(r)
+		if ignored(r) {
+			continue
+		}
+		child, ok := n.children[r]
+		if !ok {
+			child = &node{children: make(map[rune]*node), depth: n.depth + 1}
+			n.children[r] = child
+		}
+		n = child
+	}
+	if n != f.root && !n.word {
+		n.word = true
+		f.size++
+	}
+}
+
+// build 按层构建失败指针
+func (f *Filter) build() {
+	queue := make([]*node, 0, len(f.root.children))
+	for _, child := range f.root.children {
+		child.fail = f.root
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for r, child := range n.children {
+			fail := n.fail
+			for fail != nil {
+				if next, ok := fail.children[r]; ok {
+					child.fail = next
+					break
+				}
+				fail = fail.fail
+			}
+			if child.fail == nil {
+				child.fail = f.root
+			}
+			queue = append(queue, child)
+		}
+	}
+}
+
+// Size 自动机中的敏感词数量
+func (f *Filter) Size() int {
+	return f.size
+}
+
+// FindAll 找出文本中的全部敏感词
+func (f *Filter) FindAll(text string) []Match {
+	if f == nil || f.size == 0 {
+		return nil
+	}
+	runes := []rune(text)
+	// positions[i] 是参与匹配的第 i 个字符在原文中的下标
+	positions := make([]int, 0, len(runes))
+	var matches []Match
+	n := f.root
+	for i, r := range runes {
+		r = normalize(r)
+		if ignored(r) {
+			continue
+		}
+		positions = append(positions, i)
+		for n != f.root && n.children[r] == nil {
+			n = n.fail
+		}
+		if next, ok := n.children[r]; ok {
+			n = next
+		}
+		for out := n; out != f.root; out = out.fail {
+			if out.word {
+				start := positions[len(positions)-out.depth]
+				matches = append(matches, Match{Word: string(runes[start : i+1]), Start: start, End: i + 1})
+			}
+		}
+	}
+	return matches
+}
+
+// Contains 文本中是否包含敏感词
+func (f *Filter) Contains(text string) bool {
+	return len(f.FindAll(text)) > 0
+}
+
+// Mask 把文本中的敏感词替换为 *，返回替换后的文本和匹配到的敏感词
+func (f *Filter) Mask(text string) (string, []Match) {
+	matches := f.FindAll(text)
+	if len(matches) == 0 {
+		return text, nil
+	}
+	runes := []rune(text)
+	for _, m := range matches {
+		for i := m.Start; i < m.End; i++ {
+			if !ignored(normalize(runes[i])) {
+				runes[i] = '*'
+			}
+		}
+	}
+	return string(runes), matches
+}
+
+// loadWords 从词库文件读取敏感词
+func loadWords(files []string) ([]string, error) {
+	words := make([]string, 0)
+	for _, file := range files {
+		fd, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(fd)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			words = append(words, line)
+		}
+		err = scanner.Err()
+		_ = fd.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return words, nil
+}
+
+var (
+	current   atomic.Pointer[Filter]
+	wordFiles []string
+)
+
+// Init 从词库文件加载敏感词，并在文件变化时自动重新加载
+func Init(files []string) error {
+	wordFiles = files
+	if err := Reload(); err != nil {
+		return err
+	}
+	watch(files)
+	return nil
+}
+
+// Reload 重新加载词库，加载失败时继续使用原来的词库
+func Reload() error {
+	words, err := loadWords(wordFiles)
+	if err != nil {
+		return err
+	}
+	f := New(words)
+	current.Store(f)
+	zap.L().Info("敏感词库加载完成", zap.Int("words", f.Size()))
+	return nil
+}
+
+// Default 当前使用的敏感词自动机，未初始化时返回nil（不匹配任何词）
+func Default() *Filter {
+	return current.Load()
+}
+
+// watch 监听词库文件所在目录，文件被修改、替换或重新创建时重新加载
+func watch(files []string) {
+	if len(files) == 0 {
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		zap.L().Error("create sensitive word watcher failed", zap.Error(err))
+		return
+	}
+	watched := make(map[string]struct{}, len(files))
+	dirs := make(map[string]struct{})
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			continue
+		}
+		watched[abs] = struct{}{}
+		dirs[filepath.Dir(abs)] = struct{}{}
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			zap.L().Error("watch sensitive word dir failed", zap.String("dir", dir), zap.Error(err))
+		}
+	}
+	go func() {
+		for {
+			select {
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if _, ok := watched[filepath.Clean(ev.Name)]; !ok {
+					continue
+				}
+				if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+				if err := Reload(); err != nil {
+					zap.L().Error("reload sensitive words failed", zap.String("file", ev.Name), zap.Error(err))
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				zap.L().Error("sensitive word watcher error", zap.Error(err))
+			}
+		}
+	}()
+}
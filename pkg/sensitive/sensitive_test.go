@@ -0,0 +1,199 @@
+package sensitive
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   rune
+		want rune
+	}{
+		{'Ａ', 'a'},
+		{'ｚ', 'z'},
+		{'０', '0'},
+		{'！', '!'},
+		{'～', '~'},
+		{'　', ' '},
+		{'B', 'b'},
+		{'敏', '敏'},
+	}
+	for _, tt := range tests {
+		if got := normalize(tt.in); got != tt.want {
+			t.Errorf("normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNewSize(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []string
+		want  int
+	}{
+		{"empty", nil, 0},
+		{"dedup case and width", []string{"abc", "ABC", "ａｂｃ"}, 1},
+		{"dedup skipped chars", []string{"敏感", "敏 感", "敏*感"}, 1},
+		{"punct only word", []string{"!!", "  "}, 0},
+		{"prefix words", []string{"ab", "abc"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.words).Size(); got != tt.want {
+				t.Errorf("Size() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []string
+		text  string
+		want  []Match
+	}{
+		{
+			name:  "no match",
+			words: []string{"bad"},
+			text:  "good",
+			want:  nil,
+		},
+		{
+			name:  "full width and upper case",
+			words: []string{"bad"},
+			text:  "xＢＡＤ",
+			want:  []Match{{Word: "ＢＡＤ", Start: 1, End: 4}},
+		},
+		{
+			name:  "skip whitespace and punctuation",
+			words: []string{"敏感词"},
+			text:  "这是敏 感*词。",
+			want:  []Match{{Word: "敏 感*词", Start: 2, End: 7}},
+		},
+		{
+			name:  "leading skipped chars not included",
+			words: []string{"ab"},
+			text:  "--a-b--",
+			want:  []Match{{Word: "a-b", Start: 2, End: 5}},
+		},
+		{
+			name:  "fail link output",
+			words: []string{"he", "she", "his", "hers"},
+			text:  "ushers",
+			want: []Match{
+				{Word: "she", Start: 1, End: 4},
+				{Word: "he", Start: 2, End: 4},
+				{Word: "hers", Start: 2, End: 6},
+			},
+		},
+		{
+			name:  "fail link after partial match",
+			words: []string{"abcd", "bc"},
+			text:  "abce",
+			want:  []Match{{Word: "bc", Start: 1, End: 3}},
+		},
+		{
+			name:  "fail link to shorter prefix",
+			words: []string{"aab"},
+			text:  "aaab",
+			want:  []Match{{Word: "aab", Start: 1, End: 4}},
+		},
+		{
+			name:  "overlapping matches",
+			words: []string{"ab", "bc"},
+			text:  "abc",
+			want: []Match{
+				{Word: "ab", Start: 0, End: 2},
+				{Word: "bc", Start: 1, End: 3},
+			},
+		},
+		{
+			name:  "nested matches",
+			words: []string{"abc", "b"},
+			text:  "abc",
+			want: []Match{
+				{Word: "b", Start: 1, End: 2},
+				{Word: "abc", Start: 0, End: 3},
+			},
+		},
+		{
+			name:  "repeated matches",
+			words: []string{"aa"},
+			text:  "aaa",
+			want: []Match{
+				{Word: "aa", Start: 0, End: 2},
+				{Word: "aa", Start: 1, End: 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(tt.words).FindAll(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindAll(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindAllNilFilter(t *testing.T) {
+	var f *Filter
+	if got := f.FindAll("abc"); got != nil {
+		t.Errorf("nil filter FindAll = %+v, want nil", got)
+	}
+	if f.Contains("abc") {
+		t.Error("nil filter Contains = true, want false")
+	}
+}
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		name    string
+		words   []string
+		text    string
+		want    string
+		matches int
+	}{
+		{"no match", []string{"bad"}, "good", "good", 0},
+		{"simple", []string{"bad"}, "a bad day", "a *** day", 1},
+		{"keep skipped chars", []string{"敏感词"}, "这是敏 感*词。", "这是* ***。", 1},
+		{"full width", []string{"bad"}, "ＢＡＤ!", "***!", 1},
+		{"overlapping", []string{"ab", "bc"}, "xabcx", "x***x", 2},
+		{"multi byte offsets", []string{"词"}, "中文词abc词", "中文*abc*", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, matches := New(tt.words).Mask(tt.text)
+			if got != tt.want {
+				t.Errorf("Mask(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			if len(matches) != tt.matches {
+				t.Errorf("Mask(%q) matches = %d, want %d", tt.text, len(matches), tt.matches)
+			}
+		})
+	}
+}
+
+func TestLoadWords(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "words.txt")
+	content := "# 注释\nbad\n\n  敏感词  \n#另一行注释\n"
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	words, err := loadWords([]string{file})
+	if err != nil {
+		t.Fatalf("loadWords error: %v", err)
+	}
+	want := []string{"bad", "敏感词"}
+	if !reflect.DeepEqual(words, want) {
+		t.Errorf("loadWords = %v, want %v", words, want)
+	}
+	if _, err := loadWords([]string{filepath.Join(dir, "missing.txt")}); err == nil {
+		t.Error("loadWords missing file: want error")
+	}
+}
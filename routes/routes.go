@@ -5,6 +5,7 @@ import (
 	_ "bluebell/docs" // 千万不要忘了导入把你上一步生成的docs
 	"bluebell/logger"
 	"bluebell/middleware"
+	"bluebell/models"
 	"github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
 	"github.com/swaggo/files"
@@ -24,7 +25,7 @@ func Setup(mode string) *gin.Engine {
 	v1 := r.Group("/api/v1")
 
 	// 登录业务路由
-	v1.GET("/login", controllers.LogInHandler)
+	v1.GET("/login", middleware.Audit(models.AuditLogin, models.AuditTargetUser, ""), controllers.LogInHandler)
 
 	// 注册业务路由
 	v1.POST("/signup", controllers.SignUpHandler)
@@ -32,21 +33,24 @@ func Setup(mode string) *gin.Engine {
 	// 生成验证码 API
 	v1.POST("/gen-otp", controllers.GenerateOTPHandler)
 
+	// 查询账号状态（被封禁的用户不能登录，用用户名和密码查询）
+	v1.POST("/account/status", controllers.AccountStatusHandler)
+
 	// 注册登录认证中间件
 	v1.Use(middleware.JWTAuthMiddleware()) // JWTAuthMiddleware() 应用登录认证的中间件
 
 	{
 		// 注销
-		v1.POST("/logout", controllers.LogOutHandler)
+		v1.POST("/logout", middleware.Audit(models.AuditLogout, models.AuditTargetUser, ""), controllers.LogOutHandler)
 
 		// 查询个人信息
 		v1.GET("/user", controllers.GetUserInfoHandler)
 
 		// 修改个人信息
-		v1.PATCH("/user", controllers.ModifyUserInfoHandler)
+		v1.PATCH("/user", middleware.Audit(models.AuditUserUpdate, models.AuditTargetUser, ""), controllers.ModifyUserInfoHandler)
 
 		// 修改密码 (还有问题)
-		v1.PATCH("/password", controllers.ModifyPasswordHandler)
+		v1.PATCH("/password", middleware.Audit(models.AuditPasswordChange, models.AuditTargetUser, ""), controllers.ModifyPasswordHandler)
 
 		// 根据用户名获取单个用户信息
 		v1.GET("/user/:name", controllers.GetUserByNameHandler)
@@ -57,21 +61,92 @@ func Setup(mode string) *gin.Engine {
 		// 获取用户的全部帖子
 		v1.GET("/user/posts/:id", controllers.GetUserPostHandler)
 
+		// 获取当前用户投过票的帖子
+		v1.GET("/user/votes", controllers.GetUserVotesHandler)
+
+		// 查询当前用户的信任等级及每小时配额
+		v1.GET("/user/trust", controllers.GetUserTrustHandler)
+
+		// 生成邮箱验证码、验证邮箱
+		v1.POST("/user/email/code", controllers.SendEmailCodeHandler)
+		v1.POST("/user/email/verify", controllers.VerifyEmailHandler)
+
 		// 获取全部社区
 		v1.GET("/community", controllers.GetCommunityHandler)
 
 		// 创建社区
-		v1.POST("/community", controllers.CreateCommunityHandler)
+		v1.POST("/community", middleware.Audit(models.AuditCommunityCreate, models.AuditTargetCommunity, ""), controllers.CreateCommunityHandler)
 
 		// 根据社区id/name获取社区详情
 		v1.GET("/community/detail", controllers.CommunityDetailHandler)
 
+		// 加入/退出社区
+		v1.POST("/community/:id/join", controllers.JoinCommunityHandler)
+		v1.DELETE("/community/:id/join", controllers.LeaveCommunityHandler)
+
+		// 当前用户加入的社区
+		v1.GET("/community/mine", controllers.GetMyCommunitiesHandler)
+
+		// 搜索、自动补全、热门和推荐社区
+		v1.GET("/community/search", controllers.SearchCommunitiesHandler)
+		v1.GET("/community/suggest", controllers.SuggestCommunitiesHandler)
+		v1.GET("/community/trending", controllers.GetTrendingCommunitiesHandler)
+		v1.GET("/community/recommend", controllers.GetRecommendedCommunitiesHandler)
+
+		// 社区设置
+		v1.GET("/community/:id/settings", controllers.GetCommunitySettingsHandler)
+		v1.PUT("/community/:id/settings", middleware.Audit(models.AuditCommunitySettings, models.AuditTargetCommunity, "id"), controllers.UpdateCommunitySettingsHandler)
+
+		// 修改社区简介和设置、归档社区
+		v1.PATCH("/community/:id", middleware.Audit(models.AuditCommunityUpdate, models.AuditTargetCommunity, "id"), controllers.UpdateCommunityHandler)
+		v1.POST("/community/:id/archive", middleware.Audit(models.AuditCommunityArchive, models.AuditTargetCommunity, "id"), controllers.ArchiveCommunityHandler)
+		v1.DELETE("/community/:id/archive", middleware.Audit(models.AuditCommunityUnarchive, models.AuditTargetCommunity, "id"), controllers.ArchiveCommunityHandler)
+
+		// 社区每天的统计数据
+		v1.GET("/community/:id/stats", controllers.GetCommunityStatsHandler)
+
+		// 邀请用户及审批加入申请
+		v1.POST("/community/:id/invite", controllers.InviteCommunityMemberHandler)
+		v1.GET("/community/:id/requests", controllers.GetJoinRequestsHandler)
+		v1.POST("/community/:id/requests/:user_id/approve", middleware.Audit(models.AuditJoinApprove, models.AuditTargetUser, "user_id"), controllers.ApproveJoinRequestHandler)
+		v1.POST("/community/:id/requests/:user_id/reject", middleware.Audit(models.AuditJoinReject, models.AuditTargetUser, "user_id"), controllers.RejectJoinRequestHandler)
+
+		// 社区版主
+		v1.GET("/community/:id/moderators", controllers.GetModeratorsHandler)
+		v1.POST("/community/:id/moderators", middleware.Audit(models.AuditModeratorAppoint, models.AuditTargetUser, ""), controllers.AppointModeratorHandler)
+		v1.DELETE("/community/:id/moderators/:user_id", middleware.Audit(models.AuditModeratorDismiss, models.AuditTargetUser, "user_id"), controllers.DismissModeratorHandler)
+
+		// 社区禁言
+		v1.GET("/community/:id/bans", controllers.GetCommunityBansHandler)
+		v1.POST("/community/:id/bans", middleware.Audit(models.AuditCommunityBan, models.AuditTargetUser, ""), controllers.BanCommunityUserHandler)
+		v1.DELETE("/community/:id/bans/:user_id", middleware.Audit(models.AuditCommunityUnban, models.AuditTargetUser, "user_id"), controllers.UnbanCommunityUserHandler)
+
+		// 社区管理日志
+		v1.GET("/community/:id/modlog", controllers.GetModerationLogsHandler)
+
+		// 举报及审核队列
+		v1.POST("/reports", controllers.SubmitReportHandler)
+		v1.GET("/reports", controllers.GetReportQueueHandler)
+		v1.GET("/reports/:id", controllers.GetReportCaseHandler)
+		v1.POST("/reports/:id/resolve", middleware.Audit(models.AuditReportResolve, models.AuditTargetReport, "id"), controllers.ResolveReportHandler)
+		v1.POST("/reports/:id/dismiss", middleware.Audit(models.AuditReportDismiss, models.AuditTargetReport, "id"), controllers.DismissReportHandler)
+
+		// 首页：已加入社区的热门帖子
+		v1.GET("/feed", controllers.GetHomeFeedHandler)
+
 		// 创建帖子
 		v1.POST("/post", controllers.CreatePostHandler)
 
 		// 根据帖子id获取帖子
 		v1.GET("/post/:id", controllers.GetPostDetailHandler)
 
+		// 版主移除、置顶、锁定帖子
+		v1.POST("/post/:id/remove", middleware.Audit(models.AuditPostRemove, models.AuditTargetPost, "id"), controllers.RemovePostHandler)
+		v1.POST("/post/:id/pin", middleware.Audit(models.AuditPostPin, models.AuditTargetPost, "id"), controllers.PinPostHandler)
+		v1.DELETE("/post/:id/pin", middleware.Audit(models.AuditPostUnpin, models.AuditTargetPost, "id"), controllers.PinPostHandler)
+		v1.POST("/post/:id/lock", middleware.Audit(models.AuditPostLock, models.AuditTargetPost, "id"), controllers.LockPostHandler)
+		v1.DELETE("/post/:id/lock", middleware.Audit(models.AuditPostUnlock, models.AuditTargetPost, "id"), controllers.LockPostHandler)
+
 		// 根据时间或分数或获取帖子列表(可以按照社区分区)
 		v1.GET("/post", controllers.GetPostListHandler)
 
@@ -87,26 +162,102 @@ func Setup(mode string) *gin.Engine {
 		// 查看评论的子评论
 		v1.GET("/comment/child/:parent_id", controllers.GetChildCommentsController)
 
+		// 查看帖子的评论树
+		v1.GET("/comment/tree/:post_id", controllers.GetCommentTreeController)
+
+		// 评论投票
+		v1.POST("/comment/vote", controllers.CommentVoteController)
+
+		// 编辑评论
+		v1.PUT("/comment", controllers.EditCommentController)
+
 		// 删除评论
-		v1.DELETE("/comment", controllers.DeleteCommentController)
+		v1.DELETE("/comment", middleware.Audit(models.AuditCommentDelete, models.AuditTargetComment, ""), controllers.DeleteCommentController)
 
 		// 置顶评论路由，例如 POST /comment/pin/:comment_id
-		v1.POST("/comment/pin/:comment_id", controllers.PinCommentController)
+		v1.POST("/comment/pin/:comment_id", middleware.Audit(models.AuditCommentPin, models.AuditTargetComment, "comment_id"), controllers.PinCommentController)
 
 		// 取消置顶评论路由，例如 POST /comment/pin/:comment_id
-		v1.DELETE("/comment/pin/:comment_id", controllers.UnpinCommentController)
-
-		// 评论热度省略了... 类比于帖子热度
+		v1.DELETE("/comment/pin/:comment_id", middleware.Audit(models.AuditCommentUnpin, models.AuditTargetComment, "comment_id"), controllers.UnpinCommentController)
 
 		// 上传图片
 		// 定义路由
 		v1.POST("/upload-image", controllers.UploadImageController)
 
+		// 通知列表及未读数量
+		v1.GET("/notifications", controllers.GetNotificationsHandler)
+		v1.GET("/notifications/unread", controllers.GetUnreadNotificationCountHandler)
+
+		// 标记通知已读
+		v1.POST("/notifications/:id/read", controllers.MarkNotificationReadHandler)
+		v1.POST("/notifications/read_all", controllers.MarkAllNotificationsReadHandler)
+
+		// 通知设置
+		v1.GET("/notifications/preferences", controllers.GetNotificationPreferencesHandler)
+		v1.PUT("/notifications/preferences", controllers.SetNotificationPreferenceHandler)
+
+		// 私信
+		v1.POST("/messages", controllers.SendMessageHandler)
+		v1.GET("/messages/unread", controllers.GetUnreadMessageCountHandler)
+		v1.GET("/conversations", controllers.GetConversationsHandler)
+		v1.GET("/conversations/:id/messages", controllers.GetMessagesHandler)
+		v1.POST("/conversations/:id/read", controllers.MarkConversationReadHandler)
+
+		// 屏蔽用户
+		v1.GET("/user/blocks", controllers.GetBlockedUsersHandler)
+		v1.POST("/user/block/:id", controllers.BlockUserHandler)
+		v1.DELETE("/user/block/:id", controllers.UnblockUserHandler)
+
+		// 关注用户，被关注的用户收到新关注者通知
+		v1.GET("/user/followers", controllers.GetFollowersHandler)
+		v1.GET("/user/following", controllers.GetFollowingHandler)
+		v1.POST("/user/follow/:id", controllers.FollowUserHandler)
+		v1.DELETE("/user/follow/:id", controllers.UnfollowUserHandler)
+
+		// 实时事件推送（SSE），浏览器先获取一次性凭证再建立事件流
+		v1.POST("/events/ticket", controllers.CreateStreamTicketHandler)
+		v1.GET("/events", controllers.RealtimeEventsHandler)
+
 		// 推荐系统
 		v1.GET("/recommend", controllers.RecommendController)
 
 	}
 
+	// 管理后台，只有站点管理员可以访问
+	admin := r.Group("/api/admin")
+	admin.Use(middleware.JWTAuthMiddleware(), middleware.AdminAuthMiddleware())
+	{
+		// 用户管理：搜索、查看完整记录、设置角色、重置密码
+		admin.GET("/users", controllers.AdminSearchUsersHandler)
+		admin.GET("/users/:id", controllers.AdminGetUserHandler)
+		admin.PUT("/users/:id/role", middleware.Audit(models.AuditUserRoleChange, models.AuditTargetUser, "id"), controllers.AdminSetUserRoleHandler)
+		admin.POST("/users/:id/password/reset", middleware.Audit(models.AuditPasswordReset, models.AuditTargetUser, "id"), controllers.AdminResetPasswordHandler)
+
+		// 封禁、影子封禁用户
+		admin.GET("/users/:id/sanctions", controllers.GetUserSanctionsHandler)
+		admin.POST("/users/:id/suspend", middleware.Audit(models.AuditUserSuspend, models.AuditTargetUser, "id"), controllers.SuspendUserHandler)
+		admin.DELETE("/users/:id/suspend", middleware.Audit(models.AuditUserUnsuspend, models.AuditTargetUser, "id"), controllers.UnsuspendUserHandler)
+		admin.POST("/users/:id/shadowban", middleware.Audit(models.AuditUserShadowBan, models.AuditTargetUser, "id"), controllers.ShadowBanUserHandler)
+		admin.DELETE("/users/:id/shadowban", middleware.Audit(models.AuditUserUnshadowBan, models.AuditTargetUser, "id"), controllers.UnshadowBanUserHandler)
+
+		// 内容管理：移除任意帖子和评论
+		admin.DELETE("/posts/:id", middleware.Audit(models.AuditPostForceDelete, models.AuditTargetPost, "id"), controllers.AdminDeletePostHandler)
+		admin.DELETE("/comments/:id", middleware.Audit(models.AuditCommentForceDelete, models.AuditTargetComment, "id"), controllers.AdminDeleteCommentHandler)
+
+		// 修改任意社区
+		admin.PATCH("/communities/:id", middleware.Audit(models.AuditCommunityForceEdit, models.AuditTargetCommunity, "id"), controllers.AdminUpdateCommunityHandler)
+
+		// 待处理的举报（全部社区）
+		admin.GET("/reports", controllers.GetReportQueueHandler)
+
+		// 审计日志
+		admin.GET("/audit", controllers.GetAuditLogsHandler)
+
+		// 维护任务：查看状态、手动运行
+		admin.GET("/jobs", controllers.GetMaintenanceJobsHandler)
+		admin.POST("/jobs/:name", middleware.Audit(models.AuditJobRun, "", ""), controllers.RunMaintenanceJobHandler)
+	}
+
 	r.GET("/swagger/*any", gs.WrapHandler(swaggerFiles.Handler))
 
 	pprof.Register(r) // 注册pprof相关路由